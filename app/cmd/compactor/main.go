@@ -0,0 +1,96 @@
+// Compactor: menggabungkan data di cold store secara berkala.
+// Segment hasil offloader, file overflow_*.jsonl dari ingestor, dan file offloaded/
+// lama digabung menjadi segment baru yang hanya berisi record terbaru per key.
+// Setiap putaran hanya mengambil input terbatas (segment kecil per tier ukuran dan segment
+// dengan banyak data usang); segment yang sudah penuh dan bersih tidak disentuh.
+// Record yang TTL aslinya sudah lewat dan key yang sudah dihapus (tombstone) dibuang.
+// Pergantian data dilakukan lewat satu commit manifest, jadi pembaca tidak pernah
+// melihat hasil compaction setengah jadi.
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+)
+
+func main() {
+	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	log.Print("compactor: process started")
+
+	ctx := context.Background()
+	store := coldstore.NewStoreFromEnv()
+	// Redis hanya dipakai untuk mengumumkan manifest baru ke ingestor (coldstore.InvalidateChannel)
+	r := redisx.NewCluster()
+
+	// Interval jalannya compaction (detik)
+	intervalSec := getInt("COMPACT_INTERVAL_SECONDS", 300)
+	opts := coldstore.CompactOptions{
+		// Jumlah maksimal record per segment hasil compaction
+		SegmentMaxRecords: getInt("COMPACT_SEGMENT_MAX_RECORDS", 10000),
+		// Batas input satu putaran: hanya input yang dimuat ke memori, segment lain dibiarkan
+		MaxInputSegments: getInt("COMPACT_MAX_INPUT_SEGMENTS", 8),
+		MaxInputBytes:    int64(getInt("COMPACT_MAX_INPUT_MB", 256)) << 20,
+		MaxInputFiles:    getInt("COMPACT_MAX_INPUT_FILES", 1000),
+		// Segment kecil baru digabung jika ada minimal sekian segment di tier ukuran yang sama
+		MinInputSegments: getInt("COMPACT_MIN_INPUT_SEGMENTS", 4),
+		// Segment dengan porsi data usang (tombstone/expired) minimal ini ditulis ulang walaupun sudah penuh
+		GarbageRatio: getFloat("COMPACT_GARBAGE_RATIO", 0.3),
+		// Tombstone yang lebih tua dari ini dihapus setelah data yang ditutupnya digabung
+		TombstoneGrace: time.Duration(getInt("COMPACT_TOMBSTONE_GRACE_SECONDS", 3600)) * time.Second,
+		// Segment tanpa entry manifest (commit offloader gagal) dihapus setelah umur ini
+		OrphanGrace: time.Duration(getInt("COMPACT_ORPHAN_GRACE_SECONDS", 3600)) * time.Second,
+		// Jumlah versi manifest lama yang disimpan (untuk debugging / pembaca yang tertinggal)
+		KeepManifests: getInt("COMPACT_KEEP_MANIFESTS", 10),
+	}
+
+	log.Printf("compactor started: COMPACT_INTERVAL_SECONDS=%d, COMPACT_SEGMENT_MAX_RECORDS=%d, COMPACT_MAX_INPUT_SEGMENTS=%d, COMPACT_MAX_INPUT_MB=%d, COMPACT_MAX_INPUT_FILES=%d, COMPACT_MIN_INPUT_SEGMENTS=%d, COMPACT_GARBAGE_RATIO=%.2f, COMPACT_TOMBSTONE_GRACE_SECONDS=%d, COMPACT_ORPHAN_GRACE_SECONDS=%d, COMPACT_KEEP_MANIFESTS=%d, COLD_STORE=%s",
+		intervalSec, opts.SegmentMaxRecords, opts.MaxInputSegments, opts.MaxInputBytes>>20, opts.MaxInputFiles, opts.MinInputSegments, opts.GarbageRatio,
+		int(opts.TombstoneGrace.Seconds()), int(opts.OrphanGrace.Seconds()), opts.KeepManifests, store.Name())
+
+	if err := store.Init(); err != nil {
+		log.Printf("compactor: cold store (%s) init failed: %v", store.Name(), err)
+	}
+
+	for {
+		start := time.Now()
+		st, err := store.Compact(opts)
+		if err == nil && !st.Skipped {
+			if err := r.Publish(ctx, coldstore.InvalidateChannel, store.Name()).Err(); err != nil {
+				log.Printf("compact invalidate publish failed: %v", err)
+			}
+		}
+		if err != nil {
+			log.Printf("compact run failed: %v", err)
+		} else if !st.Skipped || st.ManifestsRemoved > 0 || st.OrphansRemoved > 0 {
+			log.Printf("compact run: segments=%d segments_kept=%d overflow_files=%d legacy_files=%d records_in=%d records_out=%d superseded=%d expired=%d deleted=%d output_segments=%d tombstones_removed=%d manifests_removed=%d orphans_removed=%d manifest_version=%d took=%s",
+				st.InputSegments, st.SegmentsKept, st.InputOverflowFiles, st.InputLegacyFiles, st.RecordsIn, st.RecordsOut, st.Superseded, st.Expired, st.Deleted,
+				st.OutputSegments, st.TombstonesRemoved, st.ManifestsRemoved, st.OrphansRemoved, st.ManifestVersion, time.Since(start).Round(time.Millisecond))
+		}
+		time.Sleep(time.Duration(intervalSec) * time.Second)
+	}
+}
+
+func getInt(env string, def int) int {
+	if s := os.Getenv(env); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			return v
+		}
+	}
+	return def
+}
+
+func getFloat(env string, def float64) float64 {
+	if s := os.Getenv(env); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	return def
+}
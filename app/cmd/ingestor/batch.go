@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// batchItemResult adalah hasil per item dari POST /ingest/batch.
+// Stored berisi "redis" atau nama cold store ("hdfs", "local", "s3"); kosong jika item gagal disimpan sama sekali.
+type batchItemResult struct {
+	Key    string `json:"key"`
+	Stored string `json:"stored,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchPipelineWorkers membatasi jumlah pipeline per slot yang berjalan bersamaan.
+const batchPipelineWorkers = 8
+
+// batchIngestHandler menerima banyak event dalam satu request.
+// Body boleh berupa JSON array ([{...},{...}]) atau NDJSON (satu event per baris).
+// Alur sama dengan /ingest, tapi:
+// - ClusterMemRatio hanya dicek sekali per batch
+// - SET ke Redis dikelompokkan per hash slot lalu di-pipeline (satu round trip per slot)
+// - Semua item yang overflow (memori penuh / SET gagal) ditulis ke cold store dengan satu WriteEvents
+// - Salinan lama key item overflow di Redis dihapus setelahnya (delOverflowed)
+// Response berisi hasil per item dengan urutan sama seperti input.
+func batchIngestHandler(r *redis.ClusterClient, ctx context.Context, cache *cachex.Cache, inv *cacheInvalidator, cold coldstore.ColdStore, soft float64) gin.HandlerFunc {
+	// Batas jumlah item per request agar satu batch tidak menahan handler terlalu lama
+	maxItems := 10000
+	if s := os.Getenv("INGEST_BATCH_MAX_ITEMS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			maxItems = v
+		}
+	}
+
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		events, parseErrs, err := decodeBatch(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if len(events) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "empty batch"})
+			return
+		}
+		if len(events) > maxItems {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "error": "batch too large", "max_items": maxItems})
+			return
+		}
+
+		results := make([]batchItemResult, len(events))
+		var valid []int
+		for i := range events {
+			ev := &events[i]
+			results[i].Key = ev.Key
+			// Validasi per item: item invalid tidak menggagalkan seluruh batch
+			if parseErrs[i] != nil || ev.Key == "" || ev.Value == nil {
+				results[i].Error = "invalid event"
+				continue
+			}
+			if ev.TTLSeconds <= 0 {
+				ev.TTLSeconds = 3600
+			}
+			valid = append(valid, i)
+		}
+
+		// Payload (dengan _ts) disiapkan sebelum memilih tier, agar item yang overflow juga membawa _ts
+		var overflow, toRedis []int
+		payloads := make([][]byte, len(events))
+		for _, i := range valid {
+			b, err := encodePayload(&events[i])
+			if err != nil {
+				results[i].Error = err.Error()
+				overflow = append(overflow, i)
+				continue
+			}
+			payloads[i] = b
+			toRedis = append(toRedis, i)
+		}
+
+		// Satu kali cek rasio memori untuk seluruh batch
+		ratio, _ := redisx.ClusterMemRatio(ctx, r)
+		if ratio >= soft {
+			overflow = append(overflow, toRedis...)
+		} else {
+			// Item yang SET-nya gagal ikut di-overflow ke cold store (seperti fallback di /ingest)
+			overflow = append(overflow, pipelineSetBySlot(ctx, r, events, payloads, toRedis, results)...)
+		}
+
+		if len(overflow) > 0 {
+			batch := make([]any, 0, len(overflow))
+			for _, i := range overflow {
+				batch = append(batch, events[i])
+			}
+			if err := cold.WriteEvents(batch); err != nil {
+				for _, i := range overflow {
+					results[i].Error = err.Error()
+				}
+			} else {
+				for _, i := range overflow {
+					results[i].Stored = cold.Name()
+				}
+				delOverflowed(ctx, r, events, overflow, results)
+			}
+		}
+
+		// Sama seperti /ingest: entry local LRU item yang ditulis dibuang, kecuali item "hot_read"
+		// yang tersimpan di Redis langsung diisi value barunya; semua key diumumkan ke instance lain
+		// dalam satu PUBLISH
+		keys := make([]string, 0, len(valid))
+		for _, i := range valid {
+			ev := &events[i]
+			if results[i].Stored == "redis" && ev.CacheHint == "hot_read" {
+				cache.Add(ev.Key, string(payloads[i]), time.Duration(ev.TTLSeconds)*time.Second)
+			} else {
+				cache.Remove(ev.Key)
+			}
+			keys = append(keys, ev.Key)
+		}
+		inv.Publish(ctx, keys...)
+
+		var storedRedis, storedCold, failed int
+		for _, res := range results {
+			switch res.Stored {
+			case "redis":
+				storedRedis++
+			case "":
+				failed++
+			default:
+				storedCold++
+			}
+		}
+		c.JSON(200, gin.H{
+			"ok":           failed == 0,
+			"count":        len(results),
+			"stored_redis": storedRedis,
+			"stored_cold":  storedCold,
+			"failed":       failed,
+			"mem_ratio":    ratio,
+			"results":      results,
+		})
+	}
+}
+
+// pipelineSetBySlot menjalankan SET untuk item di idx, satu pipeline per hash slot.
+// Pipeline per slot dijalankan paralel (dibatasi batchPipelineWorkers).
+// Mengembalikan index item yang SET-nya gagal (untuk di-overflow ke cold store).
+func pipelineSetBySlot(ctx context.Context, r *redis.ClusterClient, events []Event, payloads [][]byte, idx []int, results []batchItemResult) []int {
+	keys := make([]string, len(idx))
+	for n, i := range idx {
+		keys[n] = events[i].Key
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []int
+	)
+	sem := make(chan struct{}, batchPipelineWorkers)
+	for _, group := range redisx.GroupBySlot(keys) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(group []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cmds := make([]*redis.StatusCmd, len(group))
+			_, _ = r.Pipelined(ctx, func(p redis.Pipeliner) error {
+				for n, g := range group {
+					i := idx[g]
+					cmds[n] = p.Set(ctx, events[i].Key, payloads[i], time.Duration(events[i].TTLSeconds)*time.Second)
+				}
+				return nil
+			})
+			for n, g := range group {
+				i := idx[g]
+				// Setiap item hanya ditulis oleh satu goroutine, jadi results aman tanpa lock
+				if err := cmds[n].Err(); err != nil {
+					if redisx.IsOOM(err) {
+						redisOOMTotal.Inc()
+					}
+					results[i].Error = err.Error()
+					mu.Lock()
+					failed = append(failed, i)
+					mu.Unlock()
+					continue
+				}
+				results[i].Stored = "redis"
+			}
+		}(group)
+	}
+	wg.Wait()
+	return failed
+}
+
+// delOverflowed menghapus salinan lama key item overflow di Redis setelah item ditulis ke cold store
+// (GET membaca Redis sebelum cold store): satu DEL per hash slot, semuanya dalam satu pipeline.
+// Key yang juga disimpan di Redis oleh item yang lebih akhir di batch yang sama tidak dihapus.
+// Item yang DEL-nya gagal dilaporkan gagal (Stored kosong) agar client mengulangnya.
+func delOverflowed(ctx context.Context, r *redis.ClusterClient, events []Event, overflow []int, results []batchItemResult) {
+	lastRedis := make(map[string]int)
+	for i := range events {
+		if results[i].Stored == "redis" {
+			lastRedis[events[i].Key] = i
+		}
+	}
+	var idx []int
+	var keys []string
+	for _, i := range overflow {
+		if j, ok := lastRedis[events[i].Key]; ok && j > i {
+			continue
+		}
+		idx = append(idx, i)
+		keys = append(keys, events[i].Key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	groups := redisx.GroupBySlot(keys)
+	type slotDel struct {
+		group []int
+		cmd   *redis.IntCmd
+	}
+	dels := make([]slotDel, 0, len(groups))
+	_, _ = r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, group := range groups {
+			args := make([]string, len(group))
+			for n, g := range group {
+				args[n] = keys[g]
+			}
+			dels = append(dels, slotDel{group, p.Del(ctx, args...)})
+		}
+		return nil
+	})
+	for _, d := range dels {
+		if err := d.cmd.Err(); err != nil {
+			for _, g := range d.group {
+				i := idx[g]
+				results[i].Stored = ""
+				results[i].Error = "redis delete failed: " + err.Error()
+			}
+		}
+	}
+}
+
+// decodeBatch mem-parse body batch menjadi slice Event.
+// Jika body diawali '[' dianggap JSON array, selain itu NDJSON (baris kosong di-skip).
+// Error parse per item dikembalikan di slice terpisah (index sama dengan events),
+// sedangkan error kembalian hanya untuk body yang tidak bisa dibaca sama sekali.
+func decodeBatch(body []byte) ([]Event, []error, error) {
+	body = bytes.TrimSpace(body)
+	var raws []json.RawMessage
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			raws = append(raws, json.RawMessage(append([]byte(nil), line...)))
+		}
+		if err := sc.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	events := make([]Event, len(raws))
+	errs := make([]error, len(raws))
+	for i, raw := range raws {
+		errs[i] = json.Unmarshal(raw, &events[i])
+	}
+	return events, errs, nil
+}
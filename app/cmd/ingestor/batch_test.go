@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeBatch(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		body    string
+		keys    []string // "" = item dengan error parse
+		wantErr bool
+	}{
+		{"array", `[{"key":"a","value":{"x":1}},{"key":"b","value":{}}]`, []string{"a", "b"}, false},
+		{"array with whitespace", " \n[{\"key\":\"a\",\"value\":{}}]\n", []string{"a"}, false},
+		{"ndjson", "{\"key\":\"a\",\"value\":{}}\n\n  \n{\"key\":\"b\",\"value\":{}}\r\n", []string{"a", "b"}, false},
+		{"ndjson bad line", "{\"key\":\"a\",\"value\":{}}\n{not json\n{\"key\":\"c\",\"value\":{}}", []string{"a", "", "c"}, false},
+		{"array bad item type", `[{"key":"a","value":{}},{"key":1}]`, []string{"a", ""}, false},
+		{"broken array", `[{"key":"a"}`, nil, true},
+		{"empty", "  \n", nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			events, errs, err := decodeBatch([]byte(tc.body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tc.wantErr)
+			}
+			if len(events) != len(tc.keys) || len(errs) != len(tc.keys) {
+				t.Fatalf("got %d events / %d errs, want %d", len(events), len(errs), len(tc.keys))
+			}
+			for i, k := range tc.keys {
+				if k == "" {
+					if errs[i] == nil {
+						t.Errorf("item %d: want parse error", i)
+					}
+					continue
+				}
+				if errs[i] != nil || events[i].Key != k {
+					t.Errorf("item %d = %q, %v; want %q", i, events[i].Key, errs[i], k)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchIngestMaxItems(t *testing.T) {
+	e := newTestEnv(t)
+	t.Setenv("INGEST_BATCH_MAX_ITEMS", "2")
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+
+	w := postJSON(h, `[{"key":"a","value":{}},{"key":"b","value":{}},{"key":"c","value":{}}]`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413: %s", w.Code, w.Body)
+	}
+	// Batch yang ditolak tidak menulis apa pun
+	if keys := e.srv.Keys(); len(keys) != 0 {
+		t.Fatalf("redis keys after rejected batch: %v", keys)
+	}
+
+	if w := postJSON(h, `[{"key":"a","value":{}},{"key":"b","value":{}}]`); w.Code != http.StatusOK {
+		t.Fatalf("batch at the limit: status = %d: %s", w.Code, w.Body)
+	}
+}
+
+// Hasil per item mengikuti urutan input, walaupun SET dikelompokkan per slot dan
+// sebagian item invalid atau gagal di Redis lalu di-overflow ke cold store.
+func TestBatchIngestResultOrder(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.Hook(func(args []string) error {
+		if args[0] == "set" && args[1] == "{b}fail" {
+			return errors.New("ERR injected")
+		}
+		return nil
+	})
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+
+	body := strings.Join([]string{
+		`{"key":"{a}1","value":{"n":1}}`,
+		`{"key":"{b}1","value":{"n":2}}`,
+		`{"key":"","value":{}}`,
+		`{"key":"{b}fail","value":{"n":3}}`,
+		`not json`,
+		`{"key":"{a}2","value":{"n":4}}`,
+		`{"key":"{c}1","value":{"n":5}}`,
+	}, "\n")
+	w := postJSON(h, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		OK      bool              `json:"ok"`
+		Results []batchItemResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []batchItemResult{
+		{Key: "{a}1", Stored: "redis"},
+		{Key: "{b}1", Stored: "redis"},
+		{Key: "", Error: "invalid event"},
+		{Key: "{b}fail", Stored: "local"},
+		{Key: "", Error: "invalid event"},
+		{Key: "{a}2", Stored: "redis"},
+		{Key: "{c}1", Stored: "redis"},
+	}
+	if resp.OK || len(resp.Results) != len(want) {
+		t.Fatalf("ok = %t, results = %+v", resp.OK, resp.Results)
+	}
+	for i, res := range resp.Results {
+		// Item yang overflow membawa error Redis-nya; cukup cek key dan tier
+		if res.Key != want[i].Key || res.Stored != want[i].Stored || (want[i].Error != "" && res.Error != want[i].Error) {
+			t.Errorf("result %d = %+v, want %+v", i, res, want[i])
+		}
+	}
+	if v, ok := e.srv.Get("{c}1"); !ok || !strings.Contains(v, `"n":5`) {
+		t.Fatalf("redis {c}1 = %q, %t", v, ok)
+	}
+	if _, err := e.cold.ReadByKey("{b}fail"); err != nil {
+		t.Fatalf("cold ReadByKey({b}fail): %v", err)
+	}
+}
+
+// Item yang overflow ke cold store membawa _ts seperti value di Redis.
+func TestBatchIngestOverflowKeepsTS(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetMemory(900, 1000)
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	before := time.Now().UnixMilli()
+
+	w := postJSON(h, `[{"key":"a","value":{"n":1}}]`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stored":"local"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	v, err := e.cold.ReadByKey("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(v), `"n":1`) || tsOf(t, string(v)) < before {
+		t.Fatalf("cold value = %s, want payload with _ts", v)
+	}
+}
+
+func TestBatchIngestOverflowDropsStaleRedisCopies(t *testing.T) {
+	e := newTestEnv(t)
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	keys := []string{"{a}1", "{a}2", "{b}1", "{c}1"}
+	var items []string
+	for _, k := range keys {
+		items = append(items, `{"key":"`+k+`","value":{"v":"old"}}`)
+	}
+	if w := postJSON(h, "["+strings.Join(items, ",")+"]"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	e.srv.SetMemory(900, 1000)
+	w := postJSON(h, strings.ReplaceAll("["+strings.Join(items, ",")+"]", "old", "new"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stored_cold":4`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if left := e.srv.Keys(); len(left) != 0 {
+		t.Fatalf("stale copies left in redis: %v", left)
+	}
+	for _, k := range keys {
+		if v, err := e.cold.ReadByKey(k); err != nil || !strings.Contains(string(v), "new") {
+			t.Fatalf("cold %s = %s, %v", k, v, err)
+		}
+	}
+}
+
+func TestBatchIngestOverflowDelFailure(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetMemory(900, 1000)
+	e.srv.Hook(func(args []string) error {
+		if args[0] == "del" && args[1] == "{a}1" {
+			return errors.New("ERR injected")
+		}
+		return nil
+	})
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	w := postJSON(h, `[{"key":"{a}1","value":{}},{"key":"{b}1","value":{}}]`)
+	var resp struct {
+		OK      bool              `json:"ok"`
+		Results []batchItemResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.OK || len(resp.Results) != 2 || resp.Results[0].Stored != "" || resp.Results[0].Error == "" || resp.Results[1].Stored != "local" {
+		t.Fatalf("response = %s", w.Body)
+	}
+}
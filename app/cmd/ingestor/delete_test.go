@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+func TestDeleteRemovesEveryTier(t *testing.T) {
+	e := newTestEnv(t)
+	h := deleteHandler(e.r, e.ctx, e.inv, e.cold)
+	if err := e.r.Set(e.ctx, "user:1", `{"v":2}`, time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.cold.WriteKeyValues([]coldstore.KeyValue{{Key: "user:1", Value: []byte(`{"v":1}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	e.cache.Add("user:1", `{"v":2}`, time.Hour)
+
+	w := serve(h, http.MethodDelete, "/del/*key", "/del/user:1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if _, ok := e.srv.Get("user:1"); ok {
+		t.Fatal("key still in redis")
+	}
+	if _, ok := e.cache.Get("user:1"); ok {
+		t.Fatal("key still in local cache")
+	}
+	if _, err := e.cold.ReadByKey("user:1"); !errors.Is(err, coldstore.ErrDeleted) {
+		t.Fatalf("cold ReadByKey err = %v, want ErrDeleted", err)
+	}
+
+	// Write setelah delete terlihat lagi (tombstone hanya menutupi data yang lebih lama)
+	time.Sleep(2 * time.Millisecond)
+	if err := e.cold.WriteKeyValues([]coldstore.KeyValue{{Key: "user:1", Value: []byte(`{"v":3}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := e.cold.ReadByKey("user:1"); err != nil || string(v) != `{"v":3}` {
+		t.Fatalf("cold after rewrite = %q, %v", v, err)
+	}
+}
+
+func TestDeleteMissingKey(t *testing.T) {
+	e := newTestEnv(t)
+	h := deleteHandler(e.r, e.ctx, e.inv, e.cold)
+	if w := serve(h, http.MethodDelete, "/del/*key", "/del/", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty key: status = %d, want 400", w.Code)
+	}
+	if w := serve(h, http.MethodDelete, "/del/*key", "/del/nope", ""); w.Code != http.StatusOK {
+		t.Fatalf("unknown key: status = %d: %s", w.Code, w.Body)
+	}
+}
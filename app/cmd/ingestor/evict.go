@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/metricsx"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// Redis berjalan dengan maxmemory-policy noeviction: saat memori penuh, write ditolak
+// dengan error OOM (bukan key lama yang dibuang diam-diam), lalu ingestor mengalihkan
+// event tersebut ke cold store. Eviction tetap dipantau sebagai alarm jika policy berubah.
+var (
+	redisOOMTotal = metricsx.NewCounter("ingestor_redis_oom_total",
+		"Jumlah write ke Redis yang ditolak karena OOM (maxmemory tercapai) dan dialihkan ke cold store")
+	redisEvictedTotal = metricsx.NewCounter("ingestor_redis_evicted_keys_total",
+		"Jumlah key yang di-evict Redis (value hilang sebelum sempat dipindah ke cold store)")
+)
+
+// watchEvictions menghitung setiap key yang di-evict di semua shard (keyevent notification).
+func watchEvictions(ctx context.Context, r *redis.ClusterClient) {
+	err := redisx.WatchEvictions(ctx, r, func(key string) {
+		redisEvictedTotal.Inc()
+		log.Printf("redis evicted key=%q: value lost (check maxmemory-policy, expected noeviction)", key)
+	})
+	if err != nil {
+		log.Printf("watch evictions failed: %v", err)
+	}
+}
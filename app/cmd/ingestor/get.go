@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/flight"
+	"monolith-kv-sim/internal/metricsx"
+	"monolith-kv-sim/internal/redisx"
+)
+
+var (
+	readsCoalescedTotal = metricsx.NewCounter("ingestor_reads_coalesced_total",
+		"Jumlah read (GET/MGET) yang memakai hasil fetch Redis/cold store milik request lain untuk key yang sama")
+	staleServedTotal = metricsx.NewCounter("ingestor_stale_served_total",
+		"Jumlah GET yang disajikan dari entry local cache kedaluwarsa (LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS)")
+)
+
+// getResult adalah response GET /get/*key untuk satu key; dibagi ke semua request yang menunggu fetch yang sama
+// (body hanya dibaca setelah dibuat).
+type getResult struct {
+	status int
+	body   gin.H
+}
+
+// coldResult adalah hasil baca cold store + promosi untuk satu key.
+type coldResult struct {
+	rec      coldstore.Record
+	promoted bool
+}
+
+// reader mengambil key yang miss di local cache: Redis, lalu cold store (HDFS) + promosi ke Redis.
+// Request bersamaan untuk key yang sama berbagi satu fetch (flight.Group), sehingga key hot yang
+// baru hilang dari Redis tidak memicu puluhan baca HDFS sekaligus.
+type reader struct {
+	r       *redis.ClusterClient
+	cache   *cachex.Cache
+	cold    coldstore.ColdStore
+	promote *promoter
+
+	gets  flight.Group[getResult]  // GET /get/*key: Redis + cold store
+	colds flight.Group[coldResult] // baca cold store (GET dan MGET)
+}
+
+func newReader(r *redis.ClusterClient, cache *cachex.Cache, cold coldstore.ColdStore, promote *promoter) *reader {
+	return &reader{r: r, cache: cache, cold: cold, promote: promote}
+}
+
+// Forget melepas fetch keys yang sedang berjalan, dipanggil setelah keys ditulis/dihapus
+// agar read berikutnya tidak memakai hasil fetch yang dimulai sebelum write.
+func (rd *reader) Forget(keys ...string) {
+	for _, k := range keys {
+		rd.gets.Forget(k)
+		rd.colds.Forget(k)
+	}
+}
+
+// get mengembalikan response GET untuk key (fetch digabung dengan request lain untuk key yang sama).
+func (rd *reader) get(ctx context.Context, key string) getResult {
+	res, _, shared := rd.gets.Do(key, func() (getResult, error) {
+		return rd.fetch(ctx, key), nil
+	})
+	if shared {
+		readsCoalescedTotal.Inc()
+	}
+	return res
+}
+
+// fetch membaca key dari Redis, lalu dari cold store jika tidak ada di Redis.
+// Hit string di Redis (dan hasil promosi) mengisi local cache; selain itu entry lama key
+// dibuang, termasuk entry stale yang sedang disajikan selama revalidasi.
+// Generation cache diambil sebelum membaca: jika key ditulis/dihapus selama fetch berjalan
+// (Forget tidak menghentikan fetch yang sudah jalan), value yang dibaca tidak masuk cache.
+func (rd *reader) fetch(ctx context.Context, key string) getResult {
+	gen := rd.cache.Generation(key)
+	// PTTL ikut di pipeline yang sama agar entry local LRU tidak hidup lebih lama dari key di Redis.
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+	_, _ = rd.r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, key)
+		pttl = p.PTTL(ctx, key)
+		return nil
+	})
+	val, err := get.Result()
+	if err == nil {
+		// Ditemukan di Redis: cache di local LRU untuk akses berikutnya (sampai sisa TTL Redis / max staleness)
+		if ttl, err := pttl.Result(); err == nil && ttl != -2 {
+			rd.cache.AddIfGeneration(key, val, ttl, gen)
+		} else {
+			rd.cache.Remove(key)
+		}
+		return getResult{200, gin.H{"ok": true, "source": "redis", "value": val}}
+	}
+	rd.cache.Remove(key)
+	if redis.HasErrorPrefix(err, "WRONGTYPE") {
+		// Hash/list/set/zset: dibaca sesuai tipenya, tidak di-cache di local LRU
+		tv, err := redisx.ReadTyped(ctx, rd.r, key)
+		if err != nil {
+			return getResult{404, gin.H{"ok": false, "error": err.Error()}}
+		}
+		return getResult{200, gin.H{"ok": true, "source": "redis", "type": tv.Type, "value": typedValue(tv.Type, tv.Value)}}
+	}
+	// Sesuai diagram: jika tidak ditemukan di cache, baca dari on-disk KV-Store (HDFS)
+	cr, readErr := rd.readCold(ctx, key)
+	if readErr == nil {
+		return getResult{200, gin.H{"ok": true, "source": rd.cold.Name(), "type": recordType(cr.rec), "value": typedValue(cr.rec.Type, cr.rec.Value), "promoted": cr.promoted}}
+	}
+	if errors.Is(readErr, coldstore.ErrDeleted) {
+		return getResult{404, gin.H{"ok": false, "error": readErr.Error()}}
+	}
+	return getResult{404, gin.H{"ok": false, "error": err.Error()}}
+}
+
+// readCold membaca key dari cold store lalu mempromosikannya ke Redis (read-through promotion,
+// dengan sisa TTL asli) agar read berikutnya tidak ke HDFS lagi. Baca bersamaan untuk key yang
+// sama (dari GET maupun MGET) berbagi satu ReadRecord.
+func (rd *reader) readCold(ctx context.Context, key string) (coldResult, error) {
+	res, err, shared := rd.colds.Do(key, func() (coldResult, error) {
+		gen := rd.cache.Generation(key)
+		rec, err := rd.cold.ReadRecord(key)
+		if err != nil {
+			return coldResult{}, err
+		}
+		return coldResult{rec: rec, promoted: rd.promote.Promote(ctx, key, rec, gen)}, nil
+	})
+	if shared {
+		readsCoalescedTotal.Inc()
+	}
+	return res, err
+}
+
+// getHandler mengimplementasikan GET /get/*key dengan cache-aside pattern:
+// cek local cache -> Redis -> HDFS (jika perlu).
+// Dengan LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS > 0, entry local cache yang baru kedaluwarsa
+// tetap disajikan ("stale": true) sementara fetch ulang berjalan di background.
+func getHandler(ctx context.Context, rd *reader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyParam(c)
+		if key == "" {
+			c.JSON(400, gin.H{"ok": false, "error": "missing key"})
+			return
+		}
+
+		// Cache-aside pattern: cek local LRU cache dulu (jika enabled)
+		// Ini mengurangi latency untuk hot keys yang sering diakses
+		if v, stale, ok := rd.cache.GetStale(key); ok {
+			if stale {
+				staleServedTotal.Inc()
+				go rd.get(ctx, key)
+				c.JSON(200, gin.H{"ok": true, "source": "local_cache", "value": v, "stale": true})
+				return
+			}
+			c.JSON(200, gin.H{"ok": true, "source": "local_cache", "value": v})
+			return
+		}
+
+		res := rd.get(ctx, key)
+		c.JSON(res.status, res.body)
+	}
+}
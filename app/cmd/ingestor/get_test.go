@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+// Entry local cache yang diisi GET tidak hidup lebih lama dari sisa TTL key di Redis.
+func TestGetCachesUntilRedisTTL(t *testing.T) {
+	e := newTestEnv(t)
+	e.r.Set(e.ctx, "k", "v", 30*time.Millisecond)
+	if src, _ := e.get(t, "k"); src != "redis" {
+		t.Fatalf("first GET from %s", src)
+	}
+	if src, _ := e.get(t, "k"); src != "local_cache" {
+		t.Fatalf("second GET from %s, want local_cache", src)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := e.cache.Get("k"); ok {
+		t.Fatal("cache entry outlived the Redis TTL")
+	}
+}
+
+// Write lewat /ingest dan delete membuang entry local cache key itu.
+func TestWriteInvalidatesLocalCache(t *testing.T) {
+	e := newTestEnv(t)
+	e.cache.Add("k", "old", time.Minute)
+	postJSON(ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8), `{"key":"k","value":{"v":"new"}}`)
+	if _, ok := e.cache.Get("k"); ok {
+		t.Fatal("entry left in cache after ingest")
+	}
+	e.cache.Add("k", "old", time.Minute)
+	serve(deleteHandler(e.r, e.ctx, e.inv, e.cold), "DELETE", "/del/*key", "/del/k", "")
+	if _, ok := e.cache.Get("k"); ok {
+		t.Fatal("entry left in cache after delete")
+	}
+}
+
+// Dengan stale-while-revalidate, entry yang baru kedaluwarsa tetap disajikan (stale) sementara
+// fetch di background mengisi value terbaru dari Redis.
+func TestGetServesStaleWhileRevalidating(t *testing.T) {
+	e := newTestEnv(t)
+	e.cache.StaleWhileRevalidate = time.Hour
+	e.r.Set(e.ctx, "k", "new", time.Hour)
+	e.cache.Add("k", "old", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	w := serve(getHandler(e.ctx, e.rd), http.MethodGet, "/get/*key", "/get/k", "")
+	var body struct {
+		Source string `json:"source"`
+		Value  string `json:"value"`
+		Stale  bool   `json:"stale"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Source != "local_cache" || body.Value != "old" || !body.Stale {
+		t.Fatalf("GET = %s, want stale old value from local_cache", w.Body)
+	}
+	eventually(t, "background refresh", func() bool { v, ok := e.cache.Get("k"); return ok && v == "new" })
+	if src, v := e.get(t, "k"); src != "local_cache" || v != "new" {
+		t.Fatalf("GET after refresh = %s %q", src, v)
+	}
+}
+
+// Miss bersamaan untuk key yang sama hanya memicu satu fetch ke Redis dan satu promosi dari cold store.
+func TestConcurrentMissesShareOneFetch(t *testing.T) {
+	e := newTestEnv(t)
+	if err := e.cold.WriteKeyValues([]coldstore.KeyValue{{Key: "k", Value: []byte("C")}}); err != nil {
+		t.Fatal(err)
+	}
+	// Perlambat GET agar semua request bergabung ke fetch yang sedang berjalan
+	e.srv.Hook(func(args []string) error {
+		if args[0] == "get" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil
+	})
+	e.srv.ResetCalls()
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([]getResult, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = e.rd.get(e.ctx, "k")
+		}(i)
+	}
+	wg.Wait()
+
+	if c := e.srv.Calls("get"); c != 1 {
+		t.Fatalf("redis GET called %d times, want 1", c)
+	}
+	for i, res := range results {
+		if res.status != 200 || res.body["source"] != e.cold.Name() || res.body["value"] != "C" {
+			t.Fatalf("result %d = %d %v", i, res.status, res.body)
+		}
+	}
+	if v, ok := e.srv.Get("k"); !ok || v == "" {
+		t.Fatal("cold hit was not promoted")
+	}
+}
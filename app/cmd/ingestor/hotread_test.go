@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// get menjalankan GET /get/<key> lewat getHandler dan mengembalikan source dan value response.
+func (e *testEnv) get(t *testing.T, key string) (source, value string) {
+	t.Helper()
+	w := serve(getHandler(e.ctx, e.rd), http.MethodGet, "/get/*key", "/get/"+key, "")
+	var body struct {
+		Source string `json:"source"`
+		Value  string `json:"value"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v (%s)", key, err, w.Body)
+	}
+	return body.Source, body.Value
+}
+
+// Event "hot_read" yang tersimpan di Redis langsung mengisi local cache dengan value yang sama
+// seperti di Redis, jadi GET pertama tidak perlu ke Redis.
+func TestHotReadFillsLocalCache(t *testing.T) {
+	e := newTestEnv(t)
+	ingest := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	postJSON(ingest, `{"key":"hot","value":{"v":1},"cache_hint":"hot_read"}`)
+	postJSON(ingest, `{"key":"plain","value":{"v":1}}`)
+	e.srv.ResetCalls()
+
+	inRedis, _ := e.srv.Get("hot")
+	if src, v := e.get(t, "hot"); src != "local_cache" || v != inRedis {
+		t.Fatalf("GET hot = %s %s, want local_cache %s", src, v, inRedis)
+	}
+	if n := e.srv.Calls("get"); n != 0 {
+		t.Fatalf("GET of hot_read key sent %d GET to redis", n)
+	}
+	if src, _ := e.get(t, "plain"); src != "redis" {
+		t.Fatalf("GET plain = %s, want redis", src)
+	}
+
+	// Write berikutnya tanpa hint membuang entry lama
+	postJSON(ingest, `{"key":"hot","value":{"v":2}}`)
+	if src, v := e.get(t, "hot"); src != "redis" || v == inRedis {
+		t.Fatalf("GET hot after rewrite = %s %s, want new value from redis", src, v)
+	}
+}
+
+// Event "hot_read" yang overflow ke cold store tidak mengisi local cache.
+func TestHotReadOverflowNotCached(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetMemory(900, 1000)
+	postJSON(ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8), `{"key":"hot","value":{"v":1},"cache_hint":"hot_read"}`)
+	postJSON(batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8), `[{"key":"hot2","value":{"v":1},"cache_hint":"hot_read"}]`)
+	for _, k := range []string{"hot", "hot2"} {
+		if _, ok := e.cache.Get(k); ok {
+			t.Fatalf("overflowed hot_read event %s cached", k)
+		}
+	}
+}
+
+func TestBatchHotReadFillsLocalCache(t *testing.T) {
+	e := newTestEnv(t)
+	postJSON(batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8),
+		`[{"key":"a","value":{"v":1},"cache_hint":"hot_read"},{"key":"b","value":{"v":1}}]`)
+	if v, ok := e.cache.Get("a"); !ok {
+		t.Fatal("batch hot_read item not cached")
+	} else if inRedis, _ := e.srv.Get("a"); v != inRedis {
+		t.Fatalf("cached %s, redis %s", v, inRedis)
+	}
+	if _, ok := e.cache.Get("b"); ok {
+		t.Fatal("batch item without hint cached")
+	}
+}
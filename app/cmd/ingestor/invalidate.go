@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/metricsx"
+)
+
+var (
+	cacheInvalidationsSent = metricsx.NewCounter("ingestor_cache_invalidations_sent_total",
+		"Jumlah key yang diumumkan ke instance ingestor lain lewat cachex.InvalidateChannel")
+	cacheInvalidationsReceived = metricsx.NewCounter("ingestor_cache_invalidations_received_total",
+		"Jumlah key dari instance ingestor lain yang dibuang dari local LRU")
+	cacheInvalidationFailures = metricsx.NewCounter("ingestor_cache_invalidation_failures_total",
+		"Jumlah PUBLISH invalidasi yang gagal (instance lain bisa menyajikan value lama sampai LOCAL_CACHE_MAX_STALE_SECONDS)")
+)
+
+// invalidateMessage adalah isi pesan di cachex.InvalidateChannel.
+type invalidateMessage struct {
+	From string   `json:"from"` // id instance pengirim; pesan milik sendiri diabaikan
+	Keys []string `json:"keys"`
+}
+
+// cacheInvalidator menyebarkan invalidasi local LRU antar replica ingestor lewat Redis pub/sub.
+// Setiap write/delete membuang entry di instance sendiri lalu mem-PUBLISH key-nya; instance lain
+// (run) membuang entry yang sama. Pub/sub tidak menjamin pengiriman: pesan yang hilang
+// dibatasi oleh LOCAL_CACHE_MAX_STALE_SECONDS, dan LRU dikosongkan setiap kali subscription tersambung ulang.
+type cacheInvalidator struct {
+	r     *redis.ClusterClient
+	cache *cachex.Cache
+	reads *reader
+	id    string
+}
+
+func newCacheInvalidator(r *redis.ClusterClient, cache *cachex.Cache, reads *reader) *cacheInvalidator {
+	host, _ := os.Hostname()
+	return &cacheInvalidator{r: r, cache: cache, reads: reads, id: host + "-" + strconv.Itoa(os.Getpid())}
+}
+
+// Invalidate membuang keys dari local LRU lalu mengumumkannya ke instance lain.
+func (ci *cacheInvalidator) Invalidate(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		ci.cache.Remove(k)
+	}
+	ci.Publish(ctx, keys...)
+}
+
+// Publish hanya mengumumkan keys ke instance lain (entry di instance sendiri sudah diisi value baru).
+// Dijalankan sebelum response dikirim, sehingga read ke replica lain setelah write tidak melihat value lama
+// (kecuali PUBLISH gagal). Fetch keys yang sedang berjalan di instance ini juga dilepas (reader.Forget).
+func (ci *cacheInvalidator) Publish(ctx context.Context, keys ...string) {
+	ci.reads.Forget(keys...)
+	if !ci.cache.Enabled || len(keys) == 0 {
+		return
+	}
+	b, err := json.Marshal(invalidateMessage{From: ci.id, Keys: keys})
+	if err == nil {
+		err = ci.r.Publish(ctx, cachex.InvalidateChannel, b).Err()
+	}
+	if err != nil {
+		cacheInvalidationFailures.Inc()
+		log.Printf("cache invalidate publish failed (%d keys): %v", len(keys), err)
+		return
+	}
+	cacheInvalidationsSent.Add(int64(len(keys)))
+}
+
+// run subscribe ke cachex.InvalidateChannel dan membuang key yang ditulis instance lain
+// (entry local LRU dan fetch yang sedang berjalan, seperti write di instance ini).
+func (ci *cacheInvalidator) run(ctx context.Context) {
+	if !ci.cache.Enabled {
+		return
+	}
+	sub := ci.r.Subscribe(ctx, cachex.InvalidateChannel)
+	defer sub.Close()
+	for msg := range sub.ChannelWithSubscriptions() {
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Subscribe pertama atau tersambung ulang: pesan selama terputus mungkin hilang
+			if m.Kind == "subscribe" {
+				ci.cache.Purge()
+			}
+		case *redis.Message:
+			var im invalidateMessage
+			if err := json.Unmarshal([]byte(m.Payload), &im); err != nil || im.From == ci.id {
+				continue
+			}
+			// Fetch yang dimulai sebelum write di instance lain tidak dipakai lagi oleh read berikutnya
+			ci.reads.Forget(im.Keys...)
+			for _, k := range im.Keys {
+				ci.cache.Remove(k)
+			}
+			cacheInvalidationsReceived.Add(int64(len(im.Keys)))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/cachex"
+)
+
+// eventually menunggu sampai cond true (pesan pub/sub dikirim asinkron).
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// Write di satu instance membuang entry local cache key yang sama di instance lain;
+// pesan milik sendiri diabaikan, dan cache dikosongkan saat subscription tersambung.
+func TestInvalidationAcrossInstances(t *testing.T) {
+	e := newTestEnv(t)
+	cache2 := cachex.New()
+	rd2 := newReader(e.r, cache2, e.cold, newPromoter(e.r, cache2, 0.8))
+	inv2 := newCacheInvalidator(e.r, cache2, rd2)
+	inv2.id = "instance-2"
+
+	cache2.Add("before", "v", time.Minute)
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	go inv2.run(ctx)
+	eventually(t, "purge on subscribe", func() bool { _, ok := cache2.Get("before"); return !ok })
+
+	cache2.Add("k", "old", time.Minute)
+	cache2.Add("own", "v", time.Minute)
+	inv2.Publish(e.ctx, "own")
+	postJSON(ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8), `{"key":"k","value":{"v":"new"}}`)
+	eventually(t, "invalidation of k", func() bool { _, ok := cache2.Get("k"); return !ok })
+	if _, ok := cache2.Get("own"); !ok {
+		t.Fatal("instance dropped a key from its own invalidation message")
+	}
+}
+
+// Pesan invalidasi dari instance lain juga melepas fetch key itu yang sedang berjalan,
+// sehingga read berikutnya tidak memakai value yang dibaca sebelum write.
+func TestInvalidationForgetsInFlightFetch(t *testing.T) {
+	e := newTestEnv(t)
+	cache2 := cachex.New()
+	rd2 := newReader(e.r, cache2, e.cold, newPromoter(e.r, cache2, 0.8))
+	inv2 := newCacheInvalidator(e.r, cache2, rd2)
+	inv2.id = "instance-2"
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	go inv2.run(ctx)
+	time.Sleep(20 * time.Millisecond) // tunggu subscribe (dan purge-nya) selesai
+
+	// Fetch lama dilepas setelah 1s agar test gagal (bukan hang) jika Forget tidak dipanggil
+	started, release := make(chan struct{}), make(chan struct{})
+	time.AfterFunc(time.Second, func() { close(release) })
+	go rd2.gets.Do("k", func() (getResult, error) {
+		close(started)
+		<-release
+		return getResult{status: 200}, nil
+	})
+	<-started
+
+	cache2.Add("k", "old", time.Minute)
+	e.inv.Invalidate(e.ctx, "k")
+	// Forget dijalankan sebelum Remove saat pesan diterima
+	eventually(t, "invalidation of k", func() bool { _, ok := cache2.Get("k"); return !ok })
+	res, _, shared := rd2.gets.Do("k", func() (getResult, error) { return getResult{status: 404}, nil })
+	if shared || res.status != 404 {
+		t.Fatalf("read after invalidation joined the old fetch (shared %v, status %d)", shared, res.status)
+	}
+}
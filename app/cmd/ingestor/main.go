@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -11,7 +12,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"monolith-kv-sim/internal/cachex"
-	"monolith-kv-sim/internal/hdfsx"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/metricsx"
 	"monolith-kv-sim/internal/redisx"
 )
 
@@ -24,7 +26,7 @@ func seedOldKeysHandler(r *redis.ClusterClient, ctx context.Context) gin.Handler
 				count = n
 			}
 		}
-		ts := time.Now().Unix() - 120 // 2 menit lalu
+		ts := time.Now().Add(-2 * time.Minute).UnixMilli()
 		for i := 0; i < count; i++ {
 			key := "seed:old:" + strconv.Itoa(i)
 			val := map[string]any{"_ts": ts, "seed": true, "i": i}
@@ -35,43 +37,23 @@ func seedOldKeysHandler(r *redis.ClusterClient, ctx context.Context) gin.Handler
 	}
 }
 
-// Event merepresentasikan data event yang akan diingest ke sistem.
-// Key: identifier unik untuk event ini
-// Value: data payload dalam bentuk map
-// TTLSeconds: waktu hidup data di Redis (dalam detik)
-// CacheHint: petunjuk apakah data ini hot (sering diakses) atau tidak
-type Event struct {
-	Key        string                 `json:"key"`
-	Value      map[string]any         `json:"value"`
-	TTLSeconds int                    `json:"ttl_sec"`
-	CacheHint  string                 `json:"cache_hint"`
-}
-
-func main() {
-	// Inisialisasi koneksi ke Redis cluster (in-memory cache)
-	r := redisx.NewCluster()
-	ctx := context.Background()
-
-	// Ambil threshold penggunaan memori dari environment variable.
-	// Jika penggunaan memori Redis >= threshold ini, data akan diarahkan ke HDFS.
-	soft := 0.80
-	if s := os.Getenv("REDIS_MAXMEM_SOFT"); s != "" {
-		if v, err := strconv.ParseFloat(s, 64); err == nil {
-			soft = v
-		}
+// keyParam mengambil key dari wildcard param route (/get/*key, /del/*key).
+// Leading slash dari wildcard Gin dibuang.
+func keyParam(c *gin.Context) string {
+	key := c.Param("key")
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
 	}
+	return key
+}
 
-	// Inisialisasi local LRU cache untuk hot keys (opsional, untuk optimasi)
-	cache := cachex.NewLRU()
-	// Inisialisasi writer untuk HDFS (on-disk KV store)
-	hdfs := hdfsx.NewWriter()
-
-	// Setup Gin router untuk HTTP API
-	router := gin.Default()
-
-	// Endpoint POST /ingest: menerima event dan menyimpannya ke Redis atau HDFS
-	// Mengimplementasikan cache-aside pattern dengan overflow ke HDFS
-	router.POST("/ingest", func(c *gin.Context) {
+// ingestHandler mengimplementasikan POST /ingest untuk satu event.
+// Event disimpan di Redis selama rasio memori cluster di bawah soft; di atasnya (atau jika SET gagal)
+// event ditulis ke cold store sebagai overflow. Setelah overflow berhasil, salinan lama key di Redis
+// di-DEL: GET membaca Redis sebelum cold store, jadi value lama itu akan menutupi event yang baru ditulis.
+// Jika DEL gagal, handler membalas 503 agar client mengulang write.
+func ingestHandler(r *redis.ClusterClient, ctx context.Context, cache *cachex.Cache, inv *cacheInvalidator, cold coldstore.ColdStore, soft float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		var ev Event
 		// Validasi input: pastikan event memiliki key dan value yang valid
 		if err := c.ShouldBindJSON(&ev); err != nil || ev.Key == "" || ev.Value == nil {
@@ -82,97 +64,214 @@ func main() {
 		if ev.TTLSeconds <= 0 {
 			ev.TTLSeconds = 3600
 		}
-
-		// Jika event ditandai sebagai "hot_read", tambahkan ke local LRU cache
-		// Ini membantu mengurangi beban ke Redis untuk data yang sering diakses
-		if cache.Enabled && ev.CacheHint == "hot_read" {
-			cache.LRU.Add(ev.Key, "cached") // Flag saja, nilai aktual di-cache di path read
-		}
+		// Serialize nilai event ke JSON sebelum memilih tier (dengan _ts, lihat encodePayload).
+		// encodePayload juga menulis _ts ke ev.Value, sehingga event yang overflow ke cold store
+		// membawa _ts yang sama seperti value di Redis.
+		b, encErr := encodePayload(&ev)
+		// Write invalidation: setelah write selesai (tier mana pun), entry lama di local LRU semua
+		// instance dibuang agar GET berikutnya tidak menyajikan value lama; di instance ini kecuali
+		// entry baru dari hot_read di bawah
+		cached := false
+		defer func() {
+			if cached {
+				inv.Publish(ctx, ev.Key)
+			} else {
+				inv.Invalidate(ctx, ev.Key)
+			}
+		}()
 
 		// Cek rasio penggunaan memori Redis cluster
 		// Jika sudah mencapai threshold (misalnya 80%), alihkan ke HDFS
 		ratio, _ := redisx.ClusterMemRatio(ctx, r)
 		if ratio >= soft {
 			// Simpan ke HDFS karena Redis sudah penuh
-			_ = hdfs.WriteJSONL([]any{ev})
-			c.JSON(200, gin.H{"ok": true, "stored": "hdfs", "mem_ratio": ratio})
+			if err := cold.WriteEvents([]any{ev}); err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": err.Error(), "mem_ratio": ratio})
+				return
+			}
+			if err := r.Del(ctx, ev.Key).Err(); err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "stored": cold.Name(), "error": "redis delete failed: " + err.Error(), "mem_ratio": ratio})
+				return
+			}
+			c.JSON(200, gin.H{"ok": true, "stored": cold.Name(), "mem_ratio": ratio})
 			return
 		}
 
-		// Serialize nilai event ke JSON sebelum disimpan di Redis.
-		// Tambah _ts (timestamp) agar offloader bisa tahu umur data dan memindahkan yang sudah lama ke HDFS.
-		payload := ev.Value
-		if payload == nil {
-			payload = make(map[string]any)
-		}
-		payload["_ts"] = time.Now().Unix()
-		b, err := json.Marshal(payload)
-		if err != nil {
+		if encErr != nil {
 			// Jika gagal serialize, fallback ke HDFS
-			_ = hdfs.WriteJSONL([]any{ev})
-			c.JSON(200, gin.H{"ok": false, "stored": "hdfs", "error": err.Error(), "mem_ratio": ratio})
+			_ = cold.WriteEvents([]any{ev})
+			_ = r.Del(ctx, ev.Key).Err()
+			c.JSON(200, gin.H{"ok": false, "stored": cold.Name(), "error": encErr.Error(), "mem_ratio": ratio})
 			return
 		}
 
 		// Coba simpan ke Redis cluster dengan TTL yang ditentukan
-		err = r.Set(ctx, ev.Key, b, time.Duration(ev.TTLSeconds)*time.Second).Err()
+		err := r.Set(ctx, ev.Key, b, time.Duration(ev.TTLSeconds)*time.Second).Err()
 		if err != nil {
-			// Jika gagal menyimpan ke Redis (misalnya karena OOM), fallback ke HDFS
-			_ = hdfs.WriteJSONL([]any{ev})
-			c.JSON(200, gin.H{"ok": true, "stored": "hdfs", "error": err.Error(), "mem_ratio": ratio})
+			// Jika gagal menyimpan ke Redis (misalnya OOM karena noeviction), fallback ke HDFS.
+			// Jika cold store juga gagal, balas 503 agar client mengulang (event tidak hilang diam-diam).
+			// Salinan lama key di Redis dihapus seperti di cabang soft limit (DEL tetap diizinkan saat OOM);
+			// jika DEL gagal, GET masih menyajikan value lama, jadi balas 503 juga.
+			if redisx.IsOOM(err) {
+				redisOOMTotal.Inc()
+			}
+			if werr := cold.WriteEvents([]any{ev}); werr != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "error": werr.Error(), "redis_error": err.Error(), "mem_ratio": ratio})
+				return
+			}
+			if derr := r.Del(ctx, ev.Key).Err(); derr != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "stored": cold.Name(), "error": "redis delete failed: " + derr.Error(), "redis_error": err.Error(), "mem_ratio": ratio})
+				return
+			}
+			c.JSON(200, gin.H{"ok": true, "stored": cold.Name(), "error": err.Error(), "mem_ratio": ratio})
 			return
 		}
+		// Jika event ditandai sebagai "hot_read", value yang baru disimpan langsung dimasukkan ke local LRU
+		// (key dan isi sama seperti yang diisi path GET), sehingga read pertama pun tidak perlu ke Redis
+		if ev.CacheHint == "hot_read" {
+			cache.Add(ev.Key, string(b), time.Duration(ev.TTLSeconds)*time.Second)
+			cached = true
+		}
 		// Berhasil disimpan di Redis
 		c.JSON(200, gin.H{"ok": true, "stored": "redis", "mem_ratio": ratio})
-	})
+	}
+}
 
-	// Endpoint GET /get/*key: mengambil data berdasarkan key
-	// Mengimplementasikan cache-aside pattern: cek local cache -> Redis -> HDFS (jika perlu)
-	router.GET("/get/*key", func(c *gin.Context) {
-		key := c.Param("key")
-		// Bersihkan key dari leading slash jika ada
-		if len(key) > 0 && key[0] == '/' {
-			key = key[1:]
-		}
+// deleteHandler menghapus key dari setiap tier penyimpanan.
+// Urutan: tombstone + hapus file di cold store dulu, lalu DEL di Redis, terakhir local LRU.
+// Tombstone diumumkan lewat coldstore.InvalidateChannel sebelum DEL, agar instance lain memuat
+// ulang daftar tombstone sebelum key hilang dari Redis dan read jatuh ke cold store.
+// Terakhir entry local LRU dibuang (di instance ini dan, lewat pub/sub, di instance lain).
+// Remove menaikkan generation key, sehingga GET yang membaca value lama dari Redis sebelum DEL
+// tapi selesai sesudahnya tidak bisa mengisi ulang cache (cachex.Cache.AddIfGeneration).
+func deleteHandler(r *redis.ClusterClient, ctx context.Context, inv *cacheInvalidator, cold coldstore.ColdStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyParam(c)
 		if key == "" {
 			c.JSON(400, gin.H{"ok": false, "error": "missing key"})
 			return
 		}
 
-		// Cache-aside pattern: cek local LRU cache dulu (jika enabled)
-		// Ini mengurangi latency untuk hot keys yang sering diakses
-		if cache.Enabled {
-			if v, ok := cache.LRU.Get("VAL:" + key); ok {
-				c.JSON(200, gin.H{"ok": true, "source": "local_cache", "value": v})
-				return
-			}
+		if err := cold.Delete(key); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": cold.Name() + " delete failed: " + err.Error()})
+			return
 		}
-
-		// Jika tidak ada di local cache, coba ambil dari Redis cluster
-		val, err := r.Get(ctx, key).Result()
+		if err := r.Publish(ctx, coldstore.InvalidateChannel, cold.Name()).Err(); err != nil {
+			log.Printf("cold store invalidate publish failed: %v", err)
+		}
+		n, err := r.Del(ctx, key).Result()
 		if err != nil {
-			// Sesuai diagram: jika tidak ditemukan di cache, baca dari on-disk KV-Store (HDFS)
-			if buf, readErr := hdfs.ReadByKey(key); readErr == nil {
-				c.JSON(200, gin.H{"ok": true, "source": "hdfs", "value": string(buf)})
-				return
-			}
-			c.JSON(404, gin.H{"ok": false, "error": err.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": "redis delete failed: " + err.Error()})
 			return
 		}
-		// Jika ditemukan di Redis, cache di local LRU untuk akses berikutnya
-		if cache.Enabled {
-			cache.LRU.Add("VAL:"+key, val)
+		inv.Invalidate(ctx, key)
+		c.JSON(200, gin.H{"ok": true, "key": key, "redis_deleted": n, cold.Name(): "tombstoned"})
+	}
+}
+
+// Event merepresentasikan data event yang akan diingest ke sistem.
+// Key: identifier unik untuk event ini
+// Value: data payload dalam bentuk map
+// TTLSeconds: waktu hidup data di Redis (dalam detik)
+// CacheHint: petunjuk apakah data ini hot (sering diakses) atau tidak
+type Event struct {
+	Key        string         `json:"key"`
+	Value      map[string]any `json:"value"`
+	TTLSeconds int            `json:"ttl_sec"`
+	CacheHint  string         `json:"cache_hint"`
+}
+
+// encodePayload men-serialize Value event ke JSON untuk disimpan di Redis.
+// Tambah _ts (unix milidetik) agar offloader bisa tahu umur data dan memindahkan yang sudah lama ke HDFS.
+// _ts juga ditulis ke ev.Value, sehingga event yang kemudian ditulis ke cold store (overflow) ikut membawanya.
+func encodePayload(ev *Event) ([]byte, error) {
+	if ev.Value == nil {
+		ev.Value = make(map[string]any)
+	}
+	payload := ev.Value
+	payload["_ts"] = time.Now().UnixMilli()
+	return json.Marshal(payload)
+}
+
+func main() {
+	// Inisialisasi koneksi ke Redis cluster (in-memory cache)
+	r := redisx.NewCluster()
+	ctx := context.Background()
+
+	// Ambil threshold penggunaan memori dari environment variable.
+	// Jika penggunaan memori Redis >= threshold ini, data akan diarahkan ke HDFS.
+	soft := 0.80
+	if s := os.Getenv("REDIS_MAXMEM_SOFT"); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			soft = v
+		}
+	}
+
+	// Inisialisasi local LRU cache untuk hot keys (opsional, untuk optimasi)
+	cache := cachex.New()
+	// Inisialisasi cold store (on-disk KV store): HDFS, local, atau S3 sesuai COLD_STORE
+	cold := coldstore.New()
+	// Promosi value hasil baca HDFS kembali ke Redis (HDFS_PROMOTE, HDFS_PROMOTE_TTL_SECONDS)
+	promote := newPromoter(r, cache, soft)
+	// Read path untuk key yang miss di local cache; miss bersamaan untuk key yang sama berbagi satu fetch
+	rd := newReader(r, cache, cold, promote)
+	// Offloader/compactor mengumumkan data baru di cold store lewat pub/sub,
+	// sehingga Bloom filter & index lokal dimuat ulang tanpa menunggu HDFS_INDEX_REFRESH_SECONDS
+	go func() {
+		sub := r.Subscribe(ctx, coldstore.InvalidateChannel)
+		for range sub.Channel() {
+			cold.Invalidate()
 		}
-		c.JSON(200, gin.H{"ok": true, "source": "redis", "value": val})
+	}()
+	// Hitung key yang di-evict Redis (seharusnya nol dengan policy noeviction)
+	go watchEvictions(ctx, r)
+	// Invalidasi local LRU antar replica ingestor (cachex.InvalidateChannel)
+	inv := newCacheInvalidator(r, cache, rd)
+	go inv.run(ctx)
+
+	// Setup Gin router untuk HTTP API
+	router := gin.Default()
+
+	// Endpoint POST /ingest: menerima event dan menyimpannya ke Redis atau HDFS
+	// Mengimplementasikan cache-aside pattern dengan overflow ke HDFS
+	router.POST("/ingest", ingestHandler(r, ctx, cache, inv, cold, soft))
+
+	// Endpoint POST /ingest/batch: menerima banyak event sekaligus (JSON array atau NDJSON)
+	// Write ke Redis di-pipeline per slot, overflow ke HDFS dalam satu file JSONL
+	router.POST("/ingest/batch", batchIngestHandler(r, ctx, cache, inv, cold, soft))
+
+	// Endpoint GET /get/*key: mengambil data berdasarkan key
+	// Mengimplementasikan cache-aside pattern: cek local cache -> Redis -> HDFS (jika perlu)
+	router.GET("/get/*key", getHandler(ctx, rd))
+
+	// Endpoint POST /mget: membaca banyak key sekaligus (local LRU -> Redis MGET per slot -> HDFS)
+	router.POST("/mget", mgetHandler(r, ctx, cache, rd))
+
+	// Endpoint DELETE /del/*key: menghapus key dari semua tier (local LRU, Redis, HDFS)
+	router.DELETE("/del/*key", deleteHandler(r, ctx, inv, cold))
+
+	// Endpoint GET /metrics: counter aplikasi (mis. promosi HDFS -> Redis) untuk Prometheus
+	router.GET("/metrics", gin.WrapH(metricsx.Handler()))
+
+	// Endpoint GET /cache/stats: statistik local cache (hit/miss/eviction/admission, byte terpakai)
+	router.GET("/cache/stats", func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true, "enabled": cache.Enabled, "stats": cache.Stats()})
 	})
+	metricsx.NewGaugeFunc("ingestor_local_cache_bytes", "Byte yang terpakai local cache (key + value + overhead per entry)",
+		func() float64 { return float64(cache.Stats().Bytes) })
+	metricsx.NewGaugeFunc("ingestor_local_cache_entries", "Jumlah entry di local cache",
+		func() float64 { return float64(cache.Stats().Entries) })
+	metricsx.NewGaugeFunc("ingestor_local_cache_hit_ratio", "Rasio hit local cache sejak start",
+		func() float64 { return cache.Stats().HitRatio })
 
 	// Seed key dengan _ts di masa lalu agar offloader bisa memindahkan ke HDFS (uji deterministik).
 	// GET/POST /seed-old-keys?count=20 menulis 20 key ke Redis dengan _ts = 2 menit lalu.
 	router.GET("/seed-old-keys", seedOldKeysHandler(r, ctx))
 	router.POST("/seed-old-keys", seedOldKeysHandler(r, ctx))
 
-	// Test koneksi ke Redis sebelum start server
+	// Test koneksi ke Redis dan siapkan root cold store sebelum start server
 	_ = r.Ping(ctx).Err()
+	_ = cold.Init()
 	// Start HTTP server di port 8080
 	router.Run(":8080")
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+// testEnv adalah satu instance ingestor di atas Redis in-memory dan cold store local di direktori sementara.
+type testEnv struct {
+	ctx   context.Context
+	srv   *redistest.Server
+	r     *redis.ClusterClient
+	cache *cachex.Cache
+	cold  coldstore.ColdStore
+	rd    *reader
+	inv   *cacheInvalidator
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("COLD_STORE", "local")
+	t.Setenv("COLD_STORE_DIR", t.TempDir())
+	e := &testEnv{ctx: context.Background(), srv: redistest.NewServer(t)}
+	e.r = e.srv.Cluster(t)
+	e.cache = cachex.New()
+	e.cold = coldstore.New()
+	if err := e.cold.Init(); err != nil {
+		t.Fatal(err)
+	}
+	e.rd = newReader(e.r, e.cache, e.cold, newPromoter(e.r, e.cache, 0.8))
+	e.inv = newCacheInvalidator(e.r, e.cache, e.rd)
+	return e
+}
+
+// serve menjalankan satu request ke handler h yang dipasang di route (mis. "/get/*key").
+func serve(h gin.HandlerFunc, method, route, target, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, h)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+// postJSON adalah serve untuk handler POST tanpa path param.
+func postJSON(h gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	return serve(h, http.MethodPost, "/", "/", body)
+}
+
+func TestIngestStoresInRedis(t *testing.T) {
+	e := newTestEnv(t)
+	h := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	w := postJSON(h, `{"key":"k","value":{"n":1},"ttl_sec":60}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stored":"redis"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	v, ok := e.srv.Get("k")
+	if !ok || !strings.Contains(v, `"n":1`) || !strings.Contains(v, `"_ts":`) {
+		t.Fatalf("redis k = %q, %t", v, ok)
+	}
+	if ttl := e.srv.TTL("k"); ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("TTL = %s", ttl)
+	}
+	if w := postJSON(h, `{"key":"","value":{}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid event: status = %d", w.Code)
+	}
+}
+
+// Di atas threshold soft event ditulis ke cold store dan salinan lama di Redis dihapus,
+// sehingga GET mengembalikan event baru dari cold store.
+func TestIngestOverflowDropsStaleRedisCopy(t *testing.T) {
+	e := newTestEnv(t)
+	h := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	if w := postJSON(h, `{"key":"k","value":{"v":"old"}}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	e.srv.SetMemory(900, 1000)
+	w := postJSON(h, `{"key":"k","value":{"v":"new"}}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stored":"local"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if v, ok := e.srv.Get("k"); ok {
+		t.Fatalf("stale copy left in redis: %s", v)
+	}
+	res := e.rd.get(e.ctx, "k")
+	if res.status != http.StatusOK || res.body["source"] != "local" || !strings.Contains(res.body["value"].(string), `"v":"new"`) {
+		t.Fatalf("GET k = %d %v", res.status, res.body)
+	}
+}
+
+func TestIngestOverflowDelFailure(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetMemory(900, 1000)
+	e.srv.Hook(func(args []string) error {
+		if args[0] == "del" {
+			return errors.New("ERR injected")
+		}
+		return nil
+	})
+	h := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	if w := postJSON(h, `{"key":"k","value":{"v":1}}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", w.Code, w.Body)
+	}
+}
+
+// offload memindahkan value key dari Redis ke cold store seperti offloader (TS record dari _ts value).
+func (e *testEnv) offload(t *testing.T, key string) {
+	t.Helper()
+	v, ok := e.srv.Get(key)
+	if !ok {
+		t.Fatalf("offload %s: not in redis", key)
+	}
+	if err := e.cold.WriteKeyValues([]coldstore.KeyValue{{Key: key, Value: []byte(v)}}); err != nil {
+		t.Fatal(err)
+	}
+	e.r.Del(e.ctx, key)
+}
+
+// DELETE lalu ingest ulang dalam detik yang sama, lalu offload: value baru tidak boleh dianggap terhapus.
+func TestReingestAfterDeleteSurvivesOffload(t *testing.T) {
+	e := newTestEnv(t)
+	ingest := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	del := deleteHandler(e.r, e.ctx, e.inv, e.cold)
+
+	postJSON(ingest, `{"key":"k","value":{"v":"old"}}`)
+	e.offload(t, "k")
+	if w := serve(del, http.MethodDelete, "/del/*key", "/del/k", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	time.Sleep(2 * time.Millisecond)
+	postJSON(ingest, `{"key":"k","value":{"v":"new"}}`)
+	e.offload(t, "k")
+
+	if v, err := e.cold.ReadByKey("k"); err != nil || !strings.Contains(string(v), `"v":"new"`) {
+		t.Fatalf("cold k = %s, %v", v, err)
+	}
+}
+
+// Overflow lalu write ke Redis dalam detik yang sama, lalu offload: value Redis (lebih baru) menang.
+func TestRedisWriteAfterOverflowWinsAfterOffload(t *testing.T) {
+	e := newTestEnv(t)
+	ingest := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+
+	e.srv.SetMemory(900, 1000)
+	if w := postJSON(ingest, `{"key":"k","value":{"v":"overflow"}}`); !strings.Contains(w.Body.String(), `"stored":"local"`) {
+		t.Fatalf("overflow ingest: %s", w.Body)
+	}
+	e.srv.SetMemory(0, 1000)
+	time.Sleep(2 * time.Millisecond)
+	if w := postJSON(ingest, `{"key":"k","value":{"v":"redis"}}`); !strings.Contains(w.Body.String(), `"stored":"redis"`) {
+		t.Fatalf("redis ingest: %s", w.Body)
+	}
+	e.offload(t, "k")
+
+	if v, err := e.cold.ReadByKey("k"); err != nil || !strings.Contains(string(v), `"v":"redis"`) {
+		t.Fatalf("cold k = %s, %v", v, err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// mgetRequest adalah body POST /mget.
+type mgetRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// mgetItem adalah hasil per key dari POST /mget.
+// Source sama dengan GET /get/*key: "local_cache", "redis", atau nama cold store ("hdfs", ...).
+type mgetItem struct {
+	Key      string `json:"key"`
+	Found    bool   `json:"found"`
+	Source   string `json:"source,omitempty"`
+	Type     string `json:"type,omitempty"`  // hanya untuk key non-string
+	Value    any    `json:"value,omitempty"` // string, atau typed envelope JSON (lihat typedValue)
+	Promoted bool   `json:"promoted,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// mgetColdWorkers membatasi jumlah ReadByKey ke cold store yang berjalan bersamaan.
+const mgetColdWorkers = 8
+
+// mgetHandler membaca banyak key dalam satu request dengan rantai yang sama seperti GET:
+// 1. local LRU cache
+// 2. Redis MGET, dikelompokkan per hash slot (semua MGET dikirim dalam satu pipeline)
+// 3. Cold store ReadByKey, hanya untuk key yang miss di Redis (hit dipromosikan ke Redis via promoter)
+// Key duplikat di request hanya di-resolve sekali; urutan hasil mengikuti input. Baca cold store
+// bersamaan dengan GET/MGET lain untuk key yang sama digabung (lihat reader.readCold).
+func mgetHandler(r *redis.ClusterClient, ctx context.Context, cache *cachex.Cache, rd *reader) gin.HandlerFunc {
+	maxKeys := 1000
+	if s := os.Getenv("MGET_MAX_KEYS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			maxKeys = v
+		}
+	}
+
+	return func(c *gin.Context) {
+		var req mgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Keys) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid request: expected {\"keys\": [...]}"})
+			return
+		}
+		if len(req.Keys) > maxKeys {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "error": "too many keys", "max_keys": maxKeys})
+			return
+		}
+
+		// Dedup key (key kosong langsung ditandai invalid)
+		resolved := make(map[string]*mgetItem, len(req.Keys))
+		var unique []string
+		for _, k := range req.Keys {
+			if _, ok := resolved[k]; ok {
+				continue
+			}
+			it := &mgetItem{Key: k}
+			resolved[k] = it
+			if k == "" {
+				it.Error = "missing key"
+				continue
+			}
+			unique = append(unique, k)
+		}
+
+		// Tier 1: local LRU cache
+		var misses []string
+		for _, k := range unique {
+			if v, ok := cache.Get(k); ok {
+				*resolved[k] = mgetItem{Key: k, Found: true, Source: "local_cache", Value: v}
+				continue
+			}
+			misses = append(misses, k)
+		}
+
+		// Tier 2: Redis MGET per slot
+		misses = mgetRedis(ctx, r, cache, misses, resolved)
+
+		// Tier 3: cold store, hanya untuk key yang tidak ada di Redis
+		mgetCold(ctx, rd, misses, resolved)
+
+		results := make([]mgetItem, len(req.Keys))
+		found := 0
+		for i, k := range req.Keys {
+			results[i] = *resolved[k]
+			if results[i].Found {
+				found++
+			}
+		}
+		c.JSON(200, gin.H{"ok": true, "count": len(results), "found": found, "results": results})
+	}
+}
+
+// mgetRedis menjalankan satu MGET per hash slot untuk keys, semuanya dalam satu pipeline
+// (cluster client akan meneruskan tiap MGET ke node pemilik slot). PTTL tiap key ikut
+// di pipeline yang sama untuk expiry entry local LRU.
+// Hit ditulis ke resolved dan di-cache di local LRU seperti pada GET (generation diambil sebelum MGET);
+// untuk key yang miss, gagal dibaca, atau sudah expired, entry local LRU-nya dibuang seperti di reader.fetch.
+// Berbeda dengan GET, baca Redis di sini tidak lewat reader.gets: Group menggabungkan fetch per key,
+// sedangkan MGET membaca semua key dalam satu round trip per batch. Baca cold store untuk key yang miss
+// tetap digabung dengan GET lewat reader.readCold.
+// Mengembalikan key yang miss (atau gagal dibaca) di Redis.
+func mgetRedis(ctx context.Context, r *redis.ClusterClient, cache *cachex.Cache, keys []string, resolved map[string]*mgetItem) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+	groups := redisx.GroupBySlot(keys)
+	type slotCmd struct {
+		idx []int
+		cmd *redis.SliceCmd
+	}
+	cmds := make([]slotCmd, 0, len(groups))
+	pttls := make([]*redis.DurationCmd, len(keys))
+	gens := make([]uint64, len(keys))
+	for i, k := range keys {
+		gens[i] = cache.Generation(k)
+	}
+	_, _ = r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, idx := range groups {
+			args := make([]string, len(idx))
+			for n, i := range idx {
+				args[n] = keys[i]
+			}
+			cmds = append(cmds, slotCmd{idx: idx, cmd: p.MGet(ctx, args...)})
+			for _, i := range idx {
+				pttls[i] = p.PTTL(ctx, keys[i])
+			}
+		}
+		return nil
+	})
+
+	var misses, nils []string
+	for _, sc := range cmds {
+		vals, err := sc.cmd.Result()
+		for n, i := range sc.idx {
+			k := keys[i]
+			if err != nil {
+				// Error Redis (mis. node down): simpan pesan, tetap coba fallback ke cold store
+				cache.Remove(k)
+				resolved[k].Error = err.Error()
+				misses = append(misses, k)
+				continue
+			}
+			v, ok := vals[n].(string)
+			if !ok {
+				cache.Remove(k)
+				nils = append(nils, k)
+				continue
+			}
+			*resolved[k] = mgetItem{Key: k, Found: true, Source: "redis", Value: v}
+			if ttl, err := pttls[i].Result(); err == nil && ttl != -2 {
+				cache.AddIfGeneration(k, v, ttl, gens[i])
+			} else {
+				cache.Remove(k)
+			}
+		}
+	}
+	if len(nils) == 0 {
+		return misses
+	}
+
+	// MGET mengembalikan nil juga untuk key non-string; cek tipenya sebelum fallback ke cold store
+	tvals, errs := redisx.ReadTypedMany(ctx, r, nils)
+	for i, k := range nils {
+		if errs[i] != nil || tvals[i].Type == redisx.TypeString {
+			misses = append(misses, k)
+			continue
+		}
+		*resolved[k] = mgetItem{Key: k, Found: true, Source: "redis", Type: tvals[i].Type, Value: typedValue(tvals[i].Type, tvals[i].Value)}
+	}
+	return misses
+}
+
+// mgetCold membaca keys dari cold store secara paralel (dibatasi mgetColdWorkers).
+// Key yang tidak ditemukan tetap Found=false; tombstone dilaporkan sebagai error.
+func mgetCold(ctx context.Context, rd *reader, keys []string, resolved map[string]*mgetItem) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, mgetColdWorkers)
+	for _, k := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		// Setiap goroutine hanya menulis ke item miliknya sendiri (map tidak dimodifikasi)
+		go func(it *mgetItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cr, err := rd.readCold(ctx, it.Key)
+			if err == nil {
+				rec := cr.rec
+				*it = mgetItem{Key: it.Key, Found: true, Source: rd.cold.Name(), Type: rec.Type, Value: typedValue(rec.Type, rec.Value), Promoted: cr.promoted}
+				return
+			}
+			if errors.Is(err, coldstore.ErrDeleted) {
+				it.Error = err.Error()
+			}
+		}(resolved[k])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+type mgetResponse struct {
+	OK      bool `json:"ok"`
+	Found   int  `json:"found"`
+	Results []struct {
+		Key      string          `json:"key"`
+		Found    bool            `json:"found"`
+		Source   string          `json:"source"`
+		Type     string          `json:"type"`
+		Value    json.RawMessage `json:"value"`
+		Promoted bool            `json:"promoted"`
+		Error    string          `json:"error"`
+	} `json:"results"`
+}
+
+// Satu request mengambil key dari setiap tier; hasil mengikuti urutan input termasuk key duplikat.
+func TestMGetFanOut(t *testing.T) {
+	e := newTestEnv(t)
+	h := mgetHandler(e.r, e.ctx, e.cache, e.rd)
+
+	e.cache.Add("local", "L", time.Hour)
+	if err := e.r.Set(e.ctx, "{x}redis", "R", time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.r.HSet(e.ctx, "hash", "f", "v").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.cold.WriteKeyValues([]coldstore.KeyValue{
+		{Key: "cold", Value: []byte("C")},
+		{Key: "gone", Value: []byte("G")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := e.cold.Delete("gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := postJSON(h, `{"keys":["cold","{x}redis","local","missing","hash","","gone","{x}redis"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp mgetResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		key, source, value string
+		found, err         bool
+	}{
+		{"cold", "local", `"C"`, true, false},
+		{"{x}redis", "redis", `"R"`, true, false},
+		{"local", "local_cache", `"L"`, true, false},
+		{"missing", "", "", false, false},
+		{"hash", "redis", `{"f":"v"}`, true, false},
+		{"", "", "", false, true},
+		{"gone", "", "", false, true},
+		{"{x}redis", "redis", `"R"`, true, false},
+	}
+	if len(resp.Results) != len(want) || resp.Found != 5 {
+		t.Fatalf("found = %d, results = %+v", resp.Found, resp.Results)
+	}
+	for i, it := range resp.Results {
+		w := want[i]
+		if it.Key != w.key || it.Found != w.found || it.Source != w.source || string(it.Value) != w.value || (it.Error != "") != w.err {
+			t.Errorf("result %d = %+v, want %+v", i, it, w)
+		}
+	}
+	if !resp.Results[0].Promoted {
+		t.Error("cold hit was not promoted")
+	}
+	if v, ok := e.srv.Get("cold"); !ok || v != "C" {
+		t.Fatalf("promoted value in redis = %q, %t", v, ok)
+	}
+	// Hit Redis string masuk local cache
+	if v, ok := e.cache.Get("{x}redis"); !ok || v != "R" {
+		t.Fatalf("local cache {x}redis = %q, %t", v, ok)
+	}
+}
+
+func TestMGetRejectsBadRequests(t *testing.T) {
+	e := newTestEnv(t)
+	t.Setenv("MGET_MAX_KEYS", "2")
+	h := mgetHandler(e.r, e.ctx, e.cache, e.rd)
+	if w := postJSON(h, `{"keys":[]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty keys: status = %d", w.Code)
+	}
+	if w := postJSON(h, `{"keys":["a","b","c"]}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("too many keys: status = %d", w.Code)
+	}
+}
+
+// Key yang tidak ada lagi di Redis membuang entry local cache-nya yang sudah kedaluwarsa
+// (entry stale tidak disajikan GET berikutnya), seperti pada GET.
+func TestMGetMissDropsStaleCacheEntry(t *testing.T) {
+	e := newTestEnv(t)
+	e.cache.StaleWhileRevalidate = time.Hour
+	e.cache.Add("k", "old", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	var resp mgetResponse
+	w := postJSON(mgetHandler(e.r, e.ctx, e.cache, e.rd), `{"keys":["k"]}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Found != 0 {
+		t.Fatalf("MGET = %s, want miss", w.Body)
+	}
+	if _, _, ok := e.cache.GetStale("k"); ok {
+		t.Fatal("stale entry left in local cache after MGET miss")
+	}
+}
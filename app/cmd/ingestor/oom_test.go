@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// SET ditolak OOM (noeviction) di bawah threshold soft: event jatuh ke cold store dan
+// salinan lama key di Redis dihapus agar GET tidak menyajikan value lama.
+func TestIngestOOMFallbackDropsStaleRedisCopy(t *testing.T) {
+	e := newTestEnv(t)
+	h := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	if w := postJSON(h, `{"key":"k","value":{"v":"old"}}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	e.srv.SetOOM(true)
+	w := postJSON(h, `{"key":"k","value":{"v":"new"}}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stored":"local"`) {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if v, ok := e.srv.Get("k"); ok {
+		t.Fatalf("stale copy left in redis: %s", v)
+	}
+	res := e.rd.get(e.ctx, "k")
+	if res.status != http.StatusOK || res.body["source"] != "local" || !strings.Contains(res.body["value"].(string), `"v":"new"`) {
+		t.Fatalf("GET k = %d %v", res.status, res.body)
+	}
+}
+
+func TestIngestOOMFallbackDelFailure(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetOOM(true)
+	e.srv.Hook(func(args []string) error {
+		if args[0] == "del" {
+			return errors.New("ERR injected")
+		}
+		return nil
+	})
+	h := ingestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	w := postJSON(h, `{"key":"k","value":{"v":1}}`)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "redis delete failed") {
+		t.Fatalf("status = %d, want 503: %s", w.Code, w.Body)
+	}
+}
+
+func TestBatchIngestOOMFallbackDropsStaleRedisCopies(t *testing.T) {
+	e := newTestEnv(t)
+	h := batchIngestHandler(e.r, e.ctx, e.cache, e.inv, e.cold, 0.8)
+	postJSON(h, `[{"key":"{a}1","value":{"v":"old"}},{"key":"{b}1","value":{"v":"old"}}]`)
+
+	e.srv.SetOOM(true)
+	w := postJSON(h, `[{"key":"{a}1","value":{"v":"new"}},{"key":"{b}1","value":{"v":"new"}}]`)
+	var resp struct {
+		Results []batchItemResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range resp.Results {
+		if r.Stored != "local" {
+			t.Fatalf("item %d: %+v, want stored in cold store", i, r)
+		}
+	}
+	if keys := e.srv.Keys(); len(keys) != 0 {
+		t.Fatalf("stale copies left in redis: %v", keys)
+	}
+	for _, k := range []string{"{a}1", "{b}1"} {
+		res := e.rd.get(e.ctx, k)
+		if res.status != http.StatusOK || !strings.Contains(res.body["value"].(string), `"v":"new"`) {
+			t.Fatalf("GET %s = %d %v", k, res.status, res.body)
+		}
+	}
+}
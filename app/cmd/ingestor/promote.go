@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/cachex"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/metricsx"
+	"monolith-kv-sim/internal/redisx"
+)
+
+var (
+	promotionsTotal = metricsx.NewCounter("ingestor_promotions_total",
+		"Jumlah value hasil baca HDFS yang di-SET ulang ke Redis (rehydration)")
+	promotionSkippedTotal = metricsx.NewCounter("ingestor_promotion_skipped_total",
+		"Jumlah promosi yang dilewati karena memori Redis di atas REDIS_MAXMEM_SOFT atau key sudah ada di Redis")
+	promotionFailuresTotal = metricsx.NewCounter("ingestor_promotion_failures_total",
+		"Jumlah promosi ke Redis yang gagal")
+)
+
+// promoter memindahkan kembali value yang dibaca dari cold store (HDFS) ke Redis (read-through promotion),
+// agar read berikutnya tidak perlu mengakses HDFS lagi.
+// Promosi hanya dilakukan selama rasio memori cluster di bawah threshold soft,
+// supaya rehydration tidak langsung memicu overflow/offload lagi.
+type promoter struct {
+	Enabled bool          // HDFS_PROMOTE != "0"
+	TTL     time.Duration // TTL promosi untuk record tanpa expiry tercatat (HDFS_PROMOTE_TTL_SECONDS)
+
+	r     *redis.ClusterClient
+	cache *cachex.Cache
+	soft  float64
+
+	// Rasio memori di-cache sebentar karena ClusterMemRatio menjalankan INFO ke semua shard
+	mu        sync.Mutex
+	ratio     float64
+	ratioAt   time.Time
+	ratioTTL  time.Duration
+	ratioFail bool
+}
+
+// newPromoter membaca konfigurasi promosi dari environment variable.
+func newPromoter(r *redis.ClusterClient, cache *cachex.Cache, soft float64) *promoter {
+	ttl := 3600
+	if s := os.Getenv("HDFS_PROMOTE_TTL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			ttl = v
+		}
+	}
+	return &promoter{
+		Enabled:  os.Getenv("HDFS_PROMOTE") != "0",
+		TTL:      time.Duration(ttl) * time.Second,
+		r:        r,
+		cache:    cache,
+		soft:     soft,
+		ratioTTL: time.Second,
+	}
+}
+
+// memRatio mengembalikan rasio memori cluster, di-cache selama ratioTTL.
+func (p *promoter) memRatio(ctx context.Context) (float64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.ratioAt) < p.ratioTTL {
+		return p.ratio, !p.ratioFail
+	}
+	ratio, err := redisx.ClusterMemRatio(ctx, p.r)
+	p.ratio, p.ratioAt, p.ratioFail = ratio, time.Now(), err != nil
+	return ratio, err == nil
+}
+
+// Promote menulis ulang value dari HDFS ke Redis (SET NX dengan TTL) dan ke local LRU.
+// Record bertipe (hash/list/set/zset) ditulis ulang sesuai tipenya lewat redisx.RestoreTyped
+// dan tidak di-cache di local LRU. SET NX dipakai agar value lama dari HDFS tidak menimpa value baru yang
+// sudah di-ingest ulang ke Redis sejak read dimulai.
+// TTL di Redis adalah sisa TTL asli record (ExpireAt); p.TTL hanya dipakai untuk
+// record lama yang tidak menyimpan expiry. _ts value di-set ke waktu promosi (coldstore.TouchTS).
+// gen adalah generation local cache key sebelum record dibaca (cachex.Cache.Generation).
+// Mengembalikan true jika value berhasil dipromosikan ke Redis.
+func (p *promoter) Promote(ctx context.Context, key string, rec coldstore.Record, gen uint64) bool {
+	if !p.Enabled {
+		return false
+	}
+	ttl := p.TTL
+	if rec.ExpireAt > 0 {
+		if ttl = rec.TTL(time.Now()); ttl < time.Millisecond {
+			promotionSkippedTotal.Inc() // Sudah (hampir) kedaluwarsa: tidak ada gunanya dikembalikan ke Redis
+			return false
+		}
+	}
+	// _ts diperbarui agar offloader menghitung umur key sejak dipromosikan, bukan sejak
+	// pertama kali ditulis; tanpa ini key hot bolak-balik Redis <-> cold store setiap run
+	val := coldstore.TouchTS(rec.Type, rec.Value, time.Now())
+	ratio, ok := p.memRatio(ctx)
+	if !ok || ratio >= p.soft {
+		promotionSkippedTotal.Inc()
+		return false
+	}
+	var (
+		set bool
+		err error
+	)
+	if rec.Type != "" {
+		set, err = redisx.RestoreTyped(ctx, p.r, key, rec.Type, val, ttl)
+	} else {
+		set, err = p.r.SetNX(ctx, key, val, ttl).Result()
+	}
+	if redisx.IsOOM(err) {
+		promotionSkippedTotal.Inc() // Memori penuh (noeviction): value tetap aman di cold store
+		return false
+	}
+	if err != nil {
+		promotionFailuresTotal.Inc()
+		log.Printf("promote key=%q failed: %v", key, err)
+		return false
+	}
+	if !set {
+		promotionSkippedTotal.Inc()
+		return false
+	}
+	promotionsTotal.Inc()
+	if rec.Type == "" {
+		p.cache.AddIfGeneration(key, string(val), ttl, gen)
+	}
+	return true
+}
+
+// typedValue menyiapkan value untuk response JSON: value string dikirim sebagai string,
+// typed envelope (hash/list/set/zset) dikirim sebagai JSON apa adanya.
+func typedValue(typ string, val []byte) any {
+	if typ == "" || typ == redisx.TypeString {
+		return string(val)
+	}
+	return json.RawMessage(val)
+}
+
+// recordType mengembalikan tipe Redis asal record cold store ("string" untuk record lama tanpa tipe).
+func recordType(rec coldstore.Record) string {
+	if rec.Type == "" {
+		return redisx.TypeString
+	}
+	return rec.Type
+}
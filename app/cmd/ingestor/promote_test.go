@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+// tsOf mengembalikan _ts (angka atau string angka) dari value JSON.
+func tsOf(t *testing.T, val string) int64 {
+	t.Helper()
+	var payload struct {
+		TS json.Number `json:"_ts"`
+	}
+	if err := json.Unmarshal([]byte(val), &payload); err != nil {
+		t.Fatalf("value %s: %v", val, err)
+	}
+	ts, err := payload.TS.Int64()
+	if err != nil {
+		t.Fatalf("value %s has no numeric _ts", val)
+	}
+	return ts
+}
+
+func TestPromoteWritesTSAndCaches(t *testing.T) {
+	e := newTestEnv(t)
+	p := newPromoter(e.r, e.cache, 0.8)
+	before := time.Now().UnixMilli()
+
+	// Value cold store tanpa _ts (mis. ditulis client lain) diberi _ts waktu promosi
+	rec := coldstore.Record{Value: []byte(`{"v":1}`), ExpireAt: time.Now().Add(time.Hour).UnixMilli()}
+	if !p.Promote(e.ctx, "k", rec, e.cache.Generation("k")) {
+		t.Fatal("string record not promoted")
+	}
+	v, ok := e.srv.Get("k")
+	if !ok || !strings.Contains(v, `"v":1`) || tsOf(t, v) < before {
+		t.Fatalf("redis k = %q, %t", v, ok)
+	}
+	if ttl := e.srv.TTL("k"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("TTL = %s, want remaining ~1h", ttl)
+	}
+	if got, ok := e.cache.Get("k"); !ok || got != v {
+		t.Fatalf("local cache k = %q, %t; want %q", got, ok, v)
+	}
+
+	rec = coldstore.Record{Type: "hash", Value: []byte(`{"f":"x"}`)}
+	if !p.Promote(e.ctx, "h", rec, e.cache.Generation("h")) {
+		t.Fatal("hash record not promoted")
+	}
+	fields, err := e.r.HGetAll(e.ctx, "h").Result()
+	if err != nil || fields["f"] != "x" || fields["_ts"] == "" {
+		t.Fatalf("redis h = %v, %v", fields, err)
+	}
+	if _, ok := e.cache.Get("h"); ok {
+		t.Fatal("typed record cached in local LRU")
+	}
+
+	// SET NX: value yang sudah ada di Redis tidak ditimpa
+	if p.Promote(e.ctx, "k", coldstore.Record{Value: []byte(`{"v":0}`)}, e.cache.Generation("k")) {
+		t.Fatal("promotion overwrote existing key")
+	}
+}
+
+func TestPromoteSkippedAboveSoftLimit(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetMemory(900, 1000)
+	p := newPromoter(e.r, e.cache, 0.8)
+	if p.Promote(e.ctx, "k", coldstore.Record{Value: []byte(`{"v":1}`)}, e.cache.Generation("k")) {
+		t.Fatal("promoted while memory ratio above soft limit")
+	}
+	if keys := e.srv.Keys(); len(keys) != 0 {
+		t.Fatalf("redis keys = %v", keys)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// internalKeyPrefix adalah prefix key milik offloader sendiri (checkpoint, lease, daftar instance);
+// key dengan prefix ini dilewati saat SCAN.
+const internalKeyPrefix = "offloader:"
+
+// checkpointKey adalah hash Redis berisi progres SCAN per shard (field = node ID cluster).
+// Cursor SCAN hanya berarti untuk node yang mengeluarkannya, jadi setelah failover
+// node baru otomatis mulai dari cursor 0.
+const checkpointKey = "offloader:checkpoint"
+
+// runStats adalah statistik offload (per shard atau total satu run).
+type runStats struct {
+	Scanned     int `json:"scanned"`
+	Old         int `json:"old"`
+	Moved       int `json:"moved"`
+	Superseded  int `json:"superseded"`
+	Reclaimed   int `json:"reclaimed"` // bagian dari Moved yang dipindah oleh mode reclaim
+	WriteFail   int `json:"write_fail"`
+	ParseFail   int `json:"parse_fail"`
+	Unsupported int `json:"unsupported"` // key bertipe yang tidak bisa dipindah (stream, module, member bukan UTF-8)
+}
+
+func (s *runStats) add(o runStats) {
+	s.Scanned += o.Scanned
+	s.Old += o.Old
+	s.Moved += o.Moved
+	s.Superseded += o.Superseded
+	s.Reclaimed += o.Reclaimed
+	s.WriteFail += o.WriteFail
+	s.ParseFail += o.ParseFail
+	s.Unsupported += o.Unsupported
+}
+
+// checkpoint adalah progres satu shard. Satu pass = SCAN dari cursor 0 sampai kembali ke 0,
+// yang bisa tersebar di beberapa run jika OFFLOAD_RUN_BUDGET_SECONDS habis.
+type checkpoint struct {
+	Cursor       uint64    `json:"cursor"`
+	PassStarted  int64     `json:"pass_started"` // unix ms awal pass yang sedang berjalan
+	UpdatedAt    int64     `json:"updated_at"`
+	Pass         runStats  `json:"pass"` // akumulasi statistik pass yang sedang berjalan
+	LastPass     *runStats `json:"last_pass,omitempty"`
+	LastPassDone int64     `json:"last_pass_done,omitempty"` // unix ms selesainya pass terakhir
+}
+
+// shardID mengembalikan node ID cluster shard (CLUSTER MYID), stabil antar restart.
+func shardID(ctx context.Context, shard *redis.Client) (string, error) {
+	return shard.Do(ctx, "CLUSTER", "MYID").Text()
+}
+
+// loadCheckpoint membaca checkpoint shard; shard tanpa checkpoint mulai pass baru dari cursor 0.
+func loadCheckpoint(ctx context.Context, r *redis.ClusterClient, id string) (checkpoint, error) {
+	cp := checkpoint{PassStarted: time.Now().UnixMilli()}
+	b, err := r.HGet(ctx, checkpointKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return checkpoint{PassStarted: time.Now().UnixMilli()}, err
+	}
+	return cp, nil
+}
+
+func saveCheckpoint(ctx context.Context, r *redis.ClusterClient, id string, cp checkpoint) error {
+	cp.UpdatedAt = time.Now().UnixMilli()
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return r.HSet(ctx, checkpointKey, id, b).Err()
+}
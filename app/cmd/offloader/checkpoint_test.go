@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	e := newTestEnv(t)
+	cp, err := loadCheckpoint(e.ctx, e.r, "n1")
+	if err != nil || cp.Cursor != 0 || cp.PassStarted == 0 {
+		t.Fatalf("missing checkpoint = %+v, %v; want a new pass from cursor 0", cp, err)
+	}
+
+	cp.Cursor, cp.Pass.Scanned = 42, 7
+	if err := saveCheckpoint(e.ctx, e.r, "n1", cp); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadCheckpoint(e.ctx, e.r, "n1")
+	if err != nil || got.Cursor != 42 || got.Pass.Scanned != 7 || got.PassStarted != cp.PassStarted || got.UpdatedAt == 0 {
+		t.Fatalf("loaded = %+v, %v", got, err)
+	}
+	// Checkpoint shard lain tidak terpengaruh
+	if other, _ := loadCheckpoint(e.ctx, e.r, "n2"); other.Cursor != 0 {
+		t.Fatalf("n2 cursor = %d", other.Cursor)
+	}
+
+	e.r.HSet(e.ctx, checkpointKey, "n1", "{broken")
+	if got, err := loadCheckpoint(e.ctx, e.r, "n1"); err == nil || got.Cursor != 0 {
+		t.Fatalf("corrupt checkpoint = %+v, %v; want error and cursor 0", got, err)
+	}
+}
+
+// Run yang kehabisan budget menyimpan cursor; run berikutnya melanjutkan dari sana sampai pass selesai.
+func TestOffloadResumesFromCheckpoint(t *testing.T) {
+	e := newTestEnv(t)
+	for i := 0; i < 10; i++ {
+		e.shard.Set(e.ctx, fmt.Sprintf("k%02d", i), oldValue(0), 0) // baru, tidak dipindah
+	}
+	e.cfg.ScanCount = 3
+	e.cfg.Budget = time.Nanosecond // berhenti setelah satu batch SCAN per run
+
+	load := func() checkpoint {
+		t.Helper()
+		cp, err := loadCheckpoint(e.ctx, e.r, redistest.NodeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cp
+	}
+
+	doOffload(e.ctx, e.r, e.cold, nil, e.cfg, false)
+	first := load()
+	if first.Cursor == 0 || first.Pass.Scanned != 3 || first.LastPass != nil {
+		t.Fatalf("after run 1: %+v", first)
+	}
+	// --full-rescan mulai lagi dari cursor 0
+	doOffload(e.ctx, e.r, e.cold, nil, e.cfg, true)
+	if cp := load(); cp.Cursor != first.Cursor || cp.Pass.Scanned != 3 || cp.PassStarted < first.PassStarted {
+		t.Fatalf("after reset: %+v, want same progress as run 1", cp)
+	}
+
+	for run := 0; run < 10; run++ {
+		doOffload(e.ctx, e.r, e.cold, nil, e.cfg, false)
+		if cp := load(); cp.Cursor == 0 {
+			if cp.LastPass == nil || cp.LastPass.Scanned != 10 || cp.Pass.Scanned != 0 || cp.LastPassDone == 0 {
+				t.Fatalf("finished pass: %+v (last=%+v)", cp, cp.LastPass)
+			}
+			return
+		}
+	}
+	t.Fatal("pass never finished")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// fetched adalah value satu key hasil fetchValues.
+type fetched struct {
+	val     []byte // value string, atau typed envelope JSON untuk hash/list/set/zset
+	typ     string // "" untuk string
+	version []byte // versi untuk DEL bersyarat (value string / hasil DUMP)
+	pttl    time.Duration
+	err     error // redis.Nil jika key sudah hilang, redisx.ErrUnsupportedType untuk tipe lain
+}
+
+// candidate adalah key yang akan dipindah: record cold store dan versi untuk DEL bersyarat.
+type candidate struct {
+	kv      coldstore.KeyValue
+	version []byte
+}
+
+// newCandidate menyusun candidate dari hasil fetch; sisa TTL Redis disimpan sebagai
+// waktu kedaluwarsa absolut agar tetap berlaku di cold store.
+func newCandidate(key string, f fetched) candidate {
+	kv := coldstore.KeyValue{Key: key, Value: f.val, Type: f.typ}
+	if f.pttl > 0 {
+		kv.ExpireAt = time.Now().Add(f.pttl).UnixMilli()
+	}
+	return candidate{kv: kv, version: f.version}
+}
+
+// fetchValues membaca value dan sisa TTL keys dari satu shard dengan satu pipeline GET+PTTL.
+// Key non-string (WRONGTYPE) dibaca ulang sesuai tipenya lewat redisx.ReadTypedMany.
+func fetchValues(ctx context.Context, shard *redis.Client, cfg offloadConfig, keys []string) []fetched {
+	out := make([]fetched, len(keys))
+	_ = cfg.Limiter.Wait(ctx, 2*len(keys))
+	gets := make([]*redis.StringCmd, len(keys))
+	pttls := make([]*redis.DurationCmd, len(keys))
+	_, _ = shard.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			gets[i] = p.Get(ctx, key)
+			pttls[i] = p.PTTL(ctx, key)
+		}
+		return nil
+	})
+	var typed []int
+	for i := range keys {
+		out[i].val, out[i].err = gets[i].Bytes()
+		out[i].version = out[i].val
+		if redis.HasErrorPrefix(out[i].err, "WRONGTYPE") {
+			typed = append(typed, i)
+			continue
+		}
+		if pttl, err := pttls[i].Result(); err == nil {
+			if pttl == -2 {
+				out[i].err = redis.Nil // Key sudah expired/dihapus sejak GET
+			}
+			out[i].pttl = pttl
+		}
+	}
+	if len(typed) == 0 {
+		return out
+	}
+
+	tkeys := make([]string, len(typed))
+	for n, i := range typed {
+		tkeys[n] = keys[i]
+	}
+	_ = cfg.Limiter.Wait(ctx, 3*len(tkeys)) // TYPE, baca sesuai tipe, DUMP
+	tvals, errs := redisx.ReadTypedMany(ctx, shard, tkeys)
+	for n, i := range typed {
+		out[i] = fetched{err: errs[n], pttl: out[i].pttl}
+		if errs[n] != nil {
+			continue
+		}
+		out[i].val, out[i].typ, out[i].version = tvals[n].Value, tvals[n].Type, tvals[n].Version()
+		if out[i].typ == redisx.TypeString {
+			out[i].typ = "" // Key diganti string di antara GET dan TYPE
+		}
+		if pttl, err := pttls[i].Result(); err == nil {
+			out[i].pttl = pttl
+		}
+	}
+	return out
+}
+
+// unsupported true jika err berarti key tidak bisa dipindah karena tipenya.
+func unsupported(err error) bool {
+	return errors.Is(err, redisx.ErrUnsupportedType)
+}
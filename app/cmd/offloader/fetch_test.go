@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// Satu batch SCAN dibaca dengan satu pipeline GET+PTTL; hanya key non-string yang dibaca ulang per tipe.
+func TestFetchValues(t *testing.T) {
+	e := newTestEnv(t)
+	e.shard.Set(e.ctx, "ttl", "a", time.Hour)
+	e.shard.Set(e.ctx, "plain", "b", 0)
+	e.shard.HSet(e.ctx, "hash", "f", "x")
+	e.shard.PExpire(e.ctx, "hash", time.Minute)
+	e.srv.ResetCalls()
+
+	keys := []string{"ttl", "plain", "missing", "hash"}
+	got := fetchValues(e.ctx, e.shard, e.cfg, keys)
+
+	if f := got[0]; f.err != nil || string(f.val) != "a" || string(f.version) != "a" || f.typ != "" || f.pttl <= 59*time.Minute {
+		t.Errorf("ttl = %+v", f)
+	}
+	if f := got[1]; f.err != nil || string(f.val) != "b" || f.pttl != -1 {
+		t.Errorf("plain = %+v, want no expiry", f)
+	}
+	if f := got[2]; !errors.Is(f.err, redis.Nil) {
+		t.Errorf("missing = %+v, want redis.Nil", f)
+	}
+	if f := got[3]; f.err != nil || f.typ != redisx.TypeHash || string(f.val) != `{"f":"x"}` || len(f.version) == 0 || f.pttl <= 59*time.Second {
+		t.Errorf("hash = %+v", f)
+	}
+	for cmd, want := range map[string]int{"get": 4, "pttl": 4, "type": 1} {
+		if n := e.srv.Calls(cmd); n != want {
+			t.Errorf("%s calls = %d, want %d", cmd, n, want)
+		}
+	}
+}
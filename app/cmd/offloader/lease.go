@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/redisx"
+)
+
+const (
+	// leaseKeyPrefix + node ID: lease per shard, berisi ID instance offloader pemiliknya
+	leaseKeyPrefix = "offloader:lease:"
+	// instancesKey adalah sorted set instance offloader yang hidup (score = heartbeat terakhir, unix ms)
+	instancesKey = "offloader:instances"
+)
+
+// leaseManager membagi shard di antara beberapa replica offloader lewat lease per shard.
+// Setiap instance memegang paling banyak ceil(shard / instance hidup) lease, lease diperpanjang
+// di background setiap ttl/3, dan lease instance yang mati expired setelah ttl lalu
+// diambil instance lain di run berikutnya.
+type leaseManager struct {
+	r   *redis.ClusterClient
+	id  string
+	ttl time.Duration
+
+	mu   sync.Mutex
+	held map[string]time.Time // node ID shard -> waktu lease terakhir diambil/diperpanjang
+}
+
+func newLeaseManager(r *redis.ClusterClient, ttl time.Duration) *leaseManager {
+	host, _ := os.Hostname()
+	return &leaseManager{
+		r:    r,
+		id:   host + "-" + strconv.Itoa(os.Getpid()),
+		ttl:  ttl,
+		held: map[string]time.Time{},
+	}
+}
+
+// renewLoop mengirim heartbeat dan memperpanjang semua lease yang dipegang sampai ctx selesai.
+// Lease yang gagal diperpanjang dilepas dari held, sehingga holds() untuk shard itu menjadi false.
+func (m *leaseManager) renewLoop(ctx context.Context) {
+	t := time.NewTicker(m.ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		m.heartbeat(ctx)
+		m.mu.Lock()
+		ids := make([]string, 0, len(m.held))
+		for id := range m.held {
+			ids = append(ids, id)
+		}
+		m.mu.Unlock()
+		for _, id := range ids {
+			ok, err := redisx.RenewLease(ctx, m.r, leaseKeyPrefix+id, m.id, m.ttl)
+			if err != nil {
+				// Error sementara: lease masih berlaku sampai ttl sejak perpanjangan terakhir (lihat holds)
+				log.Printf("offload lease renew failed shard=%s: %v", id, err)
+				continue
+			}
+			m.mu.Lock()
+			if ok {
+				m.held[id] = time.Now()
+			} else {
+				log.Printf("offload lease lost shard=%s", id)
+				delete(m.held, id)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *leaseManager) heartbeat(ctx context.Context) {
+	now := time.Now().UnixMilli()
+	if err := m.r.ZAdd(ctx, instancesKey, redis.Z{Score: float64(now), Member: m.id}).Err(); err != nil && !redisx.IsOOM(err) {
+		log.Printf("offload heartbeat failed: %v", err)
+	}
+	// Instance yang tidak mengirim heartbeat selama ttl dianggap mati
+	_ = m.r.ZRemRangeByScore(ctx, instancesKey, "-inf", fmt.Sprint(now-m.ttl.Milliseconds())).Err()
+}
+
+// holds true jika instance ini masih memegang lease shard id.
+// Lease yang tidak berhasil diperpanjang selama ttl dianggap hilang walaupun Redis tidak bisa dihubungi.
+func (m *leaseManager) holds(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.held[id]
+	return ok && time.Since(t) < m.ttl
+}
+
+// claim menyeimbangkan lease sebelum run dan mengembalikan shard yang boleh diproses instance ini
+// (nilai map: true jika shard dipegang dengan lease, false jika diproses tanpa lease).
+// Lease di atas jatah dilepas, lalu shard tanpa pemilik diambil sampai jatah terpenuhi.
+// Jika lease tidak bisa diambil karena Redis OOM (SET ditolak oleh noeviction), shard tetap
+// diproses tanpa lease: membebaskan memori lebih penting, dan pemrosesan ganda aman karena
+// DEL offloader bersyarat (CompareAndDelete).
+func (m *leaseManager) claim(ctx context.Context, shards []string) map[string]bool {
+	m.heartbeat(ctx)
+	live, err := m.r.ZCard(ctx, instancesKey).Result()
+	if err != nil || live < 1 {
+		live = 1
+	}
+	quota := (len(shards) + int(live) - 1) / int(live)
+
+	sort.Strings(shards)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var mine, free []string
+	for _, id := range shards {
+		if t, ok := m.held[id]; ok && time.Since(t) < m.ttl {
+			mine = append(mine, id)
+		} else {
+			free = append(free, id)
+		}
+	}
+	for len(mine) > quota {
+		id := mine[len(mine)-1]
+		mine = mine[:len(mine)-1]
+		delete(m.held, id)
+		if err := redisx.ReleaseLease(ctx, m.r, leaseKeyPrefix+id, m.id); err != nil {
+			log.Printf("offload lease release failed shard=%s: %v", id, err)
+		}
+	}
+	out := map[string]bool{}
+	for _, id := range mine {
+		out[id] = true
+	}
+	unleased := 0
+	for _, id := range free {
+		if len(mine) >= quota {
+			break
+		}
+		ok, err := redisx.AcquireLease(ctx, m.r, leaseKeyPrefix+id, m.id, m.ttl)
+		switch {
+		case redisx.IsOOM(err):
+			out[id] = false
+			unleased++
+		case err != nil:
+			log.Printf("offload lease acquire failed shard=%s: %v", id, err)
+		case ok:
+			m.held[id] = time.Now()
+			mine = append(mine, id)
+			out[id] = true
+		}
+	}
+	if unleased > 0 {
+		log.Printf("offload lease: redis OOM, processing %d shard(s) without lease", unleased)
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func (e *testEnv) leaseManager(id string, ttl time.Duration) *leaseManager {
+	m := newLeaseManager(e.r, ttl)
+	m.id = id
+	return m
+}
+
+// Dua replica membagi shard: setiap instance memegang paling banyak ceil(shard / instance hidup).
+func TestLeaseManagerBalancesShards(t *testing.T) {
+	e := newTestEnv(t)
+	shards := []string{"a", "b", "c", "d"}
+	m1 := e.leaseManager("one", time.Minute)
+	m2 := e.leaseManager("two", time.Minute)
+	all := map[string]bool{"a": true, "b": true, "c": true, "d": true}
+
+	if got := m1.claim(e.ctx, shards); !reflect.DeepEqual(got, all) {
+		t.Fatalf("single instance claim = %v, want every shard", got)
+	}
+	// Instance kedua bergabung: semua lease masih dipegang instance pertama
+	if got := m2.claim(e.ctx, shards); len(got) != 0 {
+		t.Fatalf("second instance claim = %v, want none", got)
+	}
+	// Run berikutnya instance pertama melepas kelebihannya, lalu instance kedua mengambilnya
+	if got := m1.claim(e.ctx, shards); !reflect.DeepEqual(got, map[string]bool{"a": true, "b": true}) {
+		t.Fatalf("first instance after rebalance = %v", got)
+	}
+	if got := m2.claim(e.ctx, shards); !reflect.DeepEqual(got, map[string]bool{"c": true, "d": true}) {
+		t.Fatalf("second instance after rebalance = %v", got)
+	}
+	for _, id := range shards {
+		if m1.holds(id) == m2.holds(id) {
+			t.Fatalf("shard %s: one=%t two=%t, want exactly one owner", id, m1.holds(id), m2.holds(id))
+		}
+	}
+}
+
+// Saat Redis OOM lease tidak bisa diambil; shard tetap diproses tanpa lease.
+func TestLeaseManagerOOMProcessesWithoutLease(t *testing.T) {
+	e := newTestEnv(t)
+	e.srv.SetOOM(true)
+	m := e.leaseManager("one", time.Minute)
+	got := m.claim(e.ctx, []string{"a", "b"})
+	if !reflect.DeepEqual(got, map[string]bool{"a": false, "b": false}) {
+		t.Fatalf("claim under OOM = %v, want both shards without lease", got)
+	}
+	if m.holds("a") {
+		t.Fatal("holds lease that was never acquired")
+	}
+}
+
+// Lease yang diambil instance lain (setelah expired) terdeteksi di perpanjangan berikutnya.
+func TestLeaseManagerDetectsLostLease(t *testing.T) {
+	e := newTestEnv(t)
+	m := e.leaseManager("one", 30*time.Millisecond)
+	if got := m.claim(e.ctx, []string{"a"}); !got["a"] {
+		t.Fatalf("claim = %v", got)
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	go m.renewLoop(ctx)
+
+	// Perpanjangan menjaga lease tetap hidup melewati ttl awal
+	time.Sleep(60 * time.Millisecond)
+	if !m.holds("a") {
+		t.Fatal("lease lost while being renewed")
+	}
+	e.r.Set(e.ctx, leaseKeyPrefix+"a", "two", time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for m.holds("a") {
+		if time.Now().After(deadline) {
+			t.Fatal("lease taken by another instance still reported as held")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
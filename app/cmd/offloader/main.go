@@ -2,144 +2,443 @@
 // sesuai skema monolith recommendation — agar in-memory cache tidak penuh.
 // Setiap interval, scan key di Redis; jika umur data > OFFLOAD_AFTER_SECONDS,
 // tulis ke HDFS (on-disk KV store) lalu hapus dari Redis.
+// Key ditulis berkelompok sebagai segment file (maks OFFLOAD_SEGMENT_MAX_RECORDS per file),
+// bukan satu file per key, agar jumlah file di NameNode tidak meledak.
 
 package main
 
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"monolith-kv-sim/internal/hdfsx"
+	"monolith-kv-sim/internal/coldstore"
 	"monolith-kv-sim/internal/redisx"
+	"monolith-kv-sim/internal/throttle"
 )
 
 func main() {
+	// --full-rescan: abaikan cursor yang tersimpan dan mulai pass baru dari cursor 0 di setiap shard
+	fullRescan := flag.Bool("full-rescan", false, "ignore saved SCAN checkpoints and start a new pass from cursor 0")
+	// --dry-run: satu pass read-only, laporan JSON ke stdout lalu keluar (tidak ada write/DEL)
+	dryRunOnce := flag.Bool("dry-run", false, "scan and evaluate the offload policy without moving anything, print a JSON report to stdout and exit")
+	// --report-addr: jalankan server laporan dry-run (GET /report) alih-alih offload
+	reportAddr := flag.String("report-addr", "", "serve dry-run reports over HTTP on this address (e.g. :8090) instead of offloading")
+	flag.Parse()
+
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 	log.Print("offloader: process started")
 
 	ctx := context.Background()
-	r := redisx.NewCluster()
-	hdfs := hdfsx.NewWriter()
+	// CLIENT NO-TOUCH: GET/DUMP offloader (dan /report) tidak me-reset OBJECT IDLETIME yang dipakai mode reclaim
+	r, noTouch := redisx.NewClusterNoTouch()
+	cold := coldstore.New()
 
-	// Setelah berapa detik data dianggap "terlalu lama" dan dipindah ke HDFS
-	offloadAfterSec := getInt("OFFLOAD_AFTER_SECONDS", 600) // default 10 menit
 	// Interval jalannya proses offload (detik)
 	intervalSec := getInt("OFFLOAD_INTERVAL_SECONDS", 60)
-	// Jika rasio memori cluster >= nilai ini, offloader boleh memindahkan data lebih agresif
-	forceMemRatio := getFloat("OFFLOAD_FORCE_MEM_RATIO", 0.70)
-	// Saat mode agresif aktif, minimal umur key (detik) agar tetap tidak memindahkan key yang terlalu baru
-	forceMinAgeSec := getInt("OFFLOAD_FORCE_MIN_AGE_SECONDS", 5)
+	cfg := offloadConfig{
+		// Setelah berapa detik data dianggap "terlalu lama" dan dipindah ke HDFS
+		AfterSec: getInt("OFFLOAD_AFTER_SECONDS", 600), // default 10 menit
+		// Jika rasio memori cluster >= nilai ini, offloader boleh memindahkan data lebih agresif
+		ForceMemRatio: getFloat("OFFLOAD_FORCE_MEM_RATIO", 0.70),
+		// Saat mode agresif aktif, minimal umur key (detik) agar tetap tidak memindahkan key yang terlalu baru
+		ForceMinAgeSec: getInt("OFFLOAD_FORCE_MIN_AGE_SECONDS", 5),
+		// Jumlah maksimal record per segment file di cold store
+		SegmentMax: getInt("OFFLOAD_SEGMENT_MAX_RECORDS", 1000),
+		// Batas waktu SCAN per run; shard yang belum selesai dilanjutkan dari checkpoint di run berikutnya
+		Budget: time.Duration(getInt("OFFLOAD_RUN_BUDGET_SECONDS", intervalSec)) * time.Second,
+	}
+	if cfg.SegmentMax <= 0 {
+		cfg.SegmentMax = 1000
+	}
+	// Jumlah key per SCAN (dan per pipeline GET+PTTL)
+	if cfg.ScanCount = getInt("OFFLOAD_SCAN_COUNT", 500); cfg.ScanCount <= 0 {
+		cfg.ScanCount = 500
+	}
+	// Jumlah shard yang di-scan bersamaan
+	if cfg.Workers = getInt("OFFLOAD_SHARD_WORKERS", 4); cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	// Batas total command Redis per detik (SCAN, GET, PTTL, DEL) agar ingestor tidak kekurangan kapasitas; 0 = tanpa batas
+	maxOps := getInt("OFFLOAD_MAX_OPS_PER_SEC", 0)
+	cfg.Limiter = throttle.New(maxOps)
+	cfg.NoTouch = noTouch
+	// Mode agresif: "reclaim" (key paling lama idle dulu, sampai target per shard) atau "scan" (perilaku lama)
+	cfg.ForceMode = os.Getenv("OFFLOAD_FORCE_MODE")
+	if cfg.ForceMode != forceModeScan {
+		cfg.ForceMode = forceModeReclaim
+	}
+	cfg.Reclaim = reclaimConfig{
+		// Target rasio memori per shard; reclaim berhenti setelah shard turun di bawah nilai ini
+		Target: getFloat("OFFLOAD_RECLAIM_TARGET_RATIO", 0.50),
+		// Target khusus per shard, mis. "redis-1:7001=0.40,<node id>=0.45"
+		Targets: parseShardTargets(os.Getenv("OFFLOAD_RECLAIM_SHARD_TARGETS")),
+		// Jumlah key acak yang dinilai per putaran dan jumlah key yang dipindah per putaran
+		Sample: getInt("OFFLOAD_RECLAIM_SAMPLE", 1000),
+		Batch:  getInt("OFFLOAD_RECLAIM_BATCH", 200),
+	}
+	if cfg.Reclaim.Sample <= 0 {
+		cfg.Reclaim.Sample = 1000
+	}
+	if cfg.Reclaim.Batch <= 0 {
+		cfg.Reclaim.Batch = 200
+	}
 
 	log.Printf("offloader: connecting to Redis...")
 	if err := r.Ping(ctx).Err(); err != nil {
 		log.Fatalf("offloader: redis ping failed: %v", err)
 	}
-	log.Printf("offloader started: OFFLOAD_AFTER_SECONDS=%d, OFFLOAD_INTERVAL_SECONDS=%d, OFFLOAD_FORCE_MEM_RATIO=%.2f, OFFLOAD_FORCE_MIN_AGE_SECONDS=%d, HDFS_PATH=%s",
-		offloadAfterSec, intervalSec, forceMemRatio, forceMinAgeSec, os.Getenv("HDFS_PATH"))
+	log.Printf("offloader started: OFFLOAD_AFTER_SECONDS=%d, OFFLOAD_INTERVAL_SECONDS=%d, OFFLOAD_FORCE_MEM_RATIO=%.2f, OFFLOAD_FORCE_MIN_AGE_SECONDS=%d, OFFLOAD_SEGMENT_MAX_RECORDS=%d, OFFLOAD_RUN_BUDGET_SECONDS=%d, OFFLOAD_SCAN_COUNT=%d, OFFLOAD_SHARD_WORKERS=%d, OFFLOAD_MAX_OPS_PER_SEC=%d, OFFLOAD_FORCE_MODE=%s, OFFLOAD_RECLAIM_TARGET_RATIO=%.2f, full_rescan=%t, HDFS_PATH=%s, COLD_STORE=%s",
+		cfg.AfterSec, intervalSec, cfg.ForceMemRatio, cfg.ForceMinAgeSec, cfg.SegmentMax, int(cfg.Budget.Seconds()), cfg.ScanCount, cfg.Workers, maxOps, cfg.ForceMode, cfg.Reclaim.Target, *fullRescan, os.Getenv("HDFS_PATH"), cold.Name())
 
-	// Pastikan path HDFS sudah dibuat sejak awal agar kegagalan bisa terlihat di log lebih cepat.
-	hdfs.EnsureDir()
+	// Jumlah segmen key (dipisah ":") untuk pengelompokan by_prefix di laporan dry-run
+	prefixDepth := getInt("OFFLOAD_REPORT_PREFIX_DEPTH", 2)
+	if *dryRunOnce {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(dryRun(ctx, r, cfg, prefixDepth)); err != nil {
+			log.Fatalf("offloader: write report failed: %v", err)
+		}
+		return
+	}
+	if *reportAddr != "" {
+		log.Fatal(serveReports(*reportAddr, r, cfg, prefixDepth))
+	}
+
+	// Pastikan root cold store (path HDFS / direktori / bucket) sudah dibuat sejak awal agar kegagalan bisa terlihat di log lebih cepat.
+	if err := cold.Init(); err != nil {
+		log.Printf("offloader: cold store (%s) init failed: %v", cold.Name(), err)
+	}
+
+	// Lease per shard agar beberapa replica offloader membagi shard (0 = lease dimatikan, satu instance memproses semua shard)
+	var lm *leaseManager
+	if leaseTTL := getInt("OFFLOAD_LEASE_TTL_SECONDS", 30); leaseTTL > 0 {
+		lm = newLeaseManager(r, time.Duration(leaseTTL)*time.Second)
+		go lm.renewLoop(ctx)
+		log.Printf("offloader: instance id=%s, OFFLOAD_LEASE_TTL_SECONDS=%d", lm.id, leaseTTL)
+	}
 
+	reset := *fullRescan
 	for {
-		doOffload(ctx, r, hdfs, offloadAfterSec, forceMemRatio, forceMinAgeSec)
+		doOffload(ctx, r, cold, lm, cfg, reset)
+		reset = false
 		time.Sleep(time.Duration(intervalSec) * time.Second)
 	}
 }
 
-func doOffload(ctx context.Context, r *redis.ClusterClient, hdfs *hdfsx.Writer, offloadAfterSec int, forceMemRatio float64, forceMinAgeSec int) {
+// offloadConfig adalah konfigurasi satu run offload (dari environment variable).
+type offloadConfig struct {
+	AfterSec       int
+	ForceMemRatio  float64
+	ForceMinAgeSec int
+	SegmentMax     int
+	Budget         time.Duration     // <= 0: tanpa batas, setiap run menyelesaikan pass penuh
+	ScanCount      int               // COUNT per SCAN = ukuran batch pipeline GET+PTTL
+	Workers        int               // jumlah shard yang diproses bersamaan
+	Limiter        *throttle.Limiter // batas ops/detik ke Redis, dibagi semua shard (nil = tanpa batas)
+	ForceMode      string            // forceModeReclaim atau forceModeScan
+	Reclaim        reclaimConfig
+	NoTouch        *redisx.NoTouch // nil/tidak aktif: OBJECT IDLETIME ikut ter-reset oleh read offloader
+}
+
+// doOffload menjalankan satu run: SCAN setiap master shard melanjutkan cursor dari checkpoint,
+// sampai pass shard selesai (cursor kembali ke 0) atau cfg.Budget habis.
+// reset=true mengabaikan cursor tersimpan (--full-rescan).
+// lm nil berarti lease dimatikan dan semua shard diproses oleh instance ini.
+func doOffload(ctx context.Context, r *redis.ClusterClient, cold coldstore.ColdStore, lm *leaseManager, cfg offloadConfig, reset bool) {
+	start := time.Now()
 	memRatio, memErr := redisx.ClusterMemRatio(ctx, r)
-	forceByMem := memErr == nil && memRatio >= forceMemRatio
+	// Mode scan memakai rasio cluster; mode reclaim menilai setiap shard sendiri (lihat process)
+	forceByMem := cfg.ForceMode == forceModeScan && memErr == nil && memRatio >= cfg.ForceMemRatio
+
+	pol := newMovePolicy(cfg, forceByMem)
+
+	var (
+		mu                 sync.Mutex
+		total              runStats
+		shards, passesDone int
+	)
 
-	cutoff := time.Now().Add(-time.Duration(offloadAfterSec) * time.Second).Unix()
-	forceCutoff := time.Now().Add(-time.Duration(forceMinAgeSec) * time.Second).Unix()
-	var scanned, old, moved, writeFail, parseFail int
+	// Node ID semua master shard; replica dilewati karena DEL harus ke master
+	masters := map[string]*redis.Client{}
+	err := r.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+		id, err := shardID(ctx, shard)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		masters[id] = shard
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		log.Printf("offload shard discovery error: %v", err)
+		return
+	}
+	// Dengan lease, hanya shard yang dipegang instance ini yang diproses (nilai: diproses dengan lease)
+	claimed := map[string]bool{}
+	if lm != nil {
+		ids := make([]string, 0, len(masters))
+		for id := range masters {
+			ids = append(ids, id)
+		}
+		claimed = lm.claim(ctx, ids)
+	} else {
+		for id := range masters {
+			claimed[id] = false
+		}
+	}
+
+	process := func(id string, shard *redis.Client, leased bool) error {
+		// lost true jika lease shard hilang di tengah run (diambil instance lain setelah expired)
+		lost := func() bool { return leased && !lm.holds(id) }
+		cp, err := loadCheckpoint(ctx, r, id)
+		if err != nil {
+			log.Printf("offload checkpoint load failed shard=%s: %v (starting new pass)", id, err)
+		}
+		if reset {
+			cp.Cursor, cp.Pass, cp.PassStarted = 0, runStats{}, time.Now().UnixMilli()
+		}
+		var st runStats // statistik shard ini di run ini
+		cursor := cp.Cursor
+		// save menyimpan cursor yang semua key sebelumnya sudah selesai diproses (pending kosong)
+		// Setelah lease hilang checkpoint tidak ditulis lagi agar tidak menimpa progres pemilik baru
+		save := func() {
+			if lost() {
+				return
+			}
+			c := cp
+			c.Cursor = cursor
+			c.Pass.add(st)
+			if err := saveCheckpoint(ctx, r, id, c); err != nil {
+				log.Printf("offload checkpoint save failed shard=%s: %v", id, err)
+			}
+		}
+
+		// Key yang akan dipindah dikumpulkan lalu ditulis sebagai satu segment;
+		// key baru dihapus dari Redis setelah segment-nya berhasil ditulis.
+		var pending []candidate
+		flush := func() {
+			if len(pending) == 0 {
+				return
+			}
+			moveKeys(ctx, r, shard, cold, cfg, pending, &st)
+			pending = pending[:0]
+		}
+
+		// Mode reclaim: shard yang rasio memorinya >= OFFLOAD_FORCE_MEM_RATIO dikurangi dulu sampai
+		// di bawah targetnya, sebelum SCAN berbasis umur berjalan
+		if cfg.ForceMode == forceModeReclaim {
+			if ratio, err := redisx.ShardMemRatio(ctx, shard); err == nil && ratio >= cfg.ForceMemRatio {
+				target := cfg.Reclaim.target(id, shard.Options().Addr)
+				var deadline time.Time
+				if cfg.Budget > 0 {
+					deadline = start.Add(cfg.Budget)
+				}
+				before, after, err := reclaimShard(ctx, r, shard, cold, cfg, target, deadline, lost, &st)
+				if err != nil {
+					log.Printf("offload reclaim failed shard=%s: %v", id, err)
+				}
+				log.Printf("offload reclaim shard=%s mem_ratio=%.4f->%.4f target=%.2f reclaimed=%d", id, before, after, target, st.Reclaimed)
+			}
+		}
 
-	// Iterasi tiap shard di cluster; SCAN penuh (cursor sampai 0) agar semua key terproses
-	err := r.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
-		var cursor uint64
 		for {
-			keys, next, err := shard.Scan(ctx, cursor, "*", 100).Result()
+			if lost() {
+				log.Printf("offload lease lost shard=%s, stopping at cursor=%d", id, cursor)
+				break
+			}
+			_ = cfg.Limiter.Wait(ctx, 1)
+			scanned, next, err := shard.Scan(ctx, cursor, "*", int64(cfg.ScanCount)).Result()
 			if err != nil {
+				flush()
+				save()
 				return err
 			}
-			for _, key := range keys {
-				scanned++
-				val, err := shard.Get(ctx, key).Bytes()
-				if err != nil {
+			keys := scanned[:0]
+			for _, key := range scanned {
+				if !strings.HasPrefix(key, internalKeyPrefix) { // Checkpoint & lease offloader sendiri tidak ikut dipindah
+					keys = append(keys, key)
+				}
+			}
+			// GET + PTTL semua key hasil SCAN dalam satu pipeline (satu round trip per batch)
+			vals := fetchValues(ctx, shard, cfg, keys)
+			for i, key := range keys {
+				st.Scanned++
+				f := vals[i]
+				if f.err != nil {
+					if unsupported(f.err) {
+						st.Unsupported++
+					}
 					continue
 				}
-				ts, hasTS := extractTS(val)
+				ts, hasTS := extractTS(f.val)
 				if !hasTS {
-					parseFail++
+					st.ParseFail++
 				}
 
-				shouldMove := false
-				if hasTS && ts < cutoff {
-					old++
-					shouldMove = true
-				} else if forceByMem && hasTS && ts < forceCutoff {
-					shouldMove = true
-				} else if forceByMem && !hasTS {
-					shouldMove = true
+				old, shouldMove := pol.evaluate(ts, hasTS)
+				if old {
+					st.Old++
 				}
 
 				if shouldMove {
-					if err := hdfs.WriteKeyValue(key, val); err != nil {
-						writeFail++
-						log.Printf("offload write failed key=%q: %v", key, err)
-						continue
-					}
-					if shard.Del(ctx, key).Err() == nil {
-						moved++
+					pending = append(pending, newCandidate(key, f))
+					if len(pending) >= cfg.SegmentMax {
+						flush()
 					}
 				}
 			}
 			cursor = next
-			if cursor == 0 {
+			if cursor == 0 || (cfg.Budget > 0 && time.Since(start) >= cfg.Budget) {
 				break
 			}
+			if len(pending) == 0 {
+				save()
+			}
 		}
+		flush()
+
+		mu.Lock()
+		total.add(st)
+		shards++
+		if cursor == 0 {
+			passesDone++
+		}
+		mu.Unlock()
+
+		if cursor == 0 {
+			// Pass selesai: statistiknya disimpan sebagai last_pass, pass berikutnya mulai dari awal
+			last := cp.Pass
+			last.add(st)
+			cp.LastPass, cp.LastPassDone = &last, time.Now().UnixMilli()
+			cp.Pass, cp.PassStarted, st = runStats{}, time.Now().UnixMilli(), runStats{}
+		}
+		save()
 		return nil
-	})
-	if err != nil {
-		log.Printf("offload scan error: %v", err)
-		return
 	}
+
+	// Shard diproses paralel, paling banyak cfg.Workers sekaligus
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, cfg.Workers)
+	for id, leased := range claimed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string, leased bool) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := process(id, masters[id], leased); err != nil {
+				log.Printf("offload scan error shard=%s: %v", id, err)
+			}
+		}(id, leased)
+	}
+	wg.Wait()
 	if memErr != nil {
 		log.Printf("offload mem ratio check failed: %v", memErr)
 	}
-	if scanned > 0 || old > 0 || moved > 0 || writeFail > 0 || parseFail > 0 || forceByMem {
-		log.Printf("offload run: scanned=%d old=%d moved=%d write_fail=%d parse_fail=%d mem_ratio=%.4f force_by_mem=%t (age_cutoff=%d sec, force_min_age=%d sec)", scanned, old, moved, writeFail, parseFail, memRatio, forceByMem, offloadAfterSec, forceMinAgeSec)
+	if total.Scanned > 0 || total.Old > 0 || total.Moved > 0 || total.Reclaimed > 0 || total.Superseded > 0 || total.WriteFail > 0 || total.ParseFail > 0 || forceByMem {
+		log.Printf("offload run: scanned=%d old=%d moved=%d reclaimed=%d superseded=%d write_fail=%d parse_fail=%d unsupported=%d passes_done=%d/%d shards=%d/%d mem_ratio=%.4f force_by_mem=%t took=%s (age_cutoff=%d sec, force_min_age=%d sec)",
+			total.Scanned, total.Old, total.Moved, total.Reclaimed, total.Superseded, total.WriteFail, total.ParseFail, total.Unsupported, passesDone, shards, len(claimed), len(masters), memRatio, forceByMem, time.Since(start).Round(time.Millisecond), cfg.AfterSec, cfg.ForceMinAgeSec)
+	}
+}
+
+// movePolicy adalah aturan pemilihan key berbasis umur (_ts) untuk satu run.
+// Dipakai doOffload dan dry-run agar laporan dry-run sama persis dengan yang akan dipindah.
+type movePolicy struct {
+	cutoff      int64 // unix detik; _ts lebih tua dari ini = "old" (OFFLOAD_AFTER_SECONDS)
+	forceCutoff int64 // unix detik; batas umur saat forceByMem (OFFLOAD_FORCE_MIN_AGE_SECONDS)
+	forceByMem  bool  // mode agresif "scan" aktif
+}
+
+func newMovePolicy(cfg offloadConfig, forceByMem bool) movePolicy {
+	now := time.Now()
+	return movePolicy{
+		cutoff:      now.Add(-time.Duration(cfg.AfterSec) * time.Second).Unix(),
+		forceCutoff: now.Add(-time.Duration(cfg.ForceMinAgeSec) * time.Second).Unix(),
+		forceByMem:  forceByMem,
+	}
+}
+
+// evaluate mengembalikan apakah key "old" (melewati OFFLOAD_AFTER_SECONDS) dan apakah key dipindah.
+func (p movePolicy) evaluate(ts int64, hasTS bool) (old, move bool) {
+	switch {
+	case hasTS && ts < p.cutoff:
+		return true, true
+	case p.forceByMem && hasTS && ts < p.forceCutoff:
+		return false, true
+	case p.forceByMem && !hasTS:
+		return false, true
+	}
+	return false, false
+}
+
+// moveKeys menulis cands sebagai satu segment di cold store lalu menghapusnya dari shard.
+// Key baru dihapus dari Redis setelah segment-nya berhasil ditulis.
+func moveKeys(ctx context.Context, r *redis.ClusterClient, shard *redis.Client, cold coldstore.ColdStore, cfg offloadConfig, cands []candidate, st *runStats) {
+	kvs := make([]coldstore.KeyValue, len(cands))
+	for i, c := range cands {
+		kvs[i] = c.kv
+	}
+	if err := cold.WriteKeyValues(kvs); err != nil {
+		st.WriteFail += len(kvs)
+		log.Printf("offload segment write failed (%d keys): %v", len(kvs), err)
+		return
+	}
+	// Umumkan segment baru sebelum DEL agar ingestor sudah melihatnya saat key hilang dari Redis
+	if err := r.Publish(ctx, coldstore.InvalidateChannel, cold.Name()).Err(); err != nil {
+		log.Printf("offload invalidate publish failed: %v", err)
+	}
+	// DEL bersyarat: key hanya dihapus jika value-nya masih sama dengan yang ditulis ke segment.
+	// Jika ingestor sudah menimpa key sejak GET, value baru tetap di Redis dan salinan
+	// lama di cold store ditandai usang (Supersede) agar tidak pernah terbaca lagi.
+	// Semua DEL satu segment dikirim sebagai satu pipeline ke shard
+	keys := make([]string, len(kvs))
+	vals := make([][]byte, len(kvs))
+	for i, c := range cands {
+		keys[i], vals[i] = c.kv.Key, c.version
+	}
+	_ = cfg.Limiter.Wait(ctx, len(keys))
+	results, errs := redisx.CompareAndDeleteMany(ctx, shard, keys, vals)
+	superseded := 0
+	for i, kv := range kvs {
+		if errs[i] != nil {
+			log.Printf("offload conditional del failed key=%q: %v", kv.Key, errs[i])
+			continue
+		}
+		switch results[i] {
+		case redisx.CADDeleted:
+			st.Moved++
+		case redisx.CADChanged:
+			st.Superseded++
+			if err := cold.Supersede(kv.Key, kv.TS); err != nil {
+				log.Printf("offload supersede failed key=%q: %v", kv.Key, err)
+			} else {
+				superseded++
+			}
+		}
+		// CADMissing: key hilang dari Redis (expired/dihapus/evicted); salinan di cold store
+		// adalah value terakhir dan tetap dipakai (delete punya tombstone, expiry punya ExpireAt)
+	}
+	// Tombstone baru: ingestor memuat ulang daftar tombstone-nya
+	if superseded > 0 {
+		if err := r.Publish(ctx, coldstore.InvalidateChannel, cold.Name()).Err(); err != nil {
+			log.Printf("offload invalidate publish failed: %v", err)
+		}
 	}
 }
 
+// extractTS mengembalikan _ts value dalam unix detik (policy umur, laporan dan reclaim bekerja
+// dalam detik). _ts sendiri ditulis dalam milidetik; lihat coldstore.ValueTS.
 func extractTS(val []byte) (int64, bool) {
-	var payload map[string]any
-	if err := json.Unmarshal(val, &payload); err != nil {
-		return 0, false
-	}
-	raw, ok := payload["_ts"]
-	if !ok {
-		return 0, false
-	}
-	switch ts := raw.(type) {
-	case float64:
-		return int64(ts), true
-	case int64:
-		return ts, true
-	case int:
-		return int64(ts), true
-	case string:
-		if v, err := strconv.ParseInt(ts, 10, 64); err == nil {
-			return v, true
-		}
-	}
-	return 0, false
+	ms, ok := coldstore.ValueTS(val)
+	return ms / 1000, ok
 }
 
 func getFloat(env string, def float64) float64 {
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+// testEnv adalah offloader di atas Redis in-memory satu shard dan cold store local di direktori sementara.
+type testEnv struct {
+	ctx   context.Context
+	srv   *redistest.Server
+	r     *redis.ClusterClient
+	shard *redis.Client
+	cold  coldstore.ColdStore
+	cfg   offloadConfig
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	t.Setenv("COLD_STORE", "local")
+	t.Setenv("COLD_STORE_DIR", t.TempDir())
+	e := &testEnv{ctx: context.Background(), srv: redistest.NewServer(t)}
+	e.r = e.srv.Cluster(t)
+	e.shard = e.srv.Client(t)
+	e.cold = coldstore.New()
+	if err := e.cold.Init(); err != nil {
+		t.Fatal(err)
+	}
+	e.cfg = offloadConfig{
+		AfterSec:       600,
+		ForceMemRatio:  0.7,
+		ForceMinAgeSec: 5,
+		SegmentMax:     1000,
+		ScanCount:      100,
+		Workers:        1,
+		ForceMode:      forceModeReclaim,
+		Reclaim:        reclaimConfig{Target: 0.5, Sample: 100, Batch: 10},
+	}
+	return e
+}
+
+// move memindahkan keys seperti satu flush doOffload (fetchValues + moveKeys).
+func (e *testEnv) move(t *testing.T, keys ...string) runStats {
+	t.Helper()
+	var st runStats
+	vals := fetchValues(e.ctx, e.shard, e.cfg, keys)
+	cands := make([]candidate, 0, len(keys))
+	for i, k := range keys {
+		if vals[i].err != nil {
+			t.Fatalf("fetch %s: %v", k, vals[i].err)
+		}
+		cands = append(cands, newCandidate(k, vals[i]))
+	}
+	moveKeys(e.ctx, e.r, e.shard, e.cold, e.cfg, cands, &st)
+	return st
+}
+
+// oldValue adalah value ingestor dengan _ts d yang lalu.
+func oldValue(d time.Duration) string {
+	return `{"v":1,"_ts":` + strconv.FormatInt(time.Now().Add(-d).UnixMilli(), 10) + `}`
+}
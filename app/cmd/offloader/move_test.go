@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+// Key yang ditimpa / dihapus di antara GET dan DEL bersyarat: value baru tetap di Redis,
+// salinan lama di cold store disembunyikan (ditimpa) atau tetap dipakai (dihapus dari Redis).
+func TestMoveKeysRace(t *testing.T) {
+	e := newTestEnv(t)
+	keys := []string{"same", "overwritten", "gone"}
+	for _, k := range keys {
+		if err := e.shard.Set(e.ctx, k, oldValue(time.Hour), 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vals := fetchValues(e.ctx, e.shard, e.cfg, keys)
+	cands := make([]candidate, len(keys))
+	for i, k := range keys {
+		cands[i] = newCandidate(k, vals[i])
+	}
+	// Penulis lain menyelip setelah GET
+	fresh := oldValue(0)
+	e.shard.Set(e.ctx, "overwritten", fresh, 0)
+	e.shard.Del(e.ctx, "gone")
+
+	var st runStats
+	moveKeys(e.ctx, e.r, e.shard, e.cold, e.cfg, cands, &st)
+	if st.Moved != 1 || st.Superseded != 1 || st.WriteFail != 0 {
+		t.Fatalf("stats = %+v, want Moved=1 Superseded=1", st)
+	}
+	if got := e.srv.Keys(); len(got) != 1 || got[0] != "overwritten" {
+		t.Fatalf("redis keys = %v, want [overwritten]", got)
+	}
+	if v, _ := e.srv.Get("overwritten"); v != fresh {
+		t.Fatalf("overwritten = %s, want the new value %s", v, fresh)
+	}
+	if _, err := e.cold.ReadByKey("same"); err != nil {
+		t.Fatalf("moved key not readable from cold store: %v", err)
+	}
+	if v, err := e.cold.ReadByKey("overwritten"); !errors.Is(err, coldstore.ErrDeleted) && !errors.Is(err, coldstore.ErrNotFound) {
+		t.Fatalf("stale cold copy still readable: %s, %v", v, err)
+	}
+	if _, err := e.cold.ReadByKey("gone"); err != nil {
+		t.Fatalf("cold copy of key deleted from redis after GET: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/coldstore"
+)
+
+// Key yang dipromosikan dari cold store (ingestor Promote / rehydrate memakai coldstore.TouchTS)
+// tidak boleh langsung dipilih lagi oleh policy umur di run offload berikutnya.
+func TestPromotedKeyNotPickedByMovePolicy(t *testing.T) {
+	now := time.Now()
+	pol := newMovePolicy(offloadConfig{AfterSec: 600, ForceMinAgeSec: 5}, false)
+	written := now.Add(-2 * time.Hour)
+
+	cases := []struct {
+		name string
+		typ  string
+		val  string
+	}{
+		{"string", "", fmt.Sprintf(`{"_ts":%d,"user":"u1","score":0.5}`, written.UnixMilli())},
+		{"hash", "hash", fmt.Sprintf(`{"_ts":"%d","user":"u1"}`, written.UnixMilli())},
+		// Value yang ditulis sebelum _ts memakai milidetik
+		{"string seconds", "", fmt.Sprintf(`{"_ts":%d,"user":"u1"}`, written.Unix())},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, ok := extractTS([]byte(tc.val))
+			if old, move := pol.evaluate(ts, ok); !ok || !old || !move {
+				t.Fatalf("cold value: ts=%d ok=%t old=%t move=%t, want old key to be moved", ts, ok, old, move)
+			}
+
+			promoted := coldstore.TouchTS(tc.typ, []byte(tc.val), now)
+			ts, ok = extractTS(promoted)
+			if !ok || ts != now.Unix() {
+				t.Fatalf("promoted value %s: _ts=%d ok=%t, want %d", promoted, ts, ok, now.Unix())
+			}
+			if old, move := pol.evaluate(ts, ok); old || move {
+				t.Fatalf("promoted key picked by movePolicy (old=%t move=%t)", old, move)
+			}
+		})
+	}
+}
+
+func TestTouchTSKeepsPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	got := string(coldstore.TouchTS("", []byte(`{"note":"a<b","_ts":1,"n":12345678901234567890}`), now))
+	want := `{"_ts":1700000000000,"n":12345678901234567890,"note":"a<b"}`
+	if got != want {
+		t.Fatalf("TouchTS = %s, want %s", got, want)
+	}
+	// Objek JSON tanpa _ts diberi _ts (hash: sebagai string), agar umurnya bisa dihitung offloader
+	for _, tc := range []struct{ typ, val, want string }{
+		{"", `{"v":1}`, `{"_ts":1700000000000,"v":1}`},
+		{"string", `{}`, `{"_ts":1700000000000}`},
+		{"hash", `{"f":"x"}`, `{"_ts":"1700000000000","f":"x"}`},
+	} {
+		got := coldstore.TouchTS(tc.typ, []byte(tc.val), now)
+		if string(got) != tc.want {
+			t.Fatalf("TouchTS(%q, %s) = %s, want %s", tc.typ, tc.val, got, tc.want)
+		}
+		if ts, ok := extractTS(got); !ok || ts != now.Unix() {
+			t.Fatalf("extractTS(%s) = %d, %t", got, ts, ok)
+		}
+	}
+	// Bukan objek JSON, atau list/set/zset, tidak diubah
+	for _, tc := range []struct{ typ, val string }{
+		{"", `plain text`},
+		{"", `[1,2]`},
+		{"", `null`},
+		{"", `42`},
+		{"list", `["{\"_ts\":1}"]`},
+		{"zset", `{"a":1}`},
+	} {
+		if got := string(coldstore.TouchTS(tc.typ, []byte(tc.val), now)); got != tc.val {
+			t.Fatalf("TouchTS(%q, %s) = %s, want unchanged", tc.typ, tc.val, got)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// Mode agresif (OFFLOAD_FORCE_MODE) saat memori shard >= OFFLOAD_FORCE_MEM_RATIO.
+const (
+	// forceModeReclaim: pindahkan key yang paling lama tidak diakses dulu sampai rasio memori
+	// shard turun di bawah target (OFFLOAD_RECLAIM_TARGET_RATIO / OFFLOAD_RECLAIM_SHARD_TARGETS).
+	forceModeReclaim = "reclaim"
+	// forceModeScan: perilaku lama; selama SCAN, semua key yang lebih tua dari
+	// OFFLOAD_FORCE_MIN_AGE_SECONDS dipindah (urutan SCAN, tanpa target).
+	forceModeScan = "scan"
+)
+
+// reclaimConfig adalah konfigurasi mode reclaim.
+type reclaimConfig struct {
+	Target  float64            // target rasio memori per shard
+	Targets map[string]float64 // target khusus per shard (key: node ID atau host:port)
+	Sample  int                // jumlah key acak (RANDOMKEY) yang dinilai per putaran
+	Batch   int                // jumlah key dengan idle time terbesar yang dipindah per putaran
+}
+
+// parseShardTargets mem-parse "host:port=0.4,<node id>=0.5" menjadi map target per shard.
+func parseShardTargets(s string) map[string]float64 {
+	out := map[string]float64{}
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			out[k] = f
+		}
+	}
+	return out
+}
+
+// target mengembalikan target rasio memori untuk shard (node ID id, alamat addr).
+func (c reclaimConfig) target(id, addr string) float64 {
+	if t, ok := c.Targets[id]; ok {
+		return t
+	}
+	if t, ok := c.Targets[addr]; ok {
+		return t
+	}
+	return c.Target
+}
+
+// idleKey adalah kandidat reclaim beserta lama tidak diakses (detik).
+type idleKey struct {
+	key  string
+	idle int64
+	f    *fetched // value yang sudah dibaca saat sampling (fallback tanpa NO-TOUCH), nil jika belum
+}
+
+// reclaimShard memindahkan key dari satu shard, dimulai dari yang paling lama tidak diakses,
+// sampai rasio memori shard < target, kandidat habis, deadline lewat, atau stop() true.
+// Pemilihan korban meniru approximated LRU Redis: setiap putaran mengambil Sample key acak,
+// membaca OBJECT IDLETIME-nya, lalu memindahkan Batch key dengan idle terbesar.
+// Key yang idle kurang dari cfg.ForceMinAgeSec tidak pernah dipindah.
+// Mengembalikan rasio memori sebelum dan sesudah reclaim.
+func reclaimShard(ctx context.Context, r *redis.ClusterClient, shard *redis.Client, cold coldstore.ColdStore, cfg offloadConfig, target float64, deadline time.Time, stop func() bool, st *runStats) (float64, float64, error) {
+	var before, after float64
+	for round := 0; ; round++ {
+		ratio, err := redisx.ShardMemRatio(ctx, shard)
+		if err != nil {
+			return before, after, err
+		}
+		if round == 0 {
+			before = ratio
+		}
+		after = ratio
+		if ratio < target || stop() || (!deadline.IsZero() && time.Now().After(deadline)) {
+			return before, after, nil
+		}
+
+		cands, err := sampleIdleKeys(ctx, shard, cfg)
+		if err != nil {
+			return before, after, err
+		}
+		if len(cands) == 0 {
+			return before, after, nil
+		}
+		cands = pickIdlest(cands, cfg.Reclaim.Batch)
+
+		fillValues(ctx, shard, cfg, cands)
+		var move []candidate
+		for _, c := range cands {
+			if c.f.err != nil {
+				if unsupported(c.f.err) {
+					st.Unsupported++
+				}
+				continue // Sudah hilang, atau tipenya tidak didukung
+			}
+			move = append(move, newCandidate(c.key, *c.f))
+		}
+		if len(move) == 0 {
+			return before, after, nil
+		}
+		moved := st.Moved
+		moveKeys(ctx, r, shard, cold, cfg, move, st)
+		st.Reclaimed += st.Moved - moved
+		if st.Moved == moved {
+			return before, after, nil // Tidak ada progres (mis. cold store gagal): jangan berputar terus
+		}
+	}
+}
+
+// pickIdlest mengurutkan kandidat dari idle terbesar dan mengambil maksimal n.
+func pickIdlest(cands []idleKey, n int) []idleKey {
+	sort.Slice(cands, func(i, j int) bool { return cands[i].idle > cands[j].idle })
+	if len(cands) > n {
+		cands = cands[:n]
+	}
+	return cands
+}
+
+// fillValues membaca value kandidat yang belum dibaca saat sampling (satu fetchValues).
+func fillValues(ctx context.Context, shard *redis.Client, cfg offloadConfig, cands []idleKey) {
+	var keys []string
+	for _, c := range cands {
+		if c.f == nil {
+			keys = append(keys, c.key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	fs := fetchValues(ctx, shard, cfg, keys)
+	for i, j := 0, 0; i < len(cands); i++ {
+		if cands[i].f == nil {
+			cands[i].f = &fs[j]
+			j++
+		}
+	}
+}
+
+// sampleIdleKeys mengambil cfg.Reclaim.Sample key acak dari shard (pipeline RANDOMKEY)
+// lalu membaca OBJECT IDLETIME-nya (pipeline kedua). Key internal offloader dan key yang
+// baru diakses (idle < OFFLOAD_FORCE_MIN_AGE_SECONDS) dibuang dari kandidat.
+//
+// OBJECT IDLETIME hanya bisa dipercaya jika read offloader sendiri (fetchValues, /report)
+// tidak me-reset-nya, yaitu saat CLIENT NO-TOUCH aktif (cfg.NoTouch, Redis >= 7.2). Tanpa itu,
+// value sample dibaca (setelah idle time diambil) dan umur key dihitung dari _ts value
+// (waktu tulis/promosi terakhir); key tanpa _ts tetap memakai idle time.
+func sampleIdleKeys(ctx context.Context, shard *redis.Client, cfg offloadConfig) ([]idleKey, error) {
+	_ = cfg.Limiter.Wait(ctx, cfg.Reclaim.Sample)
+	rnd := make([]*redis.StringCmd, cfg.Reclaim.Sample)
+	if _, err := shard.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i := range rnd {
+			rnd[i] = p.RandomKey(ctx)
+		}
+		return nil
+	}); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, cmd := range rnd {
+		k, err := cmd.Result()
+		if err != nil || seen[k] || strings.HasPrefix(k, internalKeyPrefix) {
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+
+	_ = cfg.Limiter.Wait(ctx, len(keys))
+	idles := make([]*redis.DurationCmd, len(keys))
+	_, _ = shard.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			idles[i] = p.ObjectIdleTime(ctx, k)
+		}
+		return nil
+	})
+	out := make([]idleKey, 0, len(keys))
+	for i, k := range keys {
+		idle, err := idles[i].Result()
+		if err != nil {
+			continue
+		}
+		out = append(out, idleKey{key: k, idle: int64(idle / time.Second)})
+	}
+	if !cfg.NoTouch.Active() && len(out) > 0 {
+		keys = keys[:0]
+		for _, c := range out {
+			keys = append(keys, c.key)
+		}
+		now := time.Now().Unix()
+		for i, f := range fetchValues(ctx, shard, cfg, keys) {
+			f := f
+			out[i].f = &f
+			if ts, ok := extractTS(f.val); ok && f.err == nil {
+				out[i].idle = now - ts
+			}
+		}
+	}
+	kept := out[:0]
+	for _, c := range out {
+		if c.idle >= int64(cfg.ForceMinAgeSec) {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/redisx"
+)
+
+func TestReclaimTargets(t *testing.T) {
+	cfg := reclaimConfig{Target: 0.5, Targets: parseShardTargets(" redis-1:7001=0.4, n2=0.3,bad,n3=x,n4=0")}
+	if want := map[string]float64{"redis-1:7001": 0.4, "n2": 0.3}; !reflect.DeepEqual(cfg.Targets, want) {
+		t.Fatalf("parseShardTargets = %v, want %v", cfg.Targets, want)
+	}
+	for _, tc := range []struct {
+		id, addr string
+		want     float64
+	}{
+		{"n1", "redis-1:7001", 0.4},
+		{"n2", "redis-2:7002", 0.3},
+		{"n2", "redis-1:7001", 0.3}, // node ID didahulukan
+		{"n9", "redis-9:7009", 0.5},
+	} {
+		if got := cfg.target(tc.id, tc.addr); got != tc.want {
+			t.Errorf("target(%s, %s) = %v, want %v", tc.id, tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestPickIdlest(t *testing.T) {
+	got := pickIdlest([]idleKey{{key: "a", idle: 5}, {key: "b", idle: 50}, {key: "c", idle: 20}}, 2)
+	if len(got) != 2 || got[0].key != "b" || got[1].key != "c" {
+		t.Fatalf("pickIdlest = %+v, want b, c", got)
+	}
+}
+
+// Dengan NO-TOUCH, key dipindah mulai dari OBJECT IDLETIME terbesar sampai rasio memori < target.
+func TestReclaimMovesIdlestUntilTarget(t *testing.T) {
+	e := newTestEnv(t)
+	idle := map[string]time.Duration{"a": 4000 * time.Second, "b": 3000 * time.Second, "c": 2000 * time.Second, "d": 0}
+	for k, d := range idle {
+		e.shard.Set(e.ctx, k, oldValue(0), 0)
+		e.srv.SetIdle(k, d)
+	}
+	size, err := e.shard.MemoryUsage(e.ctx, "a").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.srv.SetMemory(0, 4*size) // rasio 1.0; target 0.6 tercapai setelah dua key dipindah
+	e.cfg.NoTouch = &redisx.NoTouch{}
+	e.cfg.Reclaim = reclaimConfig{Sample: 100, Batch: 1}
+
+	var st runStats
+	before, after, err := reclaimShard(e.ctx, e.r, e.shard, e.cold, e.cfg, 0.6, time.Time{}, func() bool { return false }, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != 1 || after != 0.5 || st.Reclaimed != 2 || st.Moved != 2 {
+		t.Fatalf("ratio %v -> %v, stats %+v; want 1 -> 0.5 with 2 reclaimed", before, after, st)
+	}
+	if got := e.srv.Keys(); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Fatalf("keys left = %v, want the two most recently used", got)
+	}
+}
+
+// Tanpa NO-TOUCH (Redis < 7.2) idle time tidak bisa dipercaya: umur dihitung dari _ts value,
+// dan key baru tidak pernah dipindah walaupun idle time-nya besar.
+func TestReclaimWithoutNoTouchUsesTS(t *testing.T) {
+	e := newTestEnv(t)
+	e.shard.Set(e.ctx, "old", oldValue(2*time.Hour), 0)
+	e.shard.Set(e.ctx, "fresh", oldValue(0), 0)
+	e.shard.Set(e.ctx, "raw", "no ts", 0)
+	e.srv.SetIdle("fresh", time.Hour)
+	e.srv.SetIdle("raw", time.Hour)
+	e.srv.SetMemory(1000, 1000)
+	e.cfg.Reclaim = reclaimConfig{Sample: 100, Batch: 10}
+
+	var st runStats
+	if _, _, err := reclaimShard(e.ctx, e.r, e.shard, e.cold, e.cfg, 0.5, time.Time{}, func() bool { return false }, &st); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.srv.Keys(); !reflect.DeepEqual(got, []string{"fresh"}) {
+		t.Fatalf("keys left = %v, want [fresh] (stats %+v)", got, st)
+	}
+}
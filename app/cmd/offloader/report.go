@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/flight"
+	"monolith-kv-sim/internal/redisx"
+)
+
+// Dry-run: SCAN dan evaluasi policy yang sama dengan doOffload (movePolicy, mode reclaim),
+// tanpa menulis ke cold store, tanpa DEL, tanpa lease, dan tanpa menyimpan checkpoint.
+// Setiap laporan adalah satu pass penuh dari cursor 0 di semua master shard.
+
+const (
+	// reportMaxPrefixes membatasi jumlah prefix di laporan; sisanya digabung ke "(other)"
+	reportMaxPrefixes = 1000
+	otherPrefix       = "(other)"
+	noPrefix          = "(none)"
+
+	// reclaimPreviewMaxRounds membatasi putaran sampling simulasi reclaim per shard
+	reclaimPreviewMaxRounds = 50
+)
+
+// ageBuckets adalah batas atas (detik) bucket histogram umur; bucket terakhir tanpa batas.
+var ageBuckets = []struct {
+	label string
+	le    int64
+}{
+	{"<1m", 60}, {"1m-5m", 300}, {"5m-15m", 900}, {"15m-1h", 3600},
+	{"1h-6h", 21600}, {"6h-24h", 86400}, {">24h", -1},
+}
+
+// keyGroup adalah jumlah key dan byte value untuk satu prefix / bucket umur.
+type keyGroup struct {
+	Keys           int   `json:"keys"`
+	Bytes          int64 `json:"bytes"`
+	Candidates     int   `json:"candidates"`
+	CandidateBytes int64 `json:"candidate_bytes"`
+}
+
+func (g *keyGroup) add(n int64, candidate bool) {
+	g.Keys++
+	g.Bytes += n
+	if candidate {
+		g.Candidates++
+		g.CandidateBytes += n
+	}
+}
+
+func (g *keyGroup) merge(o keyGroup) {
+	g.Keys += o.Keys
+	g.Bytes += o.Bytes
+	g.Candidates += o.Candidates
+	g.CandidateBytes += o.CandidateBytes
+}
+
+// shardReport adalah kondisi memori satu shard dan apakah mode reclaim akan aktif.
+// Untuk shard dengan would_reclaim, reclaim_* adalah perkiraan key yang akan dipindah mode reclaim
+// sebelum SCAN berbasis umur (bisa beririsan dengan candidates).
+type shardReport struct {
+	Addr              string  `json:"addr"`
+	MemRatio          float64 `json:"mem_ratio"`
+	ReclaimTarget     float64 `json:"reclaim_target,omitempty"`
+	WouldReclaim      bool    `json:"would_reclaim"`
+	ReclaimNeedBytes  int64   `json:"reclaim_need_bytes,omitempty"`   // used_memory - target * maxmemory
+	ReclaimCandidates int     `json:"reclaim_candidates,omitempty"`   // key dengan idle terbesar sampai need terpenuhi
+	ReclaimBytes      int64   `json:"reclaim_bytes,omitempty"`        // jumlah MEMORY USAGE kandidat reclaim
+	ReclaimMinIdleSec int64   `json:"reclaim_min_idle_sec,omitempty"` // idle terkecil di antara kandidat reclaim
+	Scanned           int     `json:"scanned"`
+	Candidates        int     `json:"candidates"`
+}
+
+// dryRunReport adalah hasil dry-run; "candidates" = key yang akan dipindah oleh SCAN berbasis umur.
+type dryRunReport struct {
+	GeneratedAt    time.Time               `json:"generated_at"`
+	TookMS         int64                   `json:"took_ms"`
+	AfterSeconds   int                     `json:"after_seconds"`
+	ForceMode      string                  `json:"force_mode"`
+	MemRatio       float64                 `json:"mem_ratio"`
+	ForceByMem     bool                    `json:"force_by_mem"`
+	Scanned        int                     `json:"scanned"`
+	Bytes          int64                   `json:"bytes"`
+	Candidates     int                     `json:"candidates"`
+	CandidateBytes int64                   `json:"candidate_bytes"`
+	ReclaimCands   int                     `json:"reclaim_candidates"` // total kandidat mode reclaim (lihat shardReport)
+	ReclaimBytes   int64                   `json:"reclaim_bytes"`
+	Old            int                     `json:"old"`
+	ParseFail      int                     `json:"parse_fail"`
+	ReadFail       int                     `json:"read_fail"`   // key hilang selama SCAN / error baca
+	Unsupported    int                     `json:"unsupported"` // tipe yang tidak bisa dipindah (stream, module, member bukan UTF-8)
+	ByPrefix       map[string]*keyGroup    `json:"by_prefix"`
+	ByType         map[string]*keyGroup    `json:"by_type"`       // bytes = ukuran value / typed envelope JSON
+	AgeHistogram   map[string]*keyGroup    `json:"age_histogram"` // "no_ts" untuk value tanpa _ts
+	Shards         map[string]*shardReport `json:"shards"`        // key: node ID
+	Errors         []string                `json:"errors,omitempty"`
+}
+
+func newDryRunReport() *dryRunReport {
+	return &dryRunReport{
+		ByPrefix:     map[string]*keyGroup{},
+		ByType:       map[string]*keyGroup{},
+		AgeHistogram: map[string]*keyGroup{},
+		Shards:       map[string]*shardReport{},
+	}
+}
+
+// prefixGroup mengambil grup by_prefix, dengan batas reportMaxPrefixes prefix berbeda.
+func (rep *dryRunReport) prefixGroup(p string) *keyGroup {
+	if _, ok := rep.ByPrefix[p]; !ok && len(rep.ByPrefix) >= reportMaxPrefixes {
+		p = otherPrefix
+	}
+	return rep.group(rep.ByPrefix, p)
+}
+
+func (rep *dryRunReport) group(m map[string]*keyGroup, name string) *keyGroup {
+	g, ok := m[name]
+	if !ok {
+		g = &keyGroup{}
+		m[name] = g
+	}
+	return g
+}
+
+// merge menggabungkan laporan satu shard ke laporan total.
+func (rep *dryRunReport) merge(o *dryRunReport) {
+	rep.Scanned += o.Scanned
+	rep.Bytes += o.Bytes
+	rep.Candidates += o.Candidates
+	rep.CandidateBytes += o.CandidateBytes
+	rep.Old += o.Old
+	rep.ParseFail += o.ParseFail
+	rep.ReadFail += o.ReadFail
+	rep.Unsupported += o.Unsupported
+	for p, g := range o.ByPrefix {
+		rep.prefixGroup(p).merge(*g)
+	}
+	for t, g := range o.ByType {
+		rep.group(rep.ByType, t).merge(*g)
+	}
+	for b, g := range o.AgeHistogram {
+		rep.group(rep.AgeHistogram, b).merge(*g)
+	}
+}
+
+// keyPrefix mengambil depth segmen pertama key (dipisah ":"), mis. depth 2: "feature:COLD:<uuid>" -> "feature:COLD".
+// Key yang lebih pendek memakai semua segmen kecuali yang terakhir ("user:1" -> "user");
+// key tanpa ":" masuk "(none)".
+func keyPrefix(key string, depth int) string {
+	parts := strings.SplitN(key, ":", depth+1)
+	switch {
+	case len(parts) > depth:
+		return strings.Join(parts[:depth], ":")
+	case len(parts) == 1:
+		return noPrefix
+	}
+	return strings.Join(parts[:len(parts)-1], ":")
+}
+
+// ageBucket mengembalikan label bucket histogram untuk umur age (detik).
+func ageBucket(age int64) string {
+	for _, b := range ageBuckets {
+		if b.le < 0 || age < b.le {
+			return b.label
+		}
+	}
+	return ageBuckets[len(ageBuckets)-1].label
+}
+
+// dryRun menjalankan satu pass penuh read-only di semua master shard dan menyusun laporan.
+// prefixDepth adalah jumlah segmen key (dipisah ":") yang dipakai untuk pengelompokan by_prefix.
+func dryRun(ctx context.Context, r *redis.ClusterClient, cfg offloadConfig, prefixDepth int) *dryRunReport {
+	start := time.Now()
+	memRatio, memErr := redisx.ClusterMemRatio(ctx, r)
+	forceByMem := cfg.ForceMode == forceModeScan && memErr == nil && memRatio >= cfg.ForceMemRatio
+	pol := newMovePolicy(cfg, forceByMem)
+	now := time.Now().Unix()
+
+	total := newDryRunReport()
+	total.GeneratedAt = start
+	total.AfterSeconds = cfg.AfterSec
+	total.ForceMode = cfg.ForceMode
+	total.MemRatio = memRatio
+	total.ForceByMem = forceByMem
+
+	var mu sync.Mutex
+	fail := func(err error) {
+		mu.Lock()
+		total.Errors = append(total.Errors, err.Error())
+		mu.Unlock()
+	}
+	err := r.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+		id, err := shardID(ctx, shard)
+		if err != nil {
+			fail(err)
+			return nil
+		}
+		rep := newDryRunReport()
+		sr := &shardReport{Addr: shard.Options().Addr}
+		if used, maxm, err := redisx.ShardMemory(ctx, shard); err == nil && maxm > 0 {
+			sr.MemRatio = float64(used) / float64(maxm)
+			if cfg.ForceMode == forceModeReclaim {
+				sr.ReclaimTarget = cfg.Reclaim.target(id, sr.Addr)
+				sr.WouldReclaim = sr.MemRatio >= cfg.ForceMemRatio
+			}
+			if sr.WouldReclaim {
+				sr.ReclaimNeedBytes = used - int64(sr.ReclaimTarget*float64(maxm))
+				if err := reclaimPreview(ctx, shard, cfg, sr); err != nil {
+					fail(err)
+				}
+			}
+		}
+
+		var cursor uint64
+		for {
+			_ = cfg.Limiter.Wait(ctx, 1)
+			keys, next, err := shard.Scan(ctx, cursor, "*", int64(cfg.ScanCount)).Result()
+			if err != nil {
+				fail(err)
+				break
+			}
+			vals := fetchValues(ctx, shard, cfg, keys)
+			for i, key := range keys {
+				if strings.HasPrefix(key, internalKeyPrefix) {
+					continue
+				}
+				rep.Scanned++
+				val, err := vals[i].val, vals[i].err
+				if err != nil {
+					if unsupported(err) {
+						rep.Unsupported++
+					} else {
+						rep.ReadFail++
+					}
+					continue
+				}
+				typ := vals[i].typ
+				if typ == "" {
+					typ = redisx.TypeString
+				}
+				ts, hasTS := extractTS(val)
+				old, move := pol.evaluate(ts, hasTS)
+				n := int64(len(val))
+				rep.Bytes += n
+				if !hasTS {
+					rep.ParseFail++
+				}
+				if old {
+					rep.Old++
+				}
+				if move {
+					rep.Candidates++
+					rep.CandidateBytes += n
+				}
+				rep.prefixGroup(keyPrefix(key, prefixDepth)).add(n, move)
+				rep.group(rep.ByType, typ).add(n, move)
+				bucket := "no_ts"
+				if hasTS {
+					bucket = ageBucket(now - ts)
+				}
+				rep.group(rep.AgeHistogram, bucket).add(n, move)
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+		sr.Scanned, sr.Candidates = rep.Scanned, rep.Candidates
+
+		mu.Lock()
+		total.merge(rep)
+		total.ReclaimCands += sr.ReclaimCandidates
+		total.ReclaimBytes += sr.ReclaimBytes
+		total.Shards[id] = sr
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		total.Errors = append(total.Errors, err.Error())
+	}
+	if memErr != nil {
+		total.Errors = append(total.Errors, "mem ratio: "+memErr.Error())
+	}
+	total.TookMS = time.Since(start).Milliseconds()
+	return total
+}
+
+// reclaimPreview mensimulasikan reclaimShard tanpa memindahkan apa pun: setiap putaran mengambil
+// sample idle yang sama (sampleIdleKeys) dan Batch key dengan idle terbesar yang belum terpilih,
+// lalu menjumlahkan MEMORY USAGE-nya sampai sr.ReclaimNeedBytes terpenuhi. Karena key tidak benar-benar
+// dihapus, hasilnya perkiraan (dibatasi reclaimPreviewMaxRounds putaran).
+func reclaimPreview(ctx context.Context, shard *redis.Client, cfg offloadConfig, sr *shardReport) error {
+	picked := map[string]bool{}
+	for round := 0; sr.ReclaimBytes < sr.ReclaimNeedBytes && round < reclaimPreviewMaxRounds; round++ {
+		sample, err := sampleIdleKeys(ctx, shard, cfg)
+		if err != nil {
+			return err
+		}
+		cands := sample[:0]
+		for _, c := range sample {
+			if !picked[c.key] {
+				cands = append(cands, c)
+			}
+		}
+		if len(cands) == 0 {
+			return nil
+		}
+		cands = pickIdlest(cands, cfg.Reclaim.Batch)
+		fillValues(ctx, shard, cfg, cands)
+
+		_ = cfg.Limiter.Wait(ctx, len(cands))
+		usage := make([]*redis.IntCmd, len(cands))
+		_, _ = shard.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for i, c := range cands {
+				usage[i] = p.MemoryUsage(ctx, c.key)
+			}
+			return nil
+		})
+		for i, c := range cands {
+			picked[c.key] = true
+			n, err := usage[i].Result()
+			if c.f.err != nil || err != nil {
+				continue // Tidak akan dipindah reclaim (hilang / tipe tidak didukung)
+			}
+			sr.ReclaimCandidates++
+			sr.ReclaimBytes += n
+			if sr.ReclaimMinIdleSec == 0 || c.idle < sr.ReclaimMinIdleSec {
+				sr.ReclaimMinIdleSec = c.idle
+			}
+		}
+	}
+	return nil
+}
+
+// serveReports menjalankan HTTP server dry-run di addr (GET /report, lihat reportHandler).
+func serveReports(addr string, r *redis.ClusterClient, cfg offloadConfig, prefixDepth int) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report", reportHandler(r, cfg, prefixDepth))
+	log.Printf("offloader: dry-run report server listening on %s (GET /report)", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// reportHandler menjalankan dry-run baru untuk setiap request dan membalas laporan JSON.
+// Query after_seconds mengganti OFFLOAD_AFTER_SECONDS untuk laporan itu
+// (untuk mencoba nilai baru sebelum diubah), prefix_depth mengganti OFFLOAD_REPORT_PREFIX_DEPTH.
+//
+// Setiap laporan adalah SCAN penuh semua shard, jadi hanya satu dry-run yang berjalan pada satu waktu:
+// request dengan parameter sama yang datang selama dry-run berjalan memakai hasilnya (flight.Group),
+// request dengan parameter lain menunggu giliran. Dry-run tidak dibatalkan jika client yang memulainya
+// putus, karena request lain mungkin sedang menunggu hasil yang sama.
+func reportHandler(r *redis.ClusterClient, cfg offloadConfig, prefixDepth int) http.HandlerFunc {
+	var (
+		runs flight.Group[*dryRunReport]
+		mu   sync.Mutex
+	)
+	return func(w http.ResponseWriter, req *http.Request) {
+		c, depth := cfg, prefixDepth
+		if v, err := strconv.Atoi(req.URL.Query().Get("after_seconds")); err == nil && v >= 0 {
+			c.AfterSec = v
+		}
+		if v, err := strconv.Atoi(req.URL.Query().Get("prefix_depth")); err == nil && v > 0 {
+			depth = v
+		}
+		ctx := context.WithoutCancel(req.Context())
+		rep, _, _ := runs.Do(fmt.Sprintf("%d/%d", c.AfterSec, depth), func() (*dryRunReport, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return dryRun(ctx, r, c, depth), nil
+		})
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	for _, tc := range []struct {
+		key   string
+		depth int
+		want  string
+	}{
+		{"feature:COLD:1234", 2, "feature:COLD"},
+		{"feature:COLD:1234", 1, "feature"},
+		{"user:1", 2, "user"},
+		{"a:b:c:d", 3, "a:b:c"},
+		{"plain", 2, noPrefix},
+	} {
+		if got := keyPrefix(tc.key, tc.depth); got != tc.want {
+			t.Errorf("keyPrefix(%q, %d) = %q, want %q", tc.key, tc.depth, got, tc.want)
+		}
+	}
+}
+
+func TestAgeBucket(t *testing.T) {
+	for age, want := range map[int64]string{0: "<1m", 59: "<1m", 60: "1m-5m", 3599: "15m-1h", 7200: "1h-6h", 86400: ">24h"} {
+		if got := ageBucket(age); got != want {
+			t.Errorf("ageBucket(%d) = %q, want %q", age, got, want)
+		}
+	}
+}
+
+// reportEnv mengisi shard dengan key campuran (umur, prefix, tipe) untuk laporan dry-run.
+func reportEnv(t *testing.T) *testEnv {
+	e := newTestEnv(t)
+	e.cfg.AfterSec = 3600
+	e.shard.Set(e.ctx, "user:1", oldValue(2*time.Hour), 0)
+	e.shard.Set(e.ctx, "user:2", oldValue(0), 0)
+	e.shard.Set(e.ctx, "user:3", oldValue(30*time.Minute), 0)
+	e.shard.Set(e.ctx, "feature:COLD:x", oldValue(2*time.Hour), 0)
+	e.shard.Set(e.ctx, "raw", "no ts", 0)
+	e.shard.HSet(e.ctx, "h:1", "f", "x")
+	e.shard.Set(e.ctx, leaseKeyPrefix+"n1", "other", 0) // key internal tidak ikut dilaporkan
+	return e
+}
+
+func TestDryRunReportsWithoutMoving(t *testing.T) {
+	e := reportEnv(t)
+	before := e.srv.Keys()
+	e.srv.ResetCalls()
+
+	rep := dryRun(e.ctx, e.r, e.cfg, 2)
+	if rep.Scanned != 6 || rep.Candidates != 2 || rep.Old != 2 || rep.ParseFail != 2 || len(rep.Errors) != 0 {
+		t.Fatalf("report = %+v", rep)
+	}
+	if g := rep.ByPrefix["user"]; g == nil || g.Keys != 3 || g.Candidates != 1 {
+		t.Errorf("by_prefix[user] = %+v", g)
+	}
+	if g := rep.ByPrefix["feature:COLD"]; g == nil || g.Keys != 1 || g.Candidates != 1 {
+		t.Errorf("by_prefix[feature:COLD] = %+v", g)
+	}
+	if g := rep.ByType["hash"]; g == nil || g.Keys != 1 || g.Bytes != int64(len(`{"f":"x"}`)) {
+		t.Errorf("by_type[hash] = %+v", g)
+	}
+	hist := map[string]int{}
+	for b, g := range rep.AgeHistogram {
+		hist[b] = g.Keys
+	}
+	if want := map[string]int{"<1m": 1, "15m-1h": 1, "1h-6h": 2, "no_ts": 2}; !reflect.DeepEqual(hist, want) {
+		t.Errorf("age_histogram = %v, want %v", hist, want)
+	}
+	if sr := rep.Shards[redistest.NodeID]; sr == nil || sr.Scanned != 6 || sr.Candidates != 2 {
+		t.Errorf("shard report = %+v", sr)
+	}
+
+	// Read-only: tidak ada key yang dihapus/ditulis, tidak ada checkpoint, cold store kosong
+	if after := e.srv.Keys(); !reflect.DeepEqual(after, before) {
+		t.Fatalf("keys after dry-run = %v, want %v", after, before)
+	}
+	for _, cmd := range []string{"del", "set", "hset", "evalsha", "eval"} {
+		if n := e.srv.Calls(cmd); n != 0 {
+			t.Errorf("dry-run sent %d %s", n, cmd)
+		}
+	}
+	if keys, err := e.cold.List(""); err != nil || len(keys) != 0 {
+		t.Fatalf("cold store after dry-run = %v, %v", keys, err)
+	}
+}
+
+func TestReportHandlerOverrides(t *testing.T) {
+	e := reportEnv(t)
+	h := reportHandler(e.r, e.cfg, 2)
+	get := func(target string) dryRunReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var rep dryRunReport
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &rep) != nil {
+			t.Fatalf("GET %s = %d %s", target, w.Code, w.Body)
+		}
+		return rep
+	}
+
+	if rep := get("/report"); rep.AfterSeconds != 3600 || rep.Candidates != 2 {
+		t.Fatalf("default report: after=%d candidates=%d", rep.AfterSeconds, rep.Candidates)
+	}
+	rep := get("/report?after_seconds=600&prefix_depth=1")
+	if rep.AfterSeconds != 600 || rep.Candidates != 3 {
+		t.Fatalf("after_seconds=600: after=%d candidates=%d, want 3", rep.AfterSeconds, rep.Candidates)
+	}
+	if g := rep.ByPrefix["feature"]; g == nil || g.Keys != 1 {
+		t.Fatalf("prefix_depth=1 by_prefix = %v", rep.ByPrefix)
+	}
+}
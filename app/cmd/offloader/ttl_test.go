@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Sisa TTL key di Redis ikut pindah ke cold store sebagai waktu kedaluwarsa absolut.
+func TestMovePreservesRemainingTTL(t *testing.T) {
+	e := newTestEnv(t)
+	if err := e.shard.Set(e.ctx, "ttl", oldValue(time.Hour), 90*time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.shard.Set(e.ctx, "forever", oldValue(time.Hour), 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.shard.HSet(e.ctx, "hash", "f", "x").Err(); err != nil {
+		t.Fatal(err)
+	}
+	e.shard.PExpire(e.ctx, "hash", 30*time.Minute)
+
+	if st := e.move(t, "ttl", "forever", "hash"); st.Moved != 3 {
+		t.Fatalf("moved = %d, want 3 (%+v)", st.Moved, st)
+	}
+	for _, tc := range []struct {
+		key  string
+		want time.Duration // 0 = tanpa expiry
+	}{
+		{"ttl", 90 * time.Minute},
+		{"forever", 0},
+		{"hash", 30 * time.Minute},
+	} {
+		rec, err := e.cold.ReadRecord(tc.key)
+		if err != nil {
+			t.Fatalf("ReadRecord(%s): %v", tc.key, err)
+		}
+		got := rec.TTL(time.Now())
+		if tc.want == 0 {
+			if rec.ExpireAt != 0 {
+				t.Errorf("%s: ExpireAt = %d, want none", tc.key, rec.ExpireAt)
+			}
+			continue
+		}
+		if got > tc.want || got < tc.want-time.Minute {
+			t.Errorf("%s: remaining TTL in cold store = %s, want ~%s", tc.key, got, tc.want)
+		}
+	}
+}
+
+// Key yang expired di antara SCAN dan fetch tidak dipindah.
+func TestFetchSkipsExpiredKey(t *testing.T) {
+	e := newTestEnv(t)
+	if err := e.shard.Set(e.ctx, "short", oldValue(time.Hour), time.Millisecond).Err(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if f := fetchValues(e.ctx, e.shard, e.cfg, []string{"short"})[0]; f.err == nil {
+		t.Fatalf("fetch of expired key = %+v, want error", f)
+	}
+}
@@ -0,0 +1,283 @@
+// Rehydrate: mengembalikan data dari cold store (HDFS/local/S3) ke Redis secara massal,
+// mis. setelah Redis cluster dibangun ulang atau maxmemory dinaikkan.
+// Semua record yang masih berlaku dibaca (segment hasil offloader, event overflow_*.jsonl
+// dari ingestor dan file offloaded/ lama; yang tertutup tombstone atau sudah kedaluwarsa
+// dilewati), difilter menurut prefix key dan rentang waktu data, lalu ditulis ke Redis
+// dengan SET NX dan sisa TTL aslinya (_ts di-set ke waktu rehydrate). Key yang sudah ada
+// di Redis tidak ditimpa.
+// Sekali jalan, lalu keluar.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx"
+	"monolith-kv-sim/internal/throttle"
+)
+
+// errStop menghentikan Scan cold store lebih awal (memori Redis penuh).
+var errStop = errors.New("rehydrate: stopped")
+
+func main() {
+	prefixes := flag.String("prefix", os.Getenv("REHYDRATE_PREFIX"), "only restore keys with one of these comma-separated prefixes (default: all keys)")
+	from := flag.String("from", os.Getenv("REHYDRATE_FROM"), "only restore records with data time >= this (RFC3339 or unix ms)")
+	to := flag.String("to", os.Getenv("REHYDRATE_TO"), "only restore records with data time < this (RFC3339 or unix ms)")
+	maxOps := flag.Int("max-ops", getInt("REHYDRATE_MAX_OPS_PER_SEC", 1000), "max Redis writes per second (0 = unlimited)")
+	batch := flag.Int("batch", getInt("REHYDRATE_BATCH", 200), "keys per write pipeline")
+	maxMem := flag.Float64("max-mem-ratio", getFloat("REHYDRATE_MAX_MEM_RATIO", getFloat("REDIS_MAXMEM_SOFT", 0.80)), "stop when cluster memory ratio reaches this")
+	defTTL := flag.Int("default-ttl", getInt("REHYDRATE_DEFAULT_TTL_SECONDS", 0), "TTL in seconds for records without a recorded expiry (0 = no expiry)")
+	dryRun := flag.Bool("dry-run", false, "only count matching records, do not write to Redis")
+	flag.Parse()
+
+	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+
+	f, err := newFilter(*prefixes, *from, *to)
+	if err != nil {
+		log.Fatalf("rehydrate: %v", err)
+	}
+	if *batch <= 0 {
+		*batch = 200
+	}
+
+	ctx := context.Background()
+	store := coldstore.NewStoreFromEnv()
+	r := redisx.NewCluster()
+	if !*dryRun {
+		if err := r.Ping(ctx).Err(); err != nil {
+			log.Fatalf("rehydrate: redis ping failed: %v", err)
+		}
+	}
+	log.Printf("rehydrate started: prefix=%q from=%q to=%q max_ops=%d batch=%d max_mem_ratio=%.2f default_ttl=%d dry_run=%t COLD_STORE=%s",
+		*prefixes, *from, *to, *maxOps, *batch, *maxMem, *defTTL, *dryRun, store.Name())
+
+	rh := &rehydrator{
+		r:       r,
+		limiter: throttle.New(*maxOps),
+		maxMem:  *maxMem,
+		defTTL:  time.Duration(*defTTL) * time.Second,
+		dryRun:  *dryRun,
+	}
+	start := time.Now()
+	err = rh.run(ctx, store, f, *batch)
+	st := rh.st
+	log.Printf("rehydrate done: scanned=%d matched=%d restored=%d exists=%d expired=%d failed=%d stopped=%t took=%s",
+		st.Scanned, st.Matched, st.Restored, st.Exists, st.Expired, st.Failed, errors.Is(err, errStop), time.Since(start).Round(time.Millisecond))
+	if err != nil && !errors.Is(err, errStop) {
+		log.Fatalf("rehydrate: %v", err)
+	}
+}
+
+// filter memilih record yang di-rehydrate menurut prefix key dan TS record (unix ms).
+type filter struct {
+	prefixes []string
+	from, to int64 // 0 = tanpa batas
+}
+
+func newFilter(prefixes, from, to string) (filter, error) {
+	var f filter
+	for _, p := range strings.Split(prefixes, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			f.prefixes = append(f.prefixes, p)
+		}
+	}
+	var err error
+	if f.from, err = parseTime(from); err != nil {
+		return f, fmt.Errorf("bad --from: %w", err)
+	}
+	if f.to, err = parseTime(to); err != nil {
+		return f, fmt.Errorf("bad --to: %w", err)
+	}
+	return f, nil
+}
+
+func (f filter) match(key string, rec coldstore.Record) bool {
+	if len(f.prefixes) > 0 {
+		ok := false
+		for _, p := range f.prefixes {
+			if strings.HasPrefix(key, p) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.from > 0 && rec.TS < f.from {
+		return false
+	}
+	if f.to > 0 && rec.TS >= f.to {
+		return false
+	}
+	return true
+}
+
+// parseTime menerima RFC3339 atau unix ms; string kosong berarti tanpa batas (0).
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
+
+type item struct {
+	key string
+	rec coldstore.Record
+}
+
+type rehydrateStats struct {
+	Scanned  int
+	Matched  int
+	Restored int
+	Exists   int // key sudah ada di Redis (data lebih baru), tidak ditimpa
+	Expired  int // TTL habis di antara baca dan tulis
+	Failed   int
+}
+
+// rehydrator menulis record cold store ke Redis per batch.
+type rehydrator struct {
+	r       *redis.ClusterClient
+	limiter *throttle.Limiter
+	maxMem  float64
+	defTTL  time.Duration
+	dryRun  bool
+
+	pending []item
+	st      rehydrateStats
+}
+
+// run membaca semua record cold store yang lolos filter f dan menulisnya ke Redis per batch
+// (lihat flush). Mengembalikan errStop jika rehydrate dihentikan karena memori Redis penuh.
+func (rh *rehydrator) run(ctx context.Context, store *coldstore.Store, f filter, batch int) error {
+	err := store.Scan(func(key string, rec coldstore.Record) error {
+		rh.st.Scanned++
+		if !f.match(key, rec) {
+			return nil
+		}
+		rh.st.Matched++
+		rh.pending = append(rh.pending, item{key: key, rec: rec})
+		if len(rh.pending) < batch {
+			return nil
+		}
+		return rh.flush(ctx)
+	})
+	if err == nil {
+		err = rh.flush(ctx)
+	}
+	return err
+}
+
+// flush menulis batch pending: record string dengan satu pipeline SET NX, record bertipe
+// (hash/list/set/zset) satu per satu lewat redisx.RestoreTyped. _ts value di-set ke waktu
+// rehydrate (coldstore.TouchTS) agar offloader tidak langsung memindahkannya lagi ke cold store.
+// Mengembalikan errStop jika
+// rasio memori cluster sudah mencapai maxMem atau Redis menolak write karena OOM.
+func (rh *rehydrator) flush(ctx context.Context) error {
+	batch := rh.pending
+	rh.pending = rh.pending[:0]
+	if len(batch) == 0 || rh.dryRun {
+		return nil
+	}
+	if ratio, err := redisx.ClusterMemRatio(ctx, rh.r); err == nil && ratio >= rh.maxMem {
+		log.Printf("rehydrate: cluster mem_ratio=%.2f >= %.2f, stopping", ratio, rh.maxMem)
+		return errStop
+	}
+
+	now := time.Now()
+	type write struct {
+		item
+		ttl time.Duration
+		cmd *redis.BoolCmd
+	}
+	var writes, typed []write
+	for _, it := range batch {
+		ttl := rh.defTTL
+		if it.rec.ExpireAt > 0 {
+			if ttl = it.rec.TTL(now); ttl < time.Millisecond {
+				rh.st.Expired++
+				continue
+			}
+		}
+		if it.rec.Type != "" {
+			typed = append(typed, write{item: it, ttl: ttl})
+			continue
+		}
+		writes = append(writes, write{item: it, ttl: ttl})
+	}
+
+	oom := false
+	count := func(key string, set bool, err error) {
+		switch {
+		case redisx.IsOOM(err):
+			oom = true
+			rh.st.Failed++
+		case err != nil:
+			rh.st.Failed++
+			log.Printf("rehydrate key=%q failed: %v", key, err)
+		case set:
+			rh.st.Restored++
+		default:
+			rh.st.Exists++
+		}
+	}
+
+	if len(writes) > 0 {
+		_ = rh.limiter.Wait(ctx, len(writes))
+		_, _ = rh.r.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for i, w := range writes {
+				writes[i].cmd = p.SetNX(ctx, w.key, coldstore.TouchTS(w.rec.Type, w.rec.Value, now), w.ttl)
+			}
+			return nil
+		})
+		for _, w := range writes {
+			set, err := w.cmd.Result()
+			count(w.key, set, err)
+		}
+	}
+	for _, w := range typed {
+		_ = rh.limiter.Wait(ctx, 1)
+		set, err := redisx.RestoreTyped(ctx, rh.r, w.key, w.rec.Type, coldstore.TouchTS(w.rec.Type, w.rec.Value, now), w.ttl)
+		count(w.key, set, err)
+	}
+	if oom {
+		log.Printf("rehydrate: Redis rejected writes with OOM, stopping")
+		return errStop
+	}
+	return nil
+}
+
+func getInt(env string, def int) int {
+	if s := os.Getenv(env); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			return v
+		}
+	}
+	return def
+}
+
+func getFloat(env string, def float64) float64 {
+	if s := os.Getenv(env); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	return def
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/coldstore"
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	f, err := newFilter("user:, feature:", "2024-01-01T00:00:00Z", "1704153600000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.from != from || f.to != from+24*3600*1000 || len(f.prefixes) != 2 {
+		t.Fatalf("filter = %+v", f)
+	}
+	for _, tc := range []struct {
+		key  string
+		ts   int64
+		want bool
+	}{
+		{"user:1", from, true},
+		{"feature:x", from + 1000, true},
+		{"user:1", from - 1, false},
+		{"user:1", f.to, false},
+		{"other:1", from, false},
+	} {
+		if got := f.match(tc.key, coldstore.Record{TS: tc.ts}); got != tc.want {
+			t.Errorf("match(%s, %d) = %t, want %t", tc.key, tc.ts, got, tc.want)
+		}
+	}
+	if !(filter{}).match("anything", coldstore.Record{}) {
+		t.Error("empty filter rejected a record")
+	}
+	if _, err := newFilter("", "yesterday", ""); err == nil {
+		t.Error("bad --from accepted")
+	}
+}
+
+// rehydrateEnv menyiapkan cold store local berisi beberapa record dan Redis in-memory.
+type rehydrateEnv struct {
+	ctx   context.Context
+	srv   *redistest.Server
+	r     *redis.ClusterClient
+	store *coldstore.Store
+	old   int64 // TS record (unix ms)
+}
+
+func newRehydrateEnv(t *testing.T) *rehydrateEnv {
+	t.Setenv("COLD_STORE", "local")
+	t.Setenv("COLD_STORE_DIR", t.TempDir())
+	e := &rehydrateEnv{ctx: context.Background(), srv: redistest.NewServer(t)}
+	e.r = e.srv.Cluster(t)
+	e.store = coldstore.NewStoreFromEnv()
+	if err := e.store.Init(); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	e.old = now.Add(-2 * time.Hour).UnixMilli()
+	val := []byte(`{"v":1}`)
+	err := e.store.WriteKeyValues([]coldstore.KeyValue{
+		{Key: "user:ttl", Value: val, TS: e.old, ExpireAt: now.Add(time.Hour).UnixMilli()},
+		{Key: "user:forever", Value: val, TS: e.old},
+		{Key: "user:hash", Value: []byte(`{"f":"x"}`), TS: e.old, Type: "hash"},
+		{Key: "user:exists", Value: val, TS: e.old},
+		{Key: "user:expired", Value: val, TS: e.old, ExpireAt: now.Add(-time.Minute).UnixMilli()},
+		{Key: "user:deleted", Value: val, TS: e.old},
+		{Key: "other:1", Value: val, TS: e.old},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.store.Delete("user:deleted"); err != nil {
+		t.Fatal(err)
+	}
+	e.r.Set(e.ctx, "user:exists", "newer", 0)
+	return e
+}
+
+func (e *rehydrateEnv) rehydrator() *rehydrator {
+	return &rehydrator{r: e.r, maxMem: 0.8}
+}
+
+func TestRehydrateRestoresLiveRecords(t *testing.T) {
+	e := newRehydrateEnv(t)
+	rh := e.rehydrator()
+	f, _ := newFilter("user:", "", "")
+	if err := rh.run(e.ctx, e.store, f, 2); err != nil {
+		t.Fatal(err)
+	}
+	if st := rh.st; st.Matched != 4 || st.Restored != 3 || st.Exists != 1 || st.Failed != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	start := time.Now().Add(-time.Second).UnixMilli()
+	for _, k := range []string{"user:ttl", "user:forever"} {
+		v, ok := e.srv.Get(k)
+		ts, hasTS := coldstore.ValueTS([]byte(v))
+		if !ok || !hasTS || ts < start {
+			t.Fatalf("%s = %q: want value with _ts set to rehydrate time", k, v)
+		}
+	}
+	if ttl := e.srv.TTL("user:ttl"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("user:ttl TTL = %s, want remaining ~1h", ttl)
+	}
+	if ttl := e.srv.TTL("user:forever"); ttl != 0 {
+		t.Fatalf("user:forever TTL = %s, want none", ttl)
+	}
+	h, err := e.r.HGetAll(e.ctx, "user:hash").Result()
+	if err != nil || h["f"] != "x" || h["_ts"] == "" {
+		t.Fatalf("user:hash = %v, %v", h, err)
+	}
+	if v, _ := e.srv.Get("user:exists"); v != "newer" {
+		t.Fatalf("user:exists overwritten with %q", v)
+	}
+	for _, k := range []string{"user:expired", "user:deleted", "other:1"} {
+		if n := e.r.Exists(e.ctx, k).Val(); n != 0 {
+			t.Fatalf("%s restored", k)
+		}
+	}
+}
+
+func TestRehydrateStops(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(srv *redistest.Server)
+	}{
+		{"mem ratio", func(srv *redistest.Server) { srv.SetMemory(900, 1000) }},
+		{"oom", func(srv *redistest.Server) { srv.SetOOM(true) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newRehydrateEnv(t)
+			tc.setup(e.srv)
+			rh := e.rehydrator()
+			if err := rh.run(e.ctx, e.store, filter{}, 100); !errors.Is(err, errStop) {
+				t.Fatalf("run = %v, want errStop", err)
+			}
+			if keys := e.srv.Keys(); len(keys) != 1 {
+				t.Fatalf("keys = %v, want only the pre-existing key", keys)
+			}
+		})
+	}
+}
+
+func TestRehydrateDryRunWritesNothing(t *testing.T) {
+	e := newRehydrateEnv(t)
+	rh := e.rehydrator()
+	rh.dryRun = true
+	if err := rh.run(e.ctx, e.store, filter{}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if rh.st.Matched != 5 || rh.st.Restored != 0 {
+		t.Fatalf("stats = %+v", rh.st)
+	}
+	if keys := e.srv.Keys(); len(keys) != 1 {
+		t.Fatalf("dry-run wrote keys: %v", keys)
+	}
+}
@@ -0,0 +1,272 @@
+package cachex
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache adalah local cache untuk hot keys di memory aplikasi (bukan di Redis).
+// Ini mengurangi latency untuk data yang sangat sering diakses.
+// Cache ini mengimplementasikan cache-aside pattern di level aplikasi.
+// Entry hanya berisi value hasil serialisasi (payload yang sama seperti di Redis);
+// semua pemakai (ingest, GET, MGET, promosi, delete) memakai key Redis yang sama lewat
+// Get/Add/Remove, sehingga skema key internal cache hanya ada di sini.
+//
+// Kapasitas dibatasi jumlah byte (key + value + overhead per entry, LOCAL_CACHE_MAX_BYTES),
+// dengan policy W-TinyLFU (lihat tinylfu.go): entry baru hanya menggeser entry lama jika
+// lebih sering diakses, jadi scan key sekali-lewat dan blob besar yang jarang dibaca tidak
+// mengusir value kecil yang hot.
+//
+// Setiap entry punya waktu kedaluwarsa: tidak lebih lambat dari TTL key di Redis dan
+// tidak lebih dari MaxStale sejak dimasukkan. Entry yang sudah lewat dianggap miss oleh Get;
+// selama window StaleWhileRevalidate setelahnya, GetStale masih mengembalikannya (ditandai stale)
+// agar pemanggil bisa menyajikan value lama sambil mengambil ulang value di background.
+//
+// Fetch yang mengisi cache dari Redis/cold store bisa selesai setelah key ditulis/dihapus, lalu
+// memasukkan value lama. Karena itu setiap key punya generation yang naik pada setiap Add, Remove
+// dan Purge: fetch mengambil Generation(key) sebelum membaca, lalu mengisi cache lewat
+// AddIfGeneration, yang tidak melakukan apa-apa jika generation key sudah berubah.
+type Cache struct {
+	Enabled bool // Flag apakah cache enabled atau tidak
+	// MaxStale adalah umur maksimal entry (LOCAL_CACHE_MAX_STALE_SECONDS); 0 = hanya dibatasi TTL Redis.
+	// Membatasi berapa lama instance ini bisa menyajikan value lama setelah key diubah dari luar
+	// (instance ingestor lain, offloader, redis-cli).
+	MaxStale time.Duration
+	// StaleWhileRevalidate adalah berapa lama entry yang sudah kedaluwarsa masih boleh disajikan
+	// lewat GetStale (LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS); 0 = disabled.
+	// Remove/Purge (invalidasi setelah write) tetap langsung membuang entry.
+	StaleWhileRevalidate time.Duration
+
+	mu                        sync.Mutex
+	store                     *tinyLFU
+	hits, misses, expirations int64
+	staleHits                 int64
+
+	// gens adalah generation per stripe key (hash key % genStripes), epoch naik setiap Purge.
+	// Key yang berbagi stripe hanya membuat sebagian fill ditolak (miss berikutnya membaca ulang).
+	gens  [genStripes]uint64
+	epoch uint64
+}
+
+// genStripes adalah jumlah counter generation; key dipetakan ke counter lewat hash.
+const genStripes = 4096
+
+// entry adalah value di cache beserta waktu kedaluwarsanya (zero = tanpa expiry).
+type entry struct {
+	val      string
+	expireAt time.Time
+}
+
+// Stats adalah ringkasan aktivitas cache sejak start (lihat Cache.Stats).
+type Stats struct {
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRatio    float64 `json:"hit_ratio"`
+	Evictions   int64   `json:"evictions"`   // entry yang dibuang untuk memberi tempat entry lain
+	Rejections  int64   `json:"rejections"`  // entry baru yang tidak diterima admission policy (atau lebih besar dari kapasitas)
+	Expirations int64   `json:"expirations"` // entry yang ditemukan sudah melewati TTL / MaxStale (dan window stale)
+	StaleHits   int64   `json:"stale_hits"`  // hit GetStale dari entry kedaluwarsa (termasuk di Hits)
+	Entries     int     `json:"entries"`
+	Bytes       int64   `json:"bytes"`
+	MaxBytes    int64   `json:"max_bytes"`
+}
+
+// valueKey adalah key entry cache untuk value key Redis.
+func valueKey(key string) string { return "VAL:" + key }
+
+// Get mengembalikan value key dari cache (false jika cache disabled, miss, atau entry sudah kedaluwarsa).
+func (c *Cache) Get(key string) (string, bool) {
+	v, stale, ok := c.lookup(key, false)
+	return v, ok && !stale
+}
+
+// GetStale seperti Get, tetapi entry yang kedaluwarsa kurang dari StaleWhileRevalidate lalu
+// juga dikembalikan dengan stale=true. Pemanggil menyajikannya lalu mengambil ulang value key
+// (hasilnya di-Add) atau membuangnya dengan Remove.
+func (c *Cache) GetStale(key string) (val string, stale, ok bool) {
+	return c.lookup(key, true)
+}
+
+func (c *Cache) lookup(key string, allowStale bool) (string, bool, bool) {
+	if !c.Enabled {
+		return "", false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.store.get(valueKey(key))
+	if !ok {
+		c.misses++
+		return "", false, false
+	}
+	now := time.Now()
+	if n.e.expireAt.IsZero() || now.Before(n.e.expireAt) {
+		c.hits++
+		return n.e.val, false, true
+	}
+	// Kedaluwarsa: masih disimpan selama window stale-while-revalidate, setelah itu dibuang
+	if !now.Before(n.e.expireAt.Add(c.StaleWhileRevalidate)) {
+		c.store.remove(valueKey(key))
+		c.expirations++
+		c.misses++
+		return "", false, false
+	}
+	if !allowStale {
+		c.misses++
+		return "", false, false
+	}
+	c.hits++
+	c.staleHits++
+	return n.e.val, true, true
+}
+
+// Add menyimpan value key yang baru ditulis di cache. ttl adalah sisa TTL key di Redis (<= 0 jika
+// tidak diketahui / tanpa expiry); entry kedaluwarsa pada yang lebih dulu antara ttl dan MaxStale.
+// Entry bisa saja langsung ditolak admission policy. Generation key ikut naik, sehingga fetch yang
+// dimulai sebelumnya tidak menimpa value ini. Tidak melakukan apa-apa jika cache disabled.
+func (c *Cache) Add(key, val string, ttl time.Duration) {
+	if !c.Enabled {
+		return
+	}
+	e := c.newEntry(val, ttl)
+	c.mu.Lock()
+	c.gens[c.stripe(key)]++
+	c.store.set(valueKey(key), e)
+	c.mu.Unlock()
+}
+
+// Generation mengembalikan generation key saat ini. Diambil sebelum fetch membaca key dari
+// Redis/cold store, lalu diteruskan ke AddIfGeneration.
+func (c *Cache) Generation(key string) uint64 {
+	if !c.Enabled {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation(key)
+}
+
+// AddIfGeneration seperti Add, untuk value hasil fetch: entry hanya disimpan jika generation key
+// masih gen, artinya tidak ada Add/Remove/Purge key sejak fetch mulai membaca. Mengembalikan
+// false jika value dibuang (generation berubah atau cache disabled).
+func (c *Cache) AddIfGeneration(key, val string, ttl time.Duration, gen uint64) bool {
+	if !c.Enabled {
+		return false
+	}
+	e := c.newEntry(val, ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation(key) != gen {
+		return false
+	}
+	c.store.set(valueKey(key), e)
+	return true
+}
+
+func (c *Cache) newEntry(val string, ttl time.Duration) entry {
+	if c.MaxStale > 0 && (ttl <= 0 || ttl > c.MaxStale) {
+		ttl = c.MaxStale
+	}
+	e := entry{val: val}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+	return e
+}
+
+func (c *Cache) stripe(key string) uint64 { return c.store.hash(key) % genStripes }
+
+// generation: counter stripe dan epoch sama-sama hanya naik, jadi jumlahnya berubah jika salah satunya berubah.
+func (c *Cache) generation(key string) uint64 { return c.gens[c.stripe(key)] + c.epoch }
+
+// Remove menghapus value key dari cache dan menaikkan generation key. Dipanggil setelah setiap
+// write/delete key agar GET berikutnya tidak menyajikan value lama, dan fetch yang membaca
+// key sebelum write tidak mengisi ulang cache (AddIfGeneration).
+func (c *Cache) Remove(key string) {
+	if !c.Enabled {
+		return
+	}
+	c.mu.Lock()
+	c.gens[c.stripe(key)]++
+	c.store.remove(valueKey(key))
+	c.mu.Unlock()
+}
+
+// Purge mengosongkan cache, mis. setelah koneksi ke InvalidateChannel terputus
+// (invalidasi dari instance lain selama terputus bisa saja terlewat). Generation semua key naik.
+func (c *Cache) Purge() {
+	if !c.Enabled {
+		return
+	}
+	c.mu.Lock()
+	c.epoch++
+	c.store.purge()
+	c.mu.Unlock()
+}
+
+// Stats mengembalikan statistik cache saat ini (nol semua jika cache disabled).
+func (c *Cache) Stats() Stats {
+	if !c.Enabled {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := Stats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.store.evictions,
+		Rejections:  c.store.rejections,
+		Expirations: c.expirations,
+		StaleHits:   c.staleHits,
+		Entries:     len(c.store.items),
+		Bytes:       c.store.bytes(),
+		MaxBytes:    c.store.maxBytes,
+	}
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRatio = float64(st.Hits) / float64(total)
+	}
+	return st
+}
+
+// InvalidateChannel adalah channel Redis pub/sub tempat instance ingestor mengumumkan key
+// yang baru ditulis/dihapus, agar local cache instance lain membuang entry key tersebut.
+const InvalidateChannel = "cachex:invalidate"
+
+// New membuat instance Cache baru.
+// Cache bisa di-enable/disable melalui environment variable LOCAL_CACHE_HOTKEYS.
+// Kapasitas di-set melalui LOCAL_CACHE_MAX_BYTES (default: 64 MiB).
+// Umur maksimal entry di-set melalui LOCAL_CACHE_MAX_STALE_SECONDS (default: 30 detik, 0 = tanpa batas).
+// Window stale-while-revalidate di-set melalui LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS (default: 0 = disabled).
+func New() *Cache {
+	// Check apakah cache enabled (default: enabled jika env var tidak di-set atau != "0")
+	enabled := os.Getenv("LOCAL_CACHE_HOTKEYS") != "0"
+	if !enabled {
+		return &Cache{Enabled: false}
+	}
+
+	maxBytes := int64(64 << 20)
+	if s := os.Getenv("LOCAL_CACHE_MAX_BYTES"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > 0 {
+			maxBytes = v
+		}
+	}
+	maxStale := 30
+	if s := os.Getenv("LOCAL_CACHE_MAX_STALE_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			maxStale = v
+		}
+	}
+	swr := 0
+	if s := os.Getenv("LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			swr = v
+		}
+	}
+	return &Cache{
+		Enabled:              true,
+		MaxStale:             time.Duration(maxStale) * time.Second,
+		StaleWhileRevalidate: time.Duration(swr) * time.Second,
+		store:                newTinyLFU(maxBytes),
+	}
+}
@@ -0,0 +1,156 @@
+package cachex
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCache() *Cache {
+	return &Cache{Enabled: true, store: newTinyLFU(1 << 20)}
+}
+
+// Fetch membaca value lama dari Redis, lalu key ditulis/dihapus (invalidasi), baru kemudian fetch
+// mengisi cache: value lama tidak boleh masuk.
+func TestFetchAfterInvalidateDoesNotCacheOldValue(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		invalidate func(c *Cache)
+		want       string // isi cache setelah fill fetch ("" = miss)
+	}{
+		{"remove", func(c *Cache) { c.Remove("k") }, ""},
+		{"write", func(c *Cache) { c.Add("k", "new", 0) }, "new"},
+		{"purge", func(c *Cache) { c.Purge() }, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestCache()
+			gen := c.Generation("k") // fetch mulai, membaca "old" dari Redis
+			tc.invalidate(c)
+			if c.AddIfGeneration("k", "old", time.Minute, gen) {
+				t.Fatal("fill from fetch started before invalidation was accepted")
+			}
+			got, _ := c.Get("k")
+			if got != tc.want {
+				t.Fatalf("cache = %q, want %q", got, tc.want)
+			}
+
+			// Fetch yang dimulai setelah invalidasi mengisi cache seperti biasa
+			gen = c.Generation("k")
+			if !c.AddIfGeneration("k", "fresh", time.Minute, gen) {
+				t.Fatal("fill from fetch started after invalidation was dropped")
+			}
+			if got, ok := c.Get("k"); !ok || got != "fresh" {
+				t.Fatalf("cache = %q, %t; want fresh", got, ok)
+			}
+		})
+	}
+}
+
+func TestGenerationIsPerKey(t *testing.T) {
+	c := newTestCache()
+	gen := c.Generation("a")
+	// Key lain (stripe berbeda) yang diinvalidasi tidak membuang fill key a
+	other := ""
+	for i := 0; other == ""; i++ {
+		if k := string(rune('b' + i)); c.stripe(k) != c.stripe("a") {
+			other = k
+		}
+	}
+	c.Remove(other)
+	if !c.AddIfGeneration("a", "v", 0, gen) {
+		t.Fatalf("fill for a dropped after Remove(%q)", other)
+	}
+	if got, ok := c.Get("a"); !ok || got != "v" {
+		t.Fatalf("Get(a) = %q, %t", got, ok)
+	}
+}
+
+func TestDisabledCacheIgnoresFill(t *testing.T) {
+	c := &Cache{}
+	if c.AddIfGeneration("k", "v", 0, c.Generation("k")) {
+		t.Fatal("disabled cache accepted a fill")
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("disabled cache returned a value")
+	}
+}
+
+func TestEntryExpiry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		maxStale time.Duration
+		ttl      time.Duration
+		expires  bool
+	}{
+		{"redis ttl", 0, 20 * time.Millisecond, true},
+		{"max stale caps ttl", 20 * time.Millisecond, time.Hour, true},
+		{"max stale without ttl", 20 * time.Millisecond, 0, true},
+		{"no ttl no max stale", 0, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestCache()
+			c.MaxStale = tc.maxStale
+			c.Add("k", "v", tc.ttl)
+			if _, ok := c.Get("k"); !ok {
+				t.Fatal("fresh entry missed")
+			}
+			time.Sleep(40 * time.Millisecond)
+			if _, ok := c.Get("k"); ok == tc.expires {
+				t.Fatalf("Get after 40ms ok=%t, want expired=%t", ok, tc.expires)
+			}
+			if tc.expires && c.Stats().Expirations != 1 {
+				t.Fatalf("stats = %+v, want 1 expiration", c.Stats())
+			}
+		})
+	}
+}
+
+// Selama window StaleWhileRevalidate entry kedaluwarsa masih bisa diambil lewat GetStale
+// (bukan lewat Get); Remove tetap langsung membuangnya.
+func TestStaleWhileRevalidate(t *testing.T) {
+	c := newTestCache()
+	c.StaleWhileRevalidate = time.Hour
+	c.Add("k", "v", 10*time.Millisecond)
+	if v, stale, ok := c.GetStale("k"); !ok || stale || v != "v" {
+		t.Fatalf("GetStale fresh = %q %t %t", v, stale, ok)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("Get returned an expired entry")
+	}
+	if v, stale, ok := c.GetStale("k"); !ok || !stale || v != "v" {
+		t.Fatalf("GetStale expired = %q %t %t, want stale v", v, stale, ok)
+	}
+	if st := c.Stats(); st.StaleHits != 1 {
+		t.Fatalf("stats = %+v, want 1 stale hit", st)
+	}
+	c.Remove("k")
+	if _, _, ok := c.GetStale("k"); ok {
+		t.Fatal("GetStale returned a removed entry")
+	}
+
+	// Tanpa window, entry kedaluwarsa langsung miss
+	c.StaleWhileRevalidate = 0
+	c.Add("k", "v", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, _, ok := c.GetStale("k"); ok {
+		t.Fatal("GetStale returned expired entry with window disabled")
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	c := New()
+	if !c.Enabled || c.MaxStale != 30*time.Second || c.StaleWhileRevalidate != 0 || c.Stats().MaxBytes != 64<<20 {
+		t.Fatalf("defaults = %+v (%+v)", c, c.Stats())
+	}
+	t.Setenv("LOCAL_CACHE_MAX_BYTES", "4096")
+	t.Setenv("LOCAL_CACHE_MAX_STALE_SECONDS", "0")
+	t.Setenv("LOCAL_CACHE_STALE_WHILE_REVALIDATE_SECONDS", "5")
+	c = New()
+	if c.MaxStale != 0 || c.StaleWhileRevalidate != 5*time.Second || c.Stats().MaxBytes != 4096 {
+		t.Fatalf("from env = %+v (%+v)", c, c.Stats())
+	}
+	t.Setenv("LOCAL_CACHE_HOTKEYS", "0")
+	if c = New(); c.Enabled {
+		t.Fatal("LOCAL_CACHE_HOTKEYS=0 did not disable the cache")
+	}
+}
@@ -0,0 +1,319 @@
+package cachex
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// Policy W-TinyLFU (Einziger et al., "TinyLFU: A Highly Efficient Cache Admission Policy"),
+// dibatasi jumlah byte, bukan jumlah entry:
+//
+//   - window: LRU kecil (windowPercent dari kapasitas) tempat semua entry baru masuk,
+//     agar key yang baru mulai sering diakses sempat mengumpulkan frekuensi.
+//   - main: SLRU (probation + protected). Entry yang keluar dari window hanya diterima
+//     jika frekuensinya (count-min sketch) lebih tinggi dari semua korban di ekor probation
+//     yang harus dibuang untuk memberinya tempat. Satu blob besar yang jarang diakses
+//     tidak bisa mengusir ratusan value kecil yang hot, dan key sekali-lewat (scan UUID
+//     generator) tertahan di window.
+//   - hit di probation memindahkan entry ke protected; kelebihan protected turun lagi ke probation.
+const (
+	windowPercent    = 1
+	protectedPercent = 80
+	// entryOverhead adalah perkiraan byte per entry di luar key+value (node, elemen list, map).
+	entryOverhead = 96
+	// avgEntryBytes dipakai untuk memperkirakan jumlah entry (ukuran sketch) dari kapasitas byte.
+	avgEntryBytes = 256
+)
+
+type segment uint8
+
+const (
+	segWindow segment = iota
+	segProbation
+	segProtected
+)
+
+type node struct {
+	key  string
+	e    entry
+	cost int64
+	seg  segment
+}
+
+// tinyLFU adalah penyimpanan cache; tidak thread-safe (dikunci oleh Cache).
+type tinyLFU struct {
+	maxBytes, windowMax, protectedMax int64
+
+	items                   map[string]*list.Element
+	window, probation, prot *list.List
+	windowBytes, probBytes  int64
+	protBytes               int64
+	sketch                  *cmSketch
+	seed                    maphash.Seed
+
+	evictions, rejections int64
+}
+
+func newTinyLFU(maxBytes int64) *tinyLFU {
+	windowMax := maxBytes * windowPercent / 100
+	if windowMax < 1 {
+		windowMax = 1
+	}
+	return &tinyLFU{
+		maxBytes:     maxBytes,
+		windowMax:    windowMax,
+		protectedMax: (maxBytes - windowMax) * protectedPercent / 100,
+		items:        map[string]*list.Element{},
+		window:       list.New(),
+		probation:    list.New(),
+		prot:         list.New(),
+		sketch:       newCMSketch(int(maxBytes / avgEntryBytes)),
+		seed:         maphash.MakeSeed(),
+	}
+}
+
+func (t *tinyLFU) hash(key string) uint64 { return maphash.String(t.seed, key) }
+
+// get mengembalikan node key dan mencatat aksesnya (hit maupun miss ikut dihitung di sketch).
+func (t *tinyLFU) get(key string) (*node, bool) {
+	t.sketch.increment(t.hash(key))
+	el, ok := t.items[key]
+	if !ok {
+		return nil, false
+	}
+	t.touch(el)
+	return el.Value.(*node), true
+}
+
+// touch memperbarui posisi entry setelah diakses (atau setelah value-nya diganti).
+func (t *tinyLFU) touch(el *list.Element) {
+	n := el.Value.(*node)
+	switch n.seg {
+	case segWindow:
+		t.window.MoveToFront(el)
+		return
+	case segProtected:
+		t.prot.MoveToFront(el)
+	case segProbation:
+		// Hit kedua di main: naik ke protected
+		t.probation.Remove(el)
+		t.probBytes -= n.cost
+		n.seg = segProtected
+		t.items[n.key] = t.prot.PushFront(n)
+		t.protBytes += n.cost
+	}
+	// Kelebihan protected (entry baru naik, atau value entry protected membesar) turun ke probation
+	for t.protBytes > t.protectedMax && t.prot.Len() > 1 {
+		back := t.prot.Back()
+		d := back.Value.(*node)
+		t.prot.Remove(back)
+		t.protBytes -= d.cost
+		d.seg = segProbation
+		t.items[d.key] = t.probation.PushFront(d)
+		t.probBytes += d.cost
+	}
+}
+
+// set menyimpan atau memperbarui key. Mengembalikan false jika entry ditolak (lebih besar dari kapasitas).
+func (t *tinyLFU) set(key string, e entry) bool {
+	cost := int64(len(key)+len(e.val)) + entryOverhead
+	if cost > t.maxBytes-t.windowMax {
+		t.remove(key)
+		t.rejections++
+		return false
+	}
+	t.sketch.increment(t.hash(key))
+	if el, ok := t.items[key]; ok {
+		n := el.Value.(*node)
+		t.addBytes(n.seg, cost-n.cost)
+		n.e, n.cost = e, cost
+		t.touch(el)
+	} else {
+		n := &node{key: key, e: e, cost: cost, seg: segWindow}
+		t.items[key] = t.window.PushFront(n)
+		t.windowBytes += cost
+	}
+	t.evict()
+	return true
+}
+
+func (t *tinyLFU) addBytes(seg segment, d int64) {
+	switch seg {
+	case segWindow:
+		t.windowBytes += d
+	case segProbation:
+		t.probBytes += d
+	case segProtected:
+		t.protBytes += d
+	}
+}
+
+// evict memindahkan kelebihan window ke main lewat filter admission, lalu memastikan
+// total byte main tidak melebihi kapasitasnya (mis. setelah value entry di main membesar).
+func (t *tinyLFU) evict() {
+	mainMax := t.maxBytes - t.windowMax
+	for t.windowBytes > t.windowMax {
+		el := t.window.Back()
+		cand := el.Value.(*node)
+		t.window.Remove(el)
+		t.windowBytes -= cand.cost
+		t.admit(cand, mainMax)
+	}
+	for t.probBytes+t.protBytes > mainMax {
+		if !t.dropVictim() {
+			break
+		}
+		t.evictions++
+	}
+}
+
+// admit memasukkan cand ke probation jika frekuensinya lebih tinggi dari setiap korban
+// yang harus dibuang untuk memberinya tempat; jika tidak, cand yang dibuang.
+func (t *tinyLFU) admit(cand *node, mainMax int64) {
+	need := t.probBytes + t.protBytes + cand.cost - mainMax
+	if need > 0 {
+		candFreq := t.sketch.estimate(t.hash(cand.key))
+		var freed int64
+		for _, l := range []*list.List{t.probation, t.prot} {
+			for el := l.Back(); el != nil && freed < need; el = el.Prev() {
+				v := el.Value.(*node)
+				if t.sketch.estimate(t.hash(v.key)) >= candFreq {
+					delete(t.items, cand.key)
+					t.rejections++
+					return
+				}
+				freed += v.cost
+			}
+		}
+		for freed = 0; freed < need; {
+			c := t.victimCost()
+			if c == 0 {
+				break
+			}
+			t.dropVictim()
+			freed += c
+			t.evictions++
+		}
+	}
+	cand.seg = segProbation
+	t.items[cand.key] = t.probation.PushFront(cand)
+	t.probBytes += cand.cost
+}
+
+// victimCost mengembalikan ukuran korban berikutnya (ekor probation, lalu ekor protected), 0 jika main kosong.
+func (t *tinyLFU) victimCost() int64 {
+	if el := t.probation.Back(); el != nil {
+		return el.Value.(*node).cost
+	}
+	if el := t.prot.Back(); el != nil {
+		return el.Value.(*node).cost
+	}
+	return 0
+}
+
+// dropVictim membuang ekor probation (atau ekor protected jika probation kosong).
+func (t *tinyLFU) dropVictim() bool {
+	for _, l := range []*list.List{t.probation, t.prot} {
+		if el := l.Back(); el != nil {
+			t.removeElement(el)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tinyLFU) remove(key string) {
+	if el, ok := t.items[key]; ok {
+		t.removeElement(el)
+	}
+}
+
+func (t *tinyLFU) removeElement(el *list.Element) {
+	n := el.Value.(*node)
+	switch n.seg {
+	case segWindow:
+		t.window.Remove(el)
+	case segProbation:
+		t.probation.Remove(el)
+	case segProtected:
+		t.prot.Remove(el)
+	}
+	t.addBytes(n.seg, -n.cost)
+	delete(t.items, n.key)
+}
+
+// purge mengosongkan semua entry; frekuensi di sketch tetap dipertahankan.
+func (t *tinyLFU) purge() {
+	t.items = map[string]*list.Element{}
+	t.window.Init()
+	t.probation.Init()
+	t.prot.Init()
+	t.windowBytes, t.probBytes, t.protBytes = 0, 0, 0
+}
+
+func (t *tinyLFU) bytes() int64 { return t.windowBytes + t.probBytes + t.protBytes }
+
+// cmSketch adalah count-min sketch 4 baris dengan counter 4-bit (16 counter per uint64).
+// Setiap sampleSize penambahan semua counter dibagi dua (aging), sehingga frekuensi
+// mencerminkan akses terbaru dan key yang dulu hot tidak menempati cache selamanya.
+type cmSketch struct {
+	rows       [4][]uint64
+	mask       uint64 // jumlah counter per baris - 1 (pangkat dua)
+	additions  int
+	sampleSize int
+}
+
+func newCMSketch(entries int) *cmSketch {
+	if entries < 64 {
+		entries = 64
+	}
+	width := 64
+	for width < entries {
+		width <<= 1
+	}
+	s := &cmSketch{mask: uint64(width - 1), sampleSize: 10 * entries}
+	for i := range s.rows {
+		s.rows[i] = make([]uint64, width/16)
+	}
+	return s
+}
+
+// index mengembalikan posisi counter baris i untuk hash h (double hashing per baris).
+func (s *cmSketch) index(h uint64, i int) (word int, shift uint) {
+	h2 := (h >> 32) | 1
+	pos := (h + uint64(i)*h2) & s.mask
+	return int(pos / 16), uint(pos%16) * 4
+}
+
+func (s *cmSketch) increment(h uint64) {
+	for i := range s.rows {
+		w, sh := s.index(h, i)
+		if (s.rows[i][w]>>sh)&0xf < 15 {
+			s.rows[i][w] += 1 << sh
+		}
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *cmSketch) estimate(h uint64) uint64 {
+	est := uint64(15)
+	for i := range s.rows {
+		w, sh := s.index(h, i)
+		if v := (s.rows[i][w] >> sh) & 0xf; v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// reset membagi dua semua counter.
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j, w := range s.rows[i] {
+			s.rows[i][j] = (w >> 1) & 0x7777777777777777
+		}
+	}
+	s.additions /= 2
+}
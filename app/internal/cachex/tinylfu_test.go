@@ -0,0 +1,197 @@
+package cachex
+
+import (
+	"container/list"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func sumCost(l *list.List) int64 {
+	var n int64
+	for el := l.Front(); el != nil; el = el.Next() {
+		n += el.Value.(*node).cost
+	}
+	return n
+}
+
+// checkBytes memastikan penghitung byte per segmen cocok dengan isi list dan kapasitas tidak terlampaui.
+func checkBytes(t *testing.T, s *tinyLFU) {
+	t.Helper()
+	if s.windowBytes != sumCost(s.window) || s.probBytes != sumCost(s.probation) || s.protBytes != sumCost(s.prot) {
+		t.Fatalf("byte counters window=%d probation=%d protected=%d, entries sum to %d/%d/%d",
+			s.windowBytes, s.probBytes, s.protBytes, sumCost(s.window), sumCost(s.probation), sumCost(s.prot))
+	}
+	if s.bytes() > s.maxBytes || s.windowBytes > s.windowMax || (s.protBytes > s.protectedMax && s.prot.Len() > 1) {
+		t.Fatalf("over capacity: total=%d/%d window=%d/%d protected=%d/%d",
+			s.bytes(), s.maxBytes, s.windowBytes, s.windowMax, s.protBytes, s.protectedMax)
+	}
+	if n := s.window.Len() + s.probation.Len() + s.prot.Len(); n != len(s.items) {
+		t.Fatalf("%d entries in lists, %d in map", n, len(s.items))
+	}
+}
+
+// value membuat value berukuran n byte.
+func value(n int) entry { return entry{val: strings.Repeat("x", n)} }
+
+func TestTinyLFUByteBound(t *testing.T) {
+	s := newTinyLFU(64 << 10)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		k := fmt.Sprintf("k%d", rnd.Intn(800))
+		switch rnd.Intn(4) {
+		case 0:
+			s.get(k)
+		case 1:
+			s.remove(k)
+		default:
+			s.set(k, value(rnd.Intn(2000)))
+		}
+		checkBytes(t, s)
+	}
+
+	// Entry yang lebih besar dari kapasitas main ditolak, dan salinan lama key ikut dibuang
+	s.set("big", value(10))
+	before := s.rejections
+	if s.set("big", value(int(s.maxBytes))) {
+		t.Fatal("entry larger than the cache accepted")
+	}
+	if _, ok := s.items["big"]; ok || s.rejections != before+1 {
+		t.Fatalf("oversized set: present=%t rejections=%d", ok, s.rejections-before)
+	}
+	checkBytes(t, s)
+}
+
+// Key sekali-lewat (scan) tidak bisa mengusir key yang sering dibaca; key yang lebih sering
+// diakses dari korbannya tetap diterima.
+func TestTinyLFUAdmission(t *testing.T) {
+	s := newTinyLFU(1 << 20)
+	const hot = 800
+	for i := 0; i < hot; i++ {
+		s.set(fmt.Sprintf("hot%d", i), value(1000))
+	}
+	for r := 0; r < 3; r++ {
+		for i := 0; i < hot; i++ {
+			s.get(fmt.Sprintf("hot%d", i))
+		}
+	}
+	for i := 0; i < 2000; i++ {
+		s.set(fmt.Sprintf("scan%d", i), value(1000))
+	}
+	checkBytes(t, s)
+	// Frekuensi dari count-min sketch bisa terlalu tinggi karena tabrakan hash (seed acak),
+	// jadi satu-dua key scan boleh lolos menggantikan key hot
+	kept := 0
+	for i := 0; i < hot; i++ {
+		if _, ok := s.items[fmt.Sprintf("hot%d", i)]; ok {
+			kept++
+		}
+	}
+	if kept < hot*98/100 {
+		t.Fatalf("%d of %d hot keys left after one-hit scan", kept, hot)
+	}
+	if s.rejections < 1500 {
+		t.Fatalf("rejections = %d, want most scan keys rejected", s.rejections)
+	}
+
+	// Key yang sering diminta (miss ikut dihitung) diterima walaupun main penuh
+	evictions := s.evictions
+	for i := 0; i < 10; i++ {
+		s.get("popular")
+	}
+	s.set("popular", value(1000))
+	for i := 0; i < 20; i++ { // dorong popular keluar dari window
+		s.set(fmt.Sprintf("filler%d", i), value(1000))
+	}
+	if el, ok := s.items["popular"]; !ok || el.Value.(*node).seg == segWindow {
+		t.Fatalf("popular key not admitted to main (present=%t)", ok)
+	}
+	if s.evictions <= evictions {
+		t.Fatal("admitting popular evicted nothing")
+	}
+	checkBytes(t, s)
+}
+
+func TestTinyLFUSegments(t *testing.T) {
+	s := newTinyLFU(100_000) // window ~1000 byte: lima entry 200 byte
+	seg := func(k string) segment {
+		t.Helper()
+		el, ok := s.items[k]
+		if !ok {
+			t.Fatalf("%s not cached", k)
+		}
+		return el.Value.(*node).seg
+	}
+	s.set("a", value(100))
+	if seg("a") != segWindow {
+		t.Fatal("new entry not in window")
+	}
+	for i := 0; i < 5; i++ {
+		s.set(fmt.Sprintf("f%d", i), value(100))
+	}
+	if seg("a") != segProbation {
+		t.Fatalf("entry pushed out of window in segment %d, want probation", seg("a"))
+	}
+	s.get("a")
+	if seg("a") != segProtected {
+		t.Fatalf("probation hit left entry in segment %d, want protected", seg("a"))
+	}
+	checkBytes(t, s)
+
+	// Protected penuh: entry paling lama di protected turun lagi ke probation
+	for i := 0; i < 500; i++ {
+		k := fmt.Sprintf("p%d", i)
+		s.set(k, value(100))
+	}
+	for i := 0; i < 500; i++ {
+		s.get(fmt.Sprintf("p%d", i))
+	}
+	if seg("a") != segProbation {
+		t.Fatalf("oldest protected entry in segment %d after protected overflow, want probation", seg("a"))
+	}
+	checkBytes(t, s)
+}
+
+func TestCMSketch(t *testing.T) {
+	s := newCMSketch(64)
+	for i := 0; i < 10; i++ {
+		s.increment(42)
+	}
+	if got := s.estimate(42); got != 10 {
+		t.Fatalf("estimate = %d, want 10", got)
+	}
+	for i := 0; i < 10; i++ {
+		s.increment(42)
+	}
+	if got := s.estimate(42); got != 15 {
+		t.Fatalf("estimate = %d, want saturated 15", got)
+	}
+	s.reset()
+	if got := s.estimate(42); got != 7 {
+		t.Fatalf("estimate after reset = %d, want 7", got)
+	}
+
+	// Counter dibagi dua otomatis setiap sampleSize penambahan
+	s.additions = s.sampleSize - 1
+	s.increment(42)
+	if got := s.estimate(42); got != 4 || s.additions != s.sampleSize/2 {
+		t.Fatalf("after sample: estimate=%d additions=%d, want 4 and %d", got, s.additions, s.sampleSize/2)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := &Cache{Enabled: true, store: newTinyLFU(64 << 10)}
+	c.Get("k")
+	c.Add("k", "v", 0)
+	c.Get("k")
+	c.Add("huge", strings.Repeat("x", 64<<10), 0)
+	st := c.Stats()
+	want := Stats{Hits: 1, Misses: 1, HitRatio: 0.5, Rejections: 1, Entries: 1, Bytes: int64(len(valueKey("k"))+len("v")) + entryOverhead, MaxBytes: 64 << 10}
+	if st != want {
+		t.Fatalf("Stats = %+v, want %+v", st, want)
+	}
+	if (&Cache{}).Stats() != (Stats{}) {
+		t.Fatal("disabled cache reported stats")
+	}
+}
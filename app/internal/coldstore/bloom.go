@@ -0,0 +1,93 @@
+package coldstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// bloomFilter adalah Bloom filter sederhana untuk key di cold tier.
+// mayContain false berarti key pasti tidak ada (definite miss), sehingga
+// ReadByKey bisa mengembalikan ErrNotFound tanpa request ke backend.
+// Posisi bit memakai double hashing (Kirsch–Mitzenmacher): h1 + i*h2.
+type bloomFilter struct {
+	k    uint32
+	bits []uint64
+}
+
+const bloomVersion = 1
+
+// newBloomFilter membuat filter untuk n key dengan target false positive rate fp.
+func newBloomFilter(n int, fp float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	if fp <= 0 || fp >= 1 {
+		fp = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2))
+	k := uint32(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	} else if k > 30 {
+		k = 30
+	}
+	return &bloomFilter{k: k, bits: make([]uint64, (uint64(m)+63)/64)}
+}
+
+func (b *bloomFilter) add(key string) {
+	h1, h2 := bloomHash(key)
+	m := uint64(len(b.bits)) * 64
+	for i := uint32(0); i < b.k; i++ {
+		pos := (h1 + uint64(i)*h2) % m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHash(key)
+	m := uint64(len(b.bits)) * 64
+	for i := uint32(0); i < b.k; i++ {
+		pos := (h1 + uint64(i)*h2) % m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// marshal: byte versi | uint32 k | uint64 bits... (big-endian).
+func (b *bloomFilter) marshal() []byte {
+	out := make([]byte, 5+8*len(b.bits))
+	out[0] = bloomVersion
+	binary.BigEndian.PutUint32(out[1:5], b.k)
+	for i, w := range b.bits {
+		binary.BigEndian.PutUint64(out[5+8*i:], w)
+	}
+	return out
+}
+
+func unmarshalBloomFilter(data []byte) (*bloomFilter, error) {
+	if len(data) < 13 || data[0] != bloomVersion || (len(data)-5)%8 != 0 {
+		return nil, errors.New("coldstore: bad bloom filter")
+	}
+	b := &bloomFilter{k: binary.BigEndian.Uint32(data[1:5]), bits: make([]uint64, (len(data)-5)/8)}
+	for i := range b.bits {
+		b.bits[i] = binary.BigEndian.Uint64(data[5+8*i:])
+	}
+	return b, nil
+}
+
+// bloomHash menghitung dua hash 64-bit dari key: FNV-1a lalu splitmix64 dari hasilnya.
+func bloomHash(key string) (uint64, uint64) {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	z := h + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return h, z | 1 // h2 ganjil agar langkah tidak nol
+}
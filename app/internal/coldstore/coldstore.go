@@ -0,0 +1,107 @@
+package coldstore
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound dikembalikan ReadByKey jika key tidak ada di cold store,
+	// dan oleh Backend.Get jika object/file tidak ada.
+	ErrNotFound = errors.New("coldstore: not found")
+	// ErrDeleted dikembalikan ReadByKey jika key sudah dihapus (ada tombstone yang lebih baru dari data).
+	ErrDeleted = errors.New("coldstore: key deleted")
+)
+
+// ColdStore adalah tier penyimpanan "dingin" (on-disk KV store) di bawah Redis.
+// Ingestor dan offloader hanya bergantung pada interface ini, sehingga backend
+// bisa dipilih lewat konfigurasi: HDFS (WebHDFS), local filesystem (dev/test),
+// atau object storage S3-compatible (mis. MinIO).
+type ColdStore interface {
+	// Name adalah nama backend ("hdfs", "local", "s3"), dipakai sebagai "source"/"stored" di API.
+	Name() string
+	// Init menyiapkan backend (mis. membuat direktori root / bucket).
+	Init() error
+	// WriteEvents menulis event overflow sebagai satu file JSONL; event dengan field "key" ter-index.
+	WriteEvents(events []any) error
+	// WriteKeyValue menulis value hasil offload untuk satu key.
+	WriteKeyValue(key string, value []byte) error
+	// WriteKeyValues menulis banyak value hasil offload sekaligus sebagai satu segment file.
+	// KeyValue.ExpireAt berisi waktu kedaluwarsa absolut (dari PTTL saat offload).
+	WriteKeyValues(kvs []KeyValue) error
+	// ReadByKey membaca value terbaru untuk key (ErrNotFound / ErrDeleted jika tidak ada).
+	ReadByKey(key string) ([]byte, error)
+	// ReadRecord seperti ReadByKey, plus TS dan waktu kedaluwarsa absolut record (untuk TTL promosi).
+	ReadRecord(key string) (Record, error)
+	// Delete menghapus key dan menulis tombstone agar value lama tidak muncul lagi.
+	Delete(key string) error
+	// Supersede menyembunyikan salinan key dengan TS <= ts (salinan usang hasil offload yang kalah balapan).
+	Supersede(key string, ts int64) error
+	// List mengembalikan key yang tersimpan di cold store dengan prefix tertentu.
+	List(prefix string) ([]string, error)
+	// Invalidate memaksa read berikutnya memuat ulang index dari backend
+	// (dipanggil saat ada notifikasi data baru di InvalidateChannel).
+	Invalidate()
+}
+
+// InvalidateChannel adalah channel Redis pub/sub tempat offloader dan compactor
+// mengumumkan versi manifest baru, dan offloader/ingestor mengumumkan tombstone baru
+// (Supersede/Delete); ingestor subscribe lalu memanggil Invalidate.
+const InvalidateChannel = "coldstore:invalidate"
+
+// Backend adalah penyimpanan blob sederhana yang dipakai Store.
+// Path selalu relatif terhadap root backend dan memakai "/" sebagai separator.
+type Backend interface {
+	// Name adalah nama backend ("hdfs", "local", "s3").
+	Name() string
+	// Init menyiapkan root (direktori / bucket).
+	Init() error
+	// Put menulis (atau menimpa) seluruh isi path.
+	Put(path string, data []byte) error
+	// PutIfAbsent menulis path hanya jika belum ada (atomic); ErrExists jika sudah ada.
+	PutIfAbsent(path string, data []byte) error
+	// Get membaca n byte mulai offset off (n <= 0: sampai akhir).
+	// Path yang tidak ada menghasilkan error yang cocok dengan errors.Is(err, ErrNotFound).
+	Get(path string, off, n int64) ([]byte, error)
+	// Delete menghapus path; path yang tidak ada bukan error.
+	Delete(path string) error
+	// List mengembalikan nama file langsung di bawah dir (tanpa prefix dir).
+	List(dir string) ([]string, error)
+}
+
+// New membuat ColdStore sesuai environment variable COLD_STORE:
+// "hdfs" (default), "local", atau "s3". Konfigurasi tiap backend dibaca oleh
+// constructor backend masing-masing (NewHDFSBackend, NewLocalBackend, NewS3Backend).
+// Interval refresh index overflow diatur lewat HDFS_INDEX_REFRESH_SECONDS (default 30),
+// false positive rate Bloom filter segment lewat COLD_BLOOM_FP_RATE (default 0.01).
+func New() ColdStore {
+	return NewStoreFromEnv()
+}
+
+// NewStoreFromEnv sama seperti New, tapi mengembalikan *Store agar operasi
+// pemeliharaan (mis. Compact) bisa dipakai oleh cmd/compactor.
+func NewStoreFromEnv() *Store {
+	var b Backend
+	switch strings.ToLower(os.Getenv("COLD_STORE")) {
+	case "local":
+		b = NewLocalBackend()
+	case "s3":
+		b = NewS3Backend()
+	default:
+		b = NewHDFSBackend()
+	}
+	refresh := 30
+	if s := os.Getenv("HDFS_INDEX_REFRESH_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			refresh = v
+		}
+	}
+	s := NewStore(b, time.Duration(refresh)*time.Second)
+	if v, err := strconv.ParseFloat(os.Getenv("COLD_BLOOM_FP_RATE"), 64); err == nil && v > 0 && v < 1 {
+		s.BloomFPRate = v
+	}
+	return s
+}
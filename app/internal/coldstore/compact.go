@@ -0,0 +1,854 @@
+package coldstore
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompactOptions mengatur satu putaran compaction.
+type CompactOptions struct {
+	// SegmentMaxRecords membatasi jumlah record per segment hasil compaction.
+	// Segment yang sudah sebesar ini dan tidak berisi data usang tidak disentuh lagi.
+	SegmentMaxRecords int
+	// MaxInputSegments dan MaxInputBytes membatasi segment yang digabung dalam satu putaran
+	// (hanya input putaran itu yang dimuat ke memori). Segment pertama yang terpilih selalu ikut
+	// walaupun lebih besar dari MaxInputBytes.
+	MaxInputSegments int
+	MaxInputBytes    int64
+	// MinInputSegments adalah jumlah minimal segment kecil dengan ukuran setara (satu tier)
+	// sebelum digabung; segment dengan data usang tidak perlu menunggu ini.
+	MinInputSegments int
+	// MaxInputFiles membatasi jumlah file overflow (per index file) + file offloaded/ lama per putaran.
+	MaxInputFiles int
+	// GarbageRatio: segment yang porsi record usangnya (tertutup tombstone, kedaluwarsa,
+	// duplikat) minimal sebesar ini ditulis ulang walaupun ukurannya sudah penuh.
+	GarbageRatio float64
+	// TombstoneGrace: tombstone baru dihapus jika lebih tua dari ini, supaya
+	// data lama yang sedang ditulis bersamaan (offloader/ingestor) tetap tertutup tombstone.
+	TombstoneGrace time.Duration
+	// OrphanGrace: segment yang tidak tercatat di manifest (commit gagal) dihapus jika lebih tua dari ini.
+	OrphanGrace time.Duration
+	// KeepManifests adalah jumlah versi manifest terbaru yang disimpan; versi lebih lama dihapus.
+	KeepManifests int
+}
+
+func (o *CompactOptions) setDefaults() {
+	if o.SegmentMaxRecords <= 0 {
+		o.SegmentMaxRecords = 10000
+	}
+	if o.MaxInputSegments <= 0 {
+		o.MaxInputSegments = 8
+	}
+	if o.MaxInputBytes <= 0 {
+		o.MaxInputBytes = 256 << 20
+	}
+	if o.MinInputSegments < 2 {
+		o.MinInputSegments = 4
+	}
+	if o.MaxInputFiles <= 0 {
+		o.MaxInputFiles = 1000
+	}
+	if o.GarbageRatio <= 0 {
+		o.GarbageRatio = 0.3
+	}
+}
+
+// CompactStats adalah ringkasan hasil satu putaran compaction.
+type CompactStats struct {
+	InputSegments      int   `json:"input_segments"`
+	InputOverflowFiles int   `json:"input_overflow_files"`
+	InputLegacyFiles   int   `json:"input_legacy_files"`
+	SegmentsKept       int   `json:"segments_kept"` // segment live yang tidak ikut digabung
+	RecordsIn          int   `json:"records_in"`
+	RecordsOut         int   `json:"records_out"`
+	Superseded         int   `json:"superseded"`
+	Expired            int   `json:"expired"`
+	Deleted            int   `json:"deleted"`
+	OutputSegments     int   `json:"output_segments"`
+	TombstonesRemoved  int   `json:"tombstones_removed"`
+	ManifestsRemoved   int   `json:"manifests_removed"`
+	OrphansRemoved     int   `json:"orphans_removed"`
+	ManifestVersion    int64 `json:"manifest_version"`
+	Skipped            bool  `json:"skipped"`
+}
+
+// errCompactConflict: segment input sudah tidak ada di manifest terbaru (compactor lain lebih dulu).
+var errCompactConflict = errors.New("coldstore: compaction conflict, input segment no longer live")
+
+// Compact menggabungkan sebagian cold tier menjadi segment baru yang hanya berisi record
+// terbaru per key. Input satu putaran dibatasi (lihat CompactOptions dan planCompaction):
+// segment dengan banyak data usang, segment kecil dengan ukuran setara, dan file overflow /
+// offloaded/ lama yang belum digabung; segment lain dibiarkan. Input digabung dengan
+// k-way merge per key, jadi yang ada di memori hanya input putaran itu dan satu segment output.
+// Record yang kedaluwarsa (ExpireAt, atau waktu tulis + ttl_sec untuk event overflow)
+// dan record yang tertutup tombstone dibuang.
+//
+// Pergantian dilakukan dengan satu commit manifest: segment input diganti segment
+// output secara atomic, segment yang ditambahkan offloader selama compaction tetap
+// dipertahankan. File input baru dihapus setelah commit sukses; pembaca yang masih
+// memegang manifest lama akan mendapat ErrNotFound lalu memuat ulang index (lihat ReadByKey).
+func (s *Store) Compact(opts CompactOptions) (CompactStats, error) {
+	opts.setDefaults()
+	var st CompactStats
+	now := time.Now().UnixMilli()
+
+	m, err := s.LoadManifest()
+	if err != nil {
+		return st, err
+	}
+	tombs, err := s.loadTombstones()
+	if err != nil {
+		return st, err
+	}
+	p, err := s.planCompaction(m, tombs, opts, now)
+	if err != nil {
+		return st, err
+	}
+	st.SegmentsKept = len(m.Segments) - len(p.segs)
+
+	if p.empty() {
+		// Tidak ada yang perlu digabung; cukup bersihkan tombstone dan sisa file
+		st.Skipped = true
+		st.ManifestVersion = m.Version
+		s.removeTombstones(p, tombs, now-opts.TombstoneGrace.Milliseconds(), &st)
+		s.compactCleanup(opts, m, &st)
+		return st, nil
+	}
+
+	outputs, consumedOverflow, err := s.mergeInputs(p, tombs, opts, now, &st)
+	abort := func() {
+		for _, si := range outputs {
+			_ = s.b.Delete(segmentDir + "/" + si.Name)
+		}
+	}
+	if err != nil {
+		abort()
+		return st, err
+	}
+	st.OutputSegments = len(outputs)
+
+	// Swap manifest: input diganti output, segment lain (tidak terpilih / tulisan baru offloader) dipertahankan
+	newM, err := s.CommitManifest(func(cur *Manifest) error {
+		found := 0
+		kept := make([]SegmentInfo, 0, len(cur.Segments))
+		for _, si := range cur.Segments {
+			if p.inputs[si.Name] {
+				found++
+				continue
+			}
+			kept = append(kept, si)
+		}
+		if found != len(p.inputs) {
+			return errCompactConflict
+		}
+		cur.Segments = append(append([]SegmentInfo(nil), outputs...), kept...)
+		return nil
+	})
+	if err != nil {
+		abort()
+		return st, err
+	}
+	st.ManifestVersion = newM.Version
+	s.applyManifest(newM)
+
+	// Hapus input yang sudah tergabung. Index file dihapus sebelum file overflow-nya
+	// agar pembaca tidak pernah melihat index yang menunjuk ke file yang hilang lebih lama dari perlu.
+	for name := range p.inputs {
+		_ = s.b.Delete(segmentDir + "/" + name)
+	}
+	for _, name := range p.idxFiles {
+		_ = s.b.Delete(indexDir + "/" + name)
+	}
+	for _, name := range consumedOverflow {
+		_ = s.b.Delete(name)
+	}
+	for _, name := range p.legacy {
+		_ = s.b.Delete(offloadDir + "/" + name)
+	}
+	s.removeTombstones(p, tombs, now-opts.TombstoneGrace.Milliseconds(), &st)
+	s.compactCleanup(opts, newM, &st)
+	return st, nil
+}
+
+// tombstone adalah satu file tombstones/ beserta waktu hapusnya (unix ms).
+type tombstone struct {
+	name      string
+	deletedAt int64
+}
+
+// loadTombstones membaca semua tombstone, per key.
+func (s *Store) loadTombstones() (map[string]tombstone, error) {
+	names, err := s.listDir(tombstoneDir)
+	if err != nil {
+		return nil, err
+	}
+	tombs := make(map[string]tombstone, len(names))
+	for _, name := range names {
+		safe := strings.TrimSuffix(name, ".json")
+		key, ok := safeFileNameToKey(safe)
+		if !ok {
+			continue
+		}
+		if deletedAt, ok := s.readTombstone(safe); ok {
+			tombs[key] = tombstone{name: name, deletedAt: deletedAt}
+		}
+	}
+	return tombs, nil
+}
+
+// removeTombstones menghapus tombstone yang lebih tua dari cutoff dan sudah tidak menutupi
+// apa pun: record lama di input sudah dibuang, dan tidak ada sumber di luar input yang
+// mungkin masih menyimpan salinan key dengan TS <= deleted_at.
+func (s *Store) removeTombstones(p *compactPlan, tombs map[string]tombstone, cutoff int64, st *CompactStats) {
+	for key, t := range tombs {
+		if t.deletedAt >= cutoff || p.mayHaveOlder(key, t.deletedAt) {
+			continue
+		}
+		if s.b.Delete(tombstoneDir+"/"+t.name) == nil {
+			st.TombstonesRemoved++
+		}
+	}
+}
+
+// compactPlan adalah input satu putaran compaction, beserta sumber di luar input yang
+// dipakai untuk memastikan record/tombstone yang dibuang tidak membuat salinan lama key
+// di tempat lain muncul kembali.
+type compactPlan struct {
+	segs     []SegmentInfo   // segment input
+	inputs   map[string]bool // nama segment input
+	idxFiles []string        // index file overflow input
+	legacy   []string        // file offloaded/ input
+
+	others          []*liveSegment   // segment live yang tidak ikut
+	pendingOverflow map[string]int64 // key -> TS terkecil di file overflow yang tidak ikut
+	pendingLegacy   map[string]bool  // key di file offloaded/ yang tidak ikut
+}
+
+func (p *compactPlan) empty() bool { return len(p.segs)+len(p.idxFiles)+len(p.legacy) == 0 }
+
+// mayHaveOlder bernilai true jika sumber di luar input mungkin menyimpan salinan key dengan TS <= ts.
+// Segment dicek lewat MinTS dan bloom; TS file offloaded/ tidak diketahui tanpa membacanya.
+func (p *compactPlan) mayHaveOlder(key string, ts int64) bool {
+	if p.pendingLegacy[key] {
+		return true
+	}
+	if t, ok := p.pendingOverflow[key]; ok && t <= ts {
+		return true
+	}
+	for _, ls := range p.others {
+		if ls.info.MinTS <= ts && ls.mayContain(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// planCompaction memilih input satu putaran tanpa membaca isi segment:
+//  1. segment yang porsi data usangnya >= GarbageRatio (perkiraan dari bloom vs tombstone dan
+//     rentang expiry di manifest, lalu dipastikan lewat footer index-nya);
+//  2. segment kecil (< SegmentMaxRecords) dari tier ukuran terkecil yang punya minimal
+//     MinInputSegments segment, terkecil lebih dulu (size-tiered: setiap record hanya
+//     ditulis ulang sekali per tier, bukan setiap putaran);
+//  3. file overflow ter-index dan file offloaded/ lama, terlama lebih dulu, sampai MaxInputFiles.
+//
+// Jumlah dan total ukuran segment dibatasi MaxInputSegments / MaxInputBytes.
+func (s *Store) planCompaction(m Manifest, tombs map[string]tombstone, opts CompactOptions, now int64) (*compactPlan, error) {
+	p := &compactPlan{inputs: map[string]bool{}, pendingOverflow: map[string]int64{}, pendingLegacy: map[string]bool{}}
+
+	// Bloom semua segment live (satu range read kecil per segment, di-cache antar putaran).
+	// Segment yang bloom-nya gagal dimuat dianggap mungkin berisi key apa pun.
+	s.applyManifest(m)
+	s.segs.mu.RLock()
+	cached := s.segs.segs
+	s.segs.mu.RUnlock()
+	segs := make([]*liveSegment, 0, len(m.Segments))
+	for _, si := range m.Segments {
+		ls := cached[si.Name]
+		if ls == nil {
+			ls = &liveSegment{info: si}
+		}
+		segs = append(segs, ls)
+	}
+
+	var inputBytes int64
+	take := func(ls *liveSegment) bool {
+		if len(p.segs) >= opts.MaxInputSegments || (len(p.segs) > 0 && inputBytes+ls.info.Size > opts.MaxInputBytes) {
+			return false
+		}
+		p.segs = append(p.segs, ls.info)
+		p.inputs[ls.info.Name] = true
+		inputBytes += ls.info.Size
+		return true
+	}
+
+	// 1. Segment dengan banyak data usang, yang paling usang lebih dulu
+	type scored struct {
+		ls      *liveSegment
+		garbage float64
+	}
+	var dirty []scored
+	for _, ls := range segs {
+		if g := s.segmentGarbage(ls, tombs, opts.GarbageRatio, now); g >= opts.GarbageRatio {
+			dirty = append(dirty, scored{ls, g})
+		}
+	}
+	sort.Slice(dirty, func(i, j int) bool { return dirty[i].garbage > dirty[j].garbage })
+	for _, d := range dirty {
+		take(d.ls)
+	}
+
+	// 2. Segment kecil per tier ukuran (jumlah record, kelipatan 4)
+	tiers := map[int][]*liveSegment{}
+	for _, ls := range segs {
+		if !p.inputs[ls.info.Name] && ls.info.Count < opts.SegmentMaxRecords {
+			t := bits.Len(uint(ls.info.Count)) / 2
+			tiers[t] = append(tiers[t], ls)
+		}
+	}
+	tierIDs := make([]int, 0, len(tiers))
+	for t, group := range tiers {
+		if len(group) >= opts.MinInputSegments {
+			tierIDs = append(tierIDs, t)
+		}
+	}
+	sort.Ints(tierIDs)
+	if len(tierIDs) > 0 {
+		group := tiers[tierIDs[0]]
+		sort.Slice(group, func(i, j int) bool { return group[i].info.Size < group[j].info.Size })
+		before := len(p.segs)
+		for _, ls := range group {
+			take(ls)
+		}
+		if len(p.segs)-before == 1 {
+			// Satu segment kecil sendirian tidak perlu ditulis ulang
+			last := p.segs[len(p.segs)-1]
+			delete(p.inputs, last.Name)
+			p.segs = p.segs[:len(p.segs)-1]
+		}
+	}
+	for _, ls := range segs {
+		if !p.inputs[ls.info.Name] {
+			p.others = append(p.others, ls)
+		}
+	}
+
+	// 3. File overflow (nama berisi waktu tulis, jadi urutan nama = terlama lebih dulu) dan offloaded/ lama.
+	// Index file yang tidak ikut tetap dibaca (metadata saja) untuk mayHaveOlder.
+	budget := opts.MaxInputFiles
+	idxNames, err := s.listDir(indexDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(idxNames)
+	for _, name := range idxNames {
+		if !strings.HasSuffix(name, ".idx.jsonl") {
+			continue
+		}
+		if budget > 0 {
+			p.idxFiles = append(p.idxFiles, name)
+			budget--
+			continue
+		}
+		entries, err := s.loadIndexFile(name)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if ts, ok := p.pendingOverflow[e.Key]; !ok || e.TS < ts {
+				p.pendingOverflow[e.Key] = e.TS
+			}
+		}
+	}
+	legacy, err := s.listDir(offloadDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range legacy {
+		key, ok := safeFileNameToKey(strings.TrimSuffix(name, ".json"))
+		if !ok {
+			continue
+		}
+		if budget > 0 {
+			p.legacy = append(p.legacy, name)
+			budget--
+			continue
+		}
+		p.pendingLegacy[key] = true
+	}
+	return p, nil
+}
+
+// segmentGarbage mengembalikan porsi record segment yang akan dibuang compaction (0..1).
+// Perkiraan murah dulu (rentang expiry di manifest, bloom segment vs daftar tombstone);
+// footer index baru dimuat jika perkiraan itu mencapai ratio, lalu dihitung pasti per record
+// sehingga false positive bloom tidak membuat segment yang sama ditulis ulang setiap putaran.
+func (s *Store) segmentGarbage(ls *liveSegment, tombs map[string]tombstone, ratio float64, now int64) float64 {
+	si := ls.info
+	if si.Count == 0 || (si.ExpireMax > 0 && si.ExpireMax <= now) {
+		return 1
+	}
+	maybe := 0
+	if si.ExpireMin > 0 && si.ExpireMin <= now {
+		maybe = si.Count
+	} else {
+		for key, t := range tombs {
+			if t.deletedAt >= si.MinTS && ls.mayContain(key) {
+				maybe++
+			}
+		}
+	}
+	if float64(maybe) < ratio*float64(si.Count) {
+		return 0
+	}
+	footer, err := s.segmentFooter(ls)
+	if err != nil {
+		return 0
+	}
+	n := si.Count - len(footer) // key duplikat di segment yang sama
+	for key, e := range footer {
+		if t, ok := tombs[key]; (ok && t.deletedAt >= e.TS) || (e.ExpireAt > 0 && e.ExpireAt <= now) {
+			n++
+		}
+	}
+	return float64(n) / float64(si.Count)
+}
+
+// recordRun adalah deretan record dari satu sumber input, terurut menurut key (untuk k-way merge).
+// rank memutuskan TS yang sama persis, dengan urutan yang sama seperti ReadByKey:
+// offloaded/ lama, lalu segment, lalu overflow.
+type recordRun struct {
+	kvs  []KeyValue
+	pos  int
+	rank int
+}
+
+type runHeap []*recordRun
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].kvs[h[i].pos].Key < h[j].kvs[h[j].pos].Key }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*recordRun)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeInputs membaca input plan p sebagai run terurut per key, menggabungkannya dengan
+// k-way merge, lalu menulis record terbaru tiap key ke segment output setiap SegmentMaxRecords.
+// Record yang kedaluwarsa dibuang; jika sumber di luar input mungkin masih menyimpan salinan
+// lebih lama, tombstone "expired" ditulis lebih dulu agar salinan itu tidak muncul kembali.
+// Mengembalikan segment output (juga saat error, untuk dihapus pemanggil) dan file overflow yang dibaca.
+func (s *Store) mergeInputs(p *compactPlan, tombs map[string]tombstone, opts CompactOptions, now int64, st *CompactStats) ([]SegmentInfo, []string, error) {
+	var (
+		runs             []*recordRun
+		consumedOverflow []string
+		legacyKVs        []KeyValue
+	)
+	addRun := func(kvs []KeyValue, rank int) {
+		if len(kvs) == 0 {
+			return
+		}
+		sort.SliceStable(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+		runs = append(runs, &recordRun{kvs: kvs, rank: rank})
+	}
+	for _, si := range p.segs {
+		kvs, err := s.readSegmentRecords(si)
+		if err != nil {
+			return nil, nil, err
+		}
+		addRun(kvs, 1)
+	}
+	for _, name := range p.idxFiles {
+		kvs, files, err := s.readOverflowRecords(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		addRun(kvs, 0)
+		consumedOverflow = append(consumedOverflow, files...)
+	}
+	for _, name := range p.legacy {
+		key, _ := safeFileNameToKey(strings.TrimSuffix(name, ".json"))
+		val, err := s.b.Get(offloadDir+"/"+name, 0, 0)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, nil, err
+		}
+		legacyKVs = append(legacyKVs, KeyValue{Key: key, Value: val, TS: valueTSMillis(val)})
+	}
+	addRun(legacyKVs, 2)
+	st.InputSegments, st.InputOverflowFiles, st.InputLegacyFiles = len(p.segs), len(consumedOverflow), len(p.legacy)
+
+	var (
+		outputs []SegmentInfo
+		batch   []KeyValue
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		si, _, err := s.putSegment(batch, time.Now().UnixMilli())
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, si)
+		st.RecordsOut += len(batch)
+		batch = make([]KeyValue, 0, opts.SegmentMaxRecords)
+		return nil
+	}
+	h := runHeap(runs)
+	heap.Init(&h)
+	for h.Len() > 0 {
+		key := h[0].kvs[h[0].pos].Key
+		var (
+			best     KeyValue
+			bestRank = -1
+		)
+		for h.Len() > 0 && h[0].kvs[h[0].pos].Key == key {
+			r := h[0]
+			kv := r.kvs[r.pos]
+			st.RecordsIn++
+			if bestRank >= 0 {
+				st.Superseded++
+			}
+			if bestRank < 0 || kv.TS > best.TS || (kv.TS == best.TS && r.rank > bestRank) {
+				best, bestRank = kv, r.rank
+			}
+			if r.pos++; r.pos == len(r.kvs) {
+				heap.Pop(&h)
+			} else {
+				heap.Fix(&h, 0)
+			}
+		}
+		if t, ok := tombs[key]; ok && t.deletedAt >= best.TS {
+			st.Deleted++
+			continue
+		}
+		if best.ExpireAt > 0 && best.ExpireAt <= now {
+			if p.mayHaveOlder(key, best.TS) {
+				if err := s.writeTombstone(key, best.TS, "expired"); err != nil {
+					return outputs, nil, err
+				}
+			}
+			st.Expired++
+			continue
+		}
+		batch = append(batch, best)
+		if len(batch) >= opts.SegmentMaxRecords {
+			if err := flush(); err != nil {
+				return outputs, nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return outputs, nil, err
+	}
+	return outputs, consumedOverflow, nil
+}
+
+// compactCleanup menghapus versi manifest lama dan segment orphan (tidak ada di manifest m).
+func (s *Store) compactCleanup(opts CompactOptions, m Manifest, st *CompactStats) {
+	if opts.KeepManifests < 1 {
+		opts.KeepManifests = 1
+	}
+	vs, err := s.manifestVersions()
+	if err == nil {
+		sort.Slice(vs, func(i, j int) bool { return vs[i] > vs[j] })
+		for i, v := range vs {
+			if i >= opts.KeepManifests && v < m.Version {
+				if s.b.Delete(segmentDir+"/"+manifestName(v)) == nil {
+					st.ManifestsRemoved++
+				}
+			}
+		}
+	}
+
+	names, err := s.listDir(segmentDir)
+	if err != nil {
+		return
+	}
+	liveSegs := make(map[string]bool, len(m.Segments))
+	for _, si := range m.Segments {
+		liveSegs[si.Name] = true
+	}
+	cutoff := time.Now().Add(-opts.OrphanGrace).UnixMilli()
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".seg") || liveSegs[name] {
+			continue
+		}
+		// Segment yang baru saja ditulis mungkin belum sempat di-commit ke manifest
+		if created, ok := segmentCreated(name); !ok || created > cutoff {
+			continue
+		}
+		if s.b.Delete(segmentDir+"/"+name) == nil {
+			st.OrphansRemoved++
+		}
+	}
+}
+
+// readSegmentRecords membaca seluruh record satu segment.
+func (s *Store) readSegmentRecords(si SegmentInfo) ([]KeyValue, error) {
+	data, err := s.b.Get(segmentDir+"/"+si.Name, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if si.IndexOff < 0 || si.IndexOff+si.IndexLen > int64(len(data)) {
+		return nil, fmt.Errorf("coldstore: segment %s shorter than manifest says", si.Name)
+	}
+	entries, err := decodeSegmentFooter(data[si.IndexOff : si.IndexOff+si.IndexLen])
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]KeyValue, 0, len(entries))
+	for _, e := range entries {
+		if e.Off < 0 || e.Off+e.Len > si.IndexOff {
+			return nil, fmt.Errorf("coldstore: bad entry for key %q in segment %s", e.Key, si.Name)
+		}
+		kvs = append(kvs, KeyValue{Key: e.Key, Value: data[e.Off : e.Off+e.Len], TS: e.TS, ExpireAt: e.ExpireAt, Type: e.Type})
+	}
+	return kvs, nil
+}
+
+// readOverflowRecords membaca semua event yang ter-index di satu index file.
+// Expiry event dihitung dari waktu tulis + ttl_sec. Mengembalikan juga nama file
+// overflow yang dirujuk (untuk dihapus setelah compaction); file yang sudah tidak ada dilewati.
+func (s *Store) readOverflowRecords(idxName string) ([]KeyValue, []string, error) {
+	entries, err := s.loadIndexFile(idxName)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	byFile := map[string][]indexEntry{}
+	for _, e := range entries {
+		f := path.Base(e.File)
+		byFile[f] = append(byFile[f], e)
+	}
+	var (
+		kvs   []KeyValue
+		files []string
+	)
+	for f, es := range byFile {
+		data, err := s.b.Get(f, 0, 0)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, nil, err
+		}
+		files = append(files, f)
+		for _, e := range es {
+			if e.Off < 0 || e.Off+e.Len > int64(len(data)) {
+				continue
+			}
+			var ev overflowEvent
+			if json.Unmarshal(data[e.Off:e.Off+e.Len], &ev) != nil || ev.Key != e.Key || len(ev.Value) == 0 {
+				continue
+			}
+			kvs = append(kvs, KeyValue{Key: e.Key, Value: ev.Value, TS: e.TS, ExpireAt: ev.expireAt(e.TS)})
+		}
+	}
+	return kvs, files, nil
+}
+
+// listDir seperti Backend.List, tapi direktori yang belum ada dianggap kosong.
+func (s *Store) listDir(dir string) ([]string, error) {
+	names, err := s.b.List(dir)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	return names, nil
+}
+
+// segmentCreated mengambil waktu pembuatan (unix ms) dari nama seg_<ms>_<seq>.seg.
+func segmentCreated(name string) (int64, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".seg"), "_")
+	if len(parts) != 3 || parts[0] != "seg" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(parts[1], 10, 64)
+	return ms, err == nil
+}
+
+// Scan memanggil fn untuk record terbaru setiap key yang masih berlaku di cold store
+// (segment live, overflow ter-index dan offloaded/ lama; tidak tertutup tombstone dan
+// belum kedaluwarsa). Isi cold tier tidak dimuat sekaligus: pemenang tiap key ditentukan
+// lebih dulu dari metadata (footer segment dan index overflow), lalu file dibaca satu per
+// satu dan fn dipanggil untuk record pemenang di file itu. Urutan pemanggilan mengikuti
+// urutan file, bukan urutan key. Error dari fn menghentikan Scan dan dikembalikan apa adanya.
+func (s *Store) Scan(fn func(key string, rec Record) error) error {
+	m, err := s.LoadManifest()
+	if err != nil {
+		return err
+	}
+	tombs, err := s.loadTombstones()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+
+	// src: indeks segment di m.Segments, scanOverflow, atau scanDone (sudah diputuskan/dipanggil)
+	const (
+		scanOverflow = -1
+		scanDone     = -2
+	)
+	type winner struct {
+		ts  int64
+		src int
+	}
+	win := map[string]winner{}
+	emit := func(key string, rec Record) error {
+		win[key] = winner{ts: rec.TS, src: scanDone}
+		if t, ok := tombs[key]; ok && t.deletedAt >= rec.TS {
+			return nil
+		}
+		if rec.ExpireAt > 0 && rec.ExpireAt <= now {
+			return nil
+		}
+		return fn(key, rec)
+	}
+
+	// 1. Metadata: footer semua segment live, lalu index overflow jika lebih baru (aturan ReadByKey)
+	for i, si := range m.Segments {
+		data, err := s.b.Get(segmentDir+"/"+si.Name, si.IndexOff, si.IndexLen)
+		if err != nil {
+			return err
+		}
+		entries, err := decodeSegmentFooter(data)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if w, ok := win[e.Key]; !ok || e.TS > w.ts {
+				win[e.Key] = winner{ts: e.TS, src: i}
+			}
+		}
+	}
+	idxNames, err := s.listDir(indexDir)
+	if err != nil {
+		return err
+	}
+	overflow := map[string]indexEntry{}
+	for _, name := range idxNames {
+		if !strings.HasSuffix(name, ".idx.jsonl") {
+			continue
+		}
+		entries, err := s.loadIndexFile(name)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		for _, e := range entries {
+			if cur, ok := overflow[e.Key]; !ok || e.TS > cur.TS {
+				overflow[e.Key] = e
+			}
+		}
+	}
+	for k, e := range overflow {
+		if w, ok := win[k]; !ok || e.TS > w.ts {
+			win[k] = winner{ts: e.TS, src: scanOverflow}
+		} else {
+			delete(overflow, k)
+		}
+	}
+
+	// 2. offloaded/ lama: TS hanya diketahui dari isi file, jadi dibaca satu per satu;
+	// menang jika tidak ada salinan yang lebih baru di segment/overflow
+	legacy, err := s.listDir(offloadDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range legacy {
+		key, ok := safeFileNameToKey(strings.TrimSuffix(name, ".json"))
+		if !ok {
+			continue
+		}
+		val, err := s.b.Get(offloadDir+"/"+name, 0, 0)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		ts := valueTSMillis(val)
+		if w, ok := win[key]; ok && w.ts > ts {
+			continue
+		}
+		if err := emit(key, Record{Value: val, TS: ts}); err != nil {
+			return err
+		}
+	}
+
+	// 3. Segment satu per satu; segment tanpa pemenang tidak dibaca
+	perSeg := make([]int, len(m.Segments))
+	for _, w := range win {
+		if w.src >= 0 {
+			perSeg[w.src]++
+		}
+	}
+	for i, si := range m.Segments {
+		if perSeg[i] == 0 {
+			continue
+		}
+		kvs, err := s.readSegmentRecords(si)
+		if err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			if w := win[kv.Key]; w.src != i || w.ts != kv.TS {
+				continue
+			}
+			if err := emit(kv.Key, Record{Value: kv.Value, TS: kv.TS, ExpireAt: kv.ExpireAt, Type: kv.Type}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 4. File overflow satu per satu
+	byFile := map[string][]indexEntry{}
+	for k, e := range overflow {
+		if win[k].src == scanOverflow {
+			f := path.Base(e.File)
+			byFile[f] = append(byFile[f], e)
+		}
+	}
+	for f, es := range byFile {
+		data, err := s.b.Get(f, 0, 0)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		for _, e := range es {
+			if e.Off < 0 || e.Off+e.Len > int64(len(data)) {
+				continue
+			}
+			var ev overflowEvent
+			if json.Unmarshal(data[e.Off:e.Off+e.Len], &ev) != nil || ev.Key != e.Key || len(ev.Value) == 0 {
+				continue
+			}
+			if err := emit(e.Key, Record{Value: ev.Value, TS: e.TS, ExpireAt: ev.expireAt(e.TS)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package coldstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSegment(t *testing.T, s *Store, kvs ...KeyValue) SegmentInfo {
+	t.Helper()
+	if err := s.WriteKeyValues(kvs); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Segments[len(m.Segments)-1]
+}
+
+func liveNames(t *testing.T, s *Store) map[string]bool {
+	t.Helper()
+	m, err := s.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]bool{}
+	for _, si := range m.Segments {
+		out[si.Name] = true
+	}
+	return out
+}
+
+func TestCompactMergesSmallSegmentsOnly(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	opts := CompactOptions{SegmentMaxRecords: 4, MinInputSegments: 3, TombstoneGrace: time.Hour, OrphanGrace: time.Hour}
+
+	var full []SegmentInfo
+	for i := 0; i < 2; i++ {
+		var kvs []KeyValue
+		for j := 0; j < 4; j++ {
+			kvs = append(kvs, KeyValue{Key: fmt.Sprintf("full:%d:%d", i, j), Value: []byte("v"), TS: 100})
+		}
+		full = append(full, writeSegment(t, s, kvs...))
+	}
+	for i := 0; i < 3; i++ {
+		writeSegment(t, s, KeyValue{Key: fmt.Sprintf("small:%d", i), Value: []byte("v"), TS: 200})
+	}
+
+	st, err := s.Compact(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Skipped || st.InputSegments != 3 || st.SegmentsKept != 2 || st.RecordsOut != 3 || st.OutputSegments != 1 {
+		t.Fatalf("stats = %+v, want only the 3 small segments merged", st)
+	}
+	live := liveNames(t, s)
+	for _, si := range full {
+		if !live[si.Name] {
+			t.Fatalf("full segment %s was rewritten", si.Name)
+		}
+	}
+
+	// Hasil gabungan (3 record) sendirian di tier-nya: putaran berikutnya tidak menulis ulang apa pun
+	st, err = s.Compact(opts)
+	if err != nil || !st.Skipped {
+		t.Fatalf("second run = %+v, %v; want skipped", st, err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.ReadRecord(fmt.Sprintf("small:%d", i)); err != nil {
+			t.Fatalf("small:%d after compaction: %v", i, err)
+		}
+	}
+}
+
+func TestCompactRespectsInputLimits(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	for i := 0; i < 6; i++ {
+		writeSegment(t, s, KeyValue{Key: fmt.Sprintf("k%d", i), Value: []byte("v")})
+	}
+	st, err := s.Compact(CompactOptions{MaxInputSegments: 3, MinInputSegments: 2, TombstoneGrace: time.Hour, OrphanGrace: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.InputSegments != 3 || st.SegmentsKept != 3 {
+		t.Fatalf("stats = %+v, want 3 of 6 segments", st)
+	}
+	if n := len(liveNames(t, s)); n != 4 {
+		t.Fatalf("%d live segments, want 3 untouched + 1 output", n)
+	}
+}
+
+func TestCompactRewritesSegmentWithTombstones(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	past := time.Now().Add(-time.Minute).UnixMilli()
+	var kvs []KeyValue
+	for i := 0; i < 4; i++ {
+		kvs = append(kvs, KeyValue{Key: fmt.Sprintf("k%d", i), Value: []byte("v"), TS: past})
+	}
+	// Segment penuh: hanya ikut karena data usangnya
+	writeSegment(t, s, kvs...)
+	for _, k := range []string{"k0", "k1"} {
+		if err := s.Delete(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	st, err := s.Compact(CompactOptions{SegmentMaxRecords: 4, OrphanGrace: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.InputSegments != 1 || st.Deleted != 2 || st.RecordsOut != 2 || st.TombstonesRemoved != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+	if _, err := s.ReadRecord("k0"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("k0 after compaction = %v, want ErrNotFound", err)
+	}
+	// Tidak ada data usang lagi: segment hasil tidak ditulis ulang
+	if st, err := s.Compact(CompactOptions{SegmentMaxRecords: 4}); err != nil || !st.Skipped {
+		t.Fatalf("second run = %+v, %v; want skipped", st, err)
+	}
+}
+
+// Record kedaluwarsa yang dibuang tidak boleh membuat salinan lama key di segment lain muncul kembali.
+func TestCompactExpiredRecordKeepsOlderCopyHidden(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	var kvs []KeyValue
+	for i := 0; i < 4; i++ {
+		kvs = append(kvs, KeyValue{Key: fmt.Sprintf("k%d", i), Value: []byte("old"), TS: 100})
+	}
+	writeSegment(t, s, kvs...)
+	writeSegment(t, s, KeyValue{Key: "k0", Value: []byte("new"), TS: 200, ExpireAt: time.Now().Add(-time.Second).UnixMilli()})
+
+	st, err := s.Compact(CompactOptions{SegmentMaxRecords: 4, TombstoneGrace: time.Hour, OrphanGrace: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.InputSegments != 1 || st.Expired != 1 || st.SegmentsKept != 1 {
+		t.Fatalf("stats = %+v, want only the expired segment compacted", st)
+	}
+	if rec, err := s.ReadRecord("k0"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("k0 = %q, %v; want older copy hidden (ErrDeleted)", rec.Value, err)
+	}
+	if _, err := s.ReadRecord("k1"); err != nil {
+		t.Fatalf("k1: %v", err)
+	}
+}
+
+func TestScanStreamsWinners(t *testing.T) {
+	dir := t.TempDir()
+	s, b := newTestStore(t, dir)
+	// Segment lama yang seluruh key-nya sudah punya salinan lebih baru
+	old := writeSegment(t, s,
+		KeyValue{Key: "a", Value: []byte("a1"), TS: 100},
+		KeyValue{Key: "b", Value: []byte("b1"), TS: 100},
+	)
+	writeSegment(t, s,
+		KeyValue{Key: "a", Value: []byte("a2"), TS: 200},
+		KeyValue{Key: "b", Value: []byte("b2"), TS: 200},
+		KeyValue{Key: "gone", Value: []byte("x"), TS: 100},
+		KeyValue{Key: "expired", Value: []byte("x"), TS: 100, ExpireAt: time.Now().Add(-time.Second).UnixMilli()},
+	)
+	if err := s.Delete("gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteEvents([]any{map[string]any{"key": "ev", "value": "e"}, map[string]any{"key": "b", "value": "b3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(offloadDir+"/"+keyToSafeFileName("legacy")+".json", []byte(`{"_ts":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	err := s.Scan(func(key string, rec Record) error {
+		if _, dup := got[key]; dup {
+			t.Errorf("key %q emitted twice", key)
+		}
+		got[key] = string(rec.Value)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "a2", "b": `"b3"`, "ev": `"e"`, "legacy": `{"_ts":1}`}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Scan = %v, want %v", got, want)
+	}
+	// Segment tanpa pemenang hanya dibaca footer-nya, tidak seluruh isinya
+	reads := 0
+	b.Backend = readLogger{b.Backend, old.Name, &reads}
+	if err := s.Scan(func(string, Record) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 0 {
+		t.Fatalf("superseded segment read in full %d times", reads)
+	}
+
+	stop := errors.New("stop")
+	if err := s.Scan(func(string, Record) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("Scan error = %v, want fn error", err)
+	}
+}
+
+// readLogger menghitung Get seluruh isi (off 0, n 0) untuk satu file.
+type readLogger struct {
+	Backend
+	name  string
+	count *int
+}
+
+func (r readLogger) Get(p string, off, n int64) ([]byte, error) {
+	if strings.HasSuffix(p, r.name) && off == 0 && n <= 0 {
+		*r.count++
+	}
+	return r.Backend.Get(p, off, n)
+}
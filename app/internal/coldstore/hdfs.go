@@ -0,0 +1,74 @@
+package coldstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"monolith-kv-sim/internal/hdfsx"
+)
+
+// hdfsBackend menyimpan blob di HDFS lewat WebHDFS (hdfsx.Client).
+type hdfsBackend struct {
+	c    *hdfsx.Client
+	root string
+}
+
+// NewHDFSBackend membuat backend HDFS dengan root HDFS_PATH (default /events_overflow).
+// Koneksi WebHDFS dikonfigurasi lewat env milik hdfsx.NewClient (WEBHDFS_URL, dll).
+func NewHDFSBackend() Backend {
+	p := os.Getenv("HDFS_PATH")
+	if p == "" {
+		p = "/events_overflow"
+	}
+	return &hdfsBackend{c: hdfsx.NewClient(), root: strings.TrimSuffix(p, "/")}
+}
+
+func (h *hdfsBackend) Name() string { return "hdfs" }
+
+// Init memastikan direktori root di HDFS sudah ada (MKDIRS, setara hdfs dfs -mkdir -p).
+func (h *hdfsBackend) Init() error { return h.c.Mkdirs(h.root) }
+
+func (h *hdfsBackend) full(p string) string { return h.root + "/" + p }
+
+// Put menulis file (CREATE overwrite); parent directory dibuat otomatis oleh namenode.
+func (h *hdfsBackend) Put(p string, data []byte) error { return h.c.Create(h.full(p), data, true) }
+
+// PutIfAbsent memakai CREATE tanpa overwrite; namenode menolak jika file sudah ada.
+func (h *hdfsBackend) PutIfAbsent(p string, data []byte) error {
+	err := h.c.Create(h.full(p), data, false)
+	if err != nil && errors.Is(err, hdfsx.ErrExists) {
+		return fmt.Errorf("%w: %v", ErrExists, err)
+	}
+	return err
+}
+
+func (h *hdfsBackend) Get(p string, off, n int64) ([]byte, error) {
+	b, err := h.c.Open(h.full(p), off, n)
+	return b, mapHDFSErr(err)
+}
+
+func (h *hdfsBackend) Delete(p string) error { return h.c.Delete(h.full(p), false) }
+
+func (h *hdfsBackend) List(dir string) ([]string, error) {
+	st, err := h.c.ListStatus(h.full(dir))
+	if err != nil {
+		return nil, mapHDFSErr(err)
+	}
+	names := make([]string, 0, len(st))
+	for _, f := range st {
+		if f.Type == "FILE" {
+			names = append(names, f.PathSuffix)
+		}
+	}
+	return names, nil
+}
+
+// mapHDFSErr menerjemahkan "file tidak ada" dari WebHDFS menjadi ErrNotFound milik coldstore.
+func mapHDFSErr(err error) error {
+	if err != nil && errors.Is(err, hdfsx.ErrNotFound) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
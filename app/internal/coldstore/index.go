@@ -0,0 +1,228 @@
+package coldstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// overflowSeq membedakan nama file overflow yang ditulis pada milidetik yang sama.
+var overflowSeq atomic.Int64
+
+// indexEntry adalah lokasi satu event di file overflow JSONL.
+// TS adalah waktu tulis (unix ms), dipakai untuk aturan "tulisan terbaru menang".
+type indexEntry struct {
+	Key  string `json:"key"`
+	File string `json:"file"`
+	Off  int64  `json:"off"`
+	Len  int64  `json:"len"`
+	TS   int64  `json:"ts"`
+}
+
+// overflowIndex adalah index in-memory key -> lokasi event terbaru di overflow_*.jsonl.
+// Index dibangun dari file index/*.idx.jsonl di backend (satu per file overflow),
+// sehingga semua instance ingestor bisa menemukan event yang ditulis instance lain.
+type overflowIndex struct {
+	mu          sync.RWMutex
+	entries     map[string]indexEntry
+	loaded      map[string]bool // nama index file yang sudah dimuat
+	refreshedAt time.Time
+}
+
+func newOverflowIndex() *overflowIndex {
+	return &overflowIndex{entries: map[string]indexEntry{}, loaded: map[string]bool{}}
+}
+
+// merge menambahkan entries ke index; untuk key yang sama, entry dengan TS terbesar menang.
+func (ix *overflowIndex) merge(entries []indexEntry) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, e := range entries {
+		if cur, ok := ix.entries[e.Key]; ok && cur.TS > e.TS {
+			continue
+		}
+		ix.entries[e.Key] = e
+	}
+}
+
+func (ix *overflowIndex) get(key string) (indexEntry, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	e, ok := ix.entries[key]
+	return e, ok
+}
+
+// dropFiles membuang entry yang menunjuk ke file overflow yang sudah dihapus (mis. oleh compactor).
+func (ix *overflowIndex) dropFiles(files map[string]bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for k, e := range ix.entries {
+		if files[path.Base(e.File)] {
+			delete(ix.entries, k)
+		}
+	}
+}
+
+// keys mengembalikan semua key yang ter-index.
+func (ix *overflowIndex) keys() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	out := make([]string, 0, len(ix.entries))
+	for k := range ix.entries {
+		out = append(out, k)
+	}
+	return out
+}
+
+// indexFileName dan overflowFileName memetakan file overflow <-> index file-nya.
+func indexFileName(overflowName string) string {
+	return strings.TrimSuffix(overflowName, ".jsonl") + ".idx.jsonl"
+}
+
+func overflowFileName(indexName string) string {
+	return strings.TrimSuffix(indexName, ".idx.jsonl") + ".jsonl"
+}
+
+// writeIndexFile menulis index untuk satu file overflow ke index/ sebagai JSONL.
+func (s *Store) writeIndexFile(overflowName string, entries []indexEntry) error {
+	name := indexFileName(overflowName)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := s.b.Put(indexDir+"/"+name, buf.Bytes()); err != nil {
+		return err
+	}
+	s.index.mu.Lock()
+	s.index.loaded[name] = true
+	s.index.mu.Unlock()
+	return nil
+}
+
+// refreshIndex memuat index file baru dari backend, paling sering sekali per IndexRefresh.
+// Index file yang sudah pernah dimuat tidak dibaca ulang (index file bersifat immutable);
+// index file yang hilang (file overflow sudah digabung compactor) dibuang dari index.
+func (s *Store) refreshIndex() {
+	ix := s.index
+	ix.mu.Lock()
+	if !ix.refreshedAt.IsZero() && time.Since(ix.refreshedAt) < s.IndexRefresh {
+		ix.mu.Unlock()
+		return
+	}
+	ix.refreshedAt = time.Now()
+	ix.mu.Unlock()
+
+	names, err := s.b.List(indexDir)
+	if err != nil {
+		return
+	}
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		listed[name] = true
+	}
+	gone := map[string]bool{}
+	ix.mu.Lock()
+	for name := range ix.loaded {
+		if !listed[name] {
+			delete(ix.loaded, name)
+			gone[overflowFileName(name)] = true
+		}
+	}
+	ix.mu.Unlock()
+	if len(gone) > 0 {
+		ix.dropFiles(gone)
+	}
+
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".idx.jsonl") {
+			continue
+		}
+		ix.mu.RLock()
+		done := ix.loaded[name]
+		ix.mu.RUnlock()
+		if done {
+			continue
+		}
+		entries, err := s.loadIndexFile(name)
+		if err != nil {
+			continue
+		}
+		ix.merge(entries)
+		ix.mu.Lock()
+		ix.loaded[name] = true
+		ix.mu.Unlock()
+	}
+}
+
+// loadIndexFile membaca satu index file dari index/.
+func (s *Store) loadIndexFile(name string) ([]indexEntry, error) {
+	data, err := s.b.Get(indexDir+"/"+name, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	var entries []indexEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e indexEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Key != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// overflowEvent adalah field event overflow (Event milik ingestor) yang dibaca cold store.
+type overflowEvent struct {
+	Key        string          `json:"key"`
+	Value      json.RawMessage `json:"value"`
+	TTLSeconds int64           `json:"ttl_sec"`
+}
+
+// expireAt menghitung waktu kedaluwarsa absolut (unix ms) dari waktu tulis ts + ttl_sec.
+func (ev overflowEvent) expireAt(ts int64) int64 {
+	if ev.TTLSeconds <= 0 {
+		return 0
+	}
+	return ts + ev.TTLSeconds*1000
+}
+
+// readOverflow membaca satu baris event dari file overflow sesuai lokasi di index,
+// lalu mengembalikan field "value"-nya (payload yang sama seperti yang disimpan di Redis)
+// beserta waktu kedaluwarsanya (waktu tulis + ttl_sec).
+// File overflow selalu berada di root; path.Base juga menangani index lama yang
+// menyimpan path HDFS absolut.
+func (s *Store) readOverflow(e indexEntry) ([]byte, int64, error) {
+	line, err := s.b.Get(path.Base(e.File), e.Off, e.Len)
+	if err != nil {
+		return nil, 0, err
+	}
+	var ev overflowEvent
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return nil, 0, err
+	}
+	if ev.Key != e.Key || len(ev.Value) == 0 {
+		return nil, 0, fmt.Errorf("coldstore: overflow index mismatch for key %q in %s", e.Key, e.File)
+	}
+	return ev.Value, ev.expireAt(e.TS), nil
+}
+
+// eventKey mengambil field "key" dari satu baris JSON event (kosong jika tidak ada).
+func eventKey(line []byte) string {
+	var ev struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return ""
+	}
+	return ev.Key
+}
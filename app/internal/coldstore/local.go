@@ -0,0 +1,132 @@
+package coldstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// localBackend menyimpan blob di local filesystem.
+// Cocok untuk development dan test tanpa cluster HDFS.
+type localBackend struct {
+	root string
+}
+
+// NewLocalBackend membuat backend local filesystem dengan root COLD_STORE_DIR
+// (default /data/coldstore).
+func NewLocalBackend() Backend {
+	dir := os.Getenv("COLD_STORE_DIR")
+	if dir == "" {
+		dir = "/data/coldstore"
+	}
+	return &localBackend{root: dir}
+}
+
+func (l *localBackend) Name() string { return "local" }
+
+func (l *localBackend) Init() error { return os.MkdirAll(l.root, 0755) }
+
+func (l *localBackend) full(p string) string { return filepath.Join(l.root, filepath.FromSlash(p)) }
+
+// Put menulis ke file temporary lalu rename, sehingga pembaca tidak pernah melihat file setengah jadi.
+func (l *localBackend) Put(p string, data []byte) error {
+	dst := l.full(p)
+	tmp, err := l.writeTemp(dst, data)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// PutIfAbsent menulis file temporary lalu hard link ke tujuan; link gagal jika tujuan sudah ada.
+func (l *localBackend) PutIfAbsent(p string, data []byte) error {
+	dst := l.full(p)
+	tmp, err := l.writeTemp(dst, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, dst); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: %v", ErrExists, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeTemp menulis data ke file temporary di direktori yang sama dengan dst.
+func (l *localBackend) writeTemp(dst string, data []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (l *localBackend) Get(p string, off, n int64) ([]byte, error) {
+	f, err := os.Open(l.full(p))
+	if err != nil {
+		return nil, mapLocalErr(err)
+	}
+	defer f.Close()
+	if off > 0 {
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	if n <= 0 {
+		return io.ReadAll(f)
+	}
+	buf := make([]byte, n)
+	m, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return buf[:m], nil
+}
+
+func (l *localBackend) Delete(p string) error {
+	if err := os.Remove(l.full(p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *localBackend) List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(l.full(dir))
+	if err != nil {
+		return nil, mapLocalErr(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		// File temporary milik Put yang sedang berjalan tidak ikut di-list
+		if e.Type().IsRegular() && e.Name()[0] != '.' {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// mapLocalErr menerjemahkan fs.ErrNotExist menjadi ErrNotFound milik coldstore.
+func mapLocalErr(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package coldstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrExists dikembalikan Backend.PutIfAbsent jika path sudah ada.
+var ErrExists = errors.New("coldstore: already exists")
+
+// Manifest mencatat segment yang "live" (boleh dibaca).
+// Setiap perubahan ditulis sebagai file baru segments/MANIFEST-<versi>.json dengan
+// PutIfAbsent, sehingga pergantian manifest bersifat atomic: pembaca selalu melihat
+// versi lama atau versi baru secara utuh, dan dua penulis yang balapan tidak saling
+// menimpa (yang kalah mengulang dari versi terbaru).
+type Manifest struct {
+	Version  int64         `json:"version"`
+	Segments []SegmentInfo `json:"segments"`
+}
+
+// SegmentInfo adalah metadata satu segment di manifest.
+type SegmentInfo struct {
+	Name     string `json:"name"` // nama file di segments/
+	Size     int64  `json:"size"`
+	IndexOff int64  `json:"index_off"` // offset footer index di file
+	IndexLen int64  `json:"index_len"`
+	BloomOff int64  `json:"bloom_off,omitempty"` // offset Bloom filter key (0: segment lama tanpa bloom)
+	BloomLen int64  `json:"bloom_len,omitempty"`
+	Count    int    `json:"count"`
+	MinTS    int64  `json:"min_ts"`
+	MaxTS    int64  `json:"max_ts"`
+	Created  int64  `json:"created"` // unix ms
+	// ExpireMin adalah ExpireAt terkecil di segment; ExpireMax yang terbesar, 0 jika ada record
+	// tanpa expiry (segment tidak pernah kedaluwarsa seluruhnya). Dipakai compactor untuk
+	// memilih segment tanpa membaca isinya; segment lama tanpa field ini bernilai 0.
+	ExpireMin int64 `json:"expire_min,omitempty"`
+	ExpireMax int64 `json:"expire_max,omitempty"`
+}
+
+const (
+	manifestPrefix = "MANIFEST-"
+	// manifestCommitRetries membatasi percobaan ulang saat balapan dengan penulis lain.
+	manifestCommitRetries = 20
+)
+
+func manifestName(v int64) string { return fmt.Sprintf("%s%020d.json", manifestPrefix, v) }
+
+// manifestVersions mengembalikan semua versi manifest yang ada di segments/.
+func (s *Store) manifestVersions() ([]int64, error) {
+	names, err := s.b.List(segmentDir)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var vs []int64
+	for _, n := range names {
+		if !strings.HasPrefix(n, manifestPrefix) || !strings.HasSuffix(n, ".json") {
+			continue
+		}
+		v, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(n, manifestPrefix), ".json"), 10, 64)
+		if err == nil {
+			vs = append(vs, v)
+		}
+	}
+	return vs, nil
+}
+
+// LoadManifest membaca manifest versi terbaru. Jika belum ada, mengembalikan manifest kosong versi 0.
+func (s *Store) LoadManifest() (Manifest, error) {
+	vs, err := s.manifestVersions()
+	if err != nil {
+		return Manifest{}, err
+	}
+	var latest int64
+	for _, v := range vs {
+		if v > latest {
+			latest = v
+		}
+	}
+	if latest == 0 {
+		return Manifest{}, nil
+	}
+	b, err := s.b.Get(segmentDir+"/"+manifestName(latest), 0, 0)
+	if err != nil {
+		return Manifest{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("coldstore: bad manifest v%d: %w", latest, err)
+	}
+	return m, nil
+}
+
+// CommitManifest menerapkan update ke manifest terbaru lalu menulisnya sebagai versi berikutnya.
+// Jika versi tersebut sudah ditulis penulis lain, update diulang dari manifest terbaru.
+// update boleh mengembalikan error untuk membatalkan commit.
+func (s *Store) CommitManifest(update func(m *Manifest) error) (Manifest, error) {
+	for i := 0; i < manifestCommitRetries; i++ {
+		m, err := s.LoadManifest()
+		if err != nil {
+			return Manifest{}, err
+		}
+		if err := update(&m); err != nil {
+			return Manifest{}, err
+		}
+		m.Version++
+		b, err := json.Marshal(m)
+		if err != nil {
+			return Manifest{}, err
+		}
+		err = s.b.PutIfAbsent(segmentDir+"/"+manifestName(m.Version), b)
+		if errors.Is(err, ErrExists) {
+			continue
+		}
+		if err != nil {
+			return Manifest{}, err
+		}
+		return m, nil
+	}
+	return Manifest{}, errors.New("coldstore: manifest commit contention, giving up")
+}
@@ -0,0 +1,331 @@
+package coldstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3Backend menyimpan blob di object storage S3-compatible (AWS S3, MinIO, dll).
+// Request ditandatangani dengan AWS Signature Version 4 dan memakai path-style URL
+// (http://endpoint/bucket/key), yang didukung MinIO tanpa konfigurasi DNS tambahan.
+type s3Backend struct {
+	endpoint  string // mis. http://minio:9000
+	bucket    string
+	prefix    string // prefix object key, tanpa "/" di akhir
+	region    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// NewS3Backend membuat backend S3 dari environment variable:
+// S3_ENDPOINT (default http://minio:9000), S3_BUCKET (default coldstore),
+// S3_PREFIX (default events_overflow), S3_REGION (default us-east-1),
+// S3_ACCESS_KEY dan S3_SECRET_KEY.
+func NewS3Backend() Backend {
+	get := func(env, def string) string {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+		return def
+	}
+	timeout := 10
+	if s := os.Getenv("S3_TIMEOUT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			timeout = v
+		}
+	}
+	return &s3Backend{
+		endpoint:  strings.TrimSuffix(get("S3_ENDPOINT", "http://minio:9000"), "/"),
+		bucket:    get("S3_BUCKET", "coldstore"),
+		prefix:    strings.Trim(get("S3_PREFIX", "events_overflow"), "/"),
+		region:    get("S3_REGION", "us-east-1"),
+		accessKey: os.Getenv("S3_ACCESS_KEY"),
+		secretKey: os.Getenv("S3_SECRET_KEY"),
+		http:      &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+func (s *s3Backend) Name() string { return "s3" }
+
+// Init membuat bucket jika belum ada.
+func (s *s3Backend) Init() error {
+	resp, err := s.do(http.MethodHead, "", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	resp, err = s.do(http.MethodPut, "", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 409 BucketAlreadyOwnedByYou: dibuat bersamaan oleh instance lain
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return s3Error(resp, "CreateBucket", s.bucket)
+	}
+	return nil
+}
+
+func (s *s3Backend) objectKey(p string) string {
+	if s.prefix == "" {
+		return p
+	}
+	return s.prefix + "/" + p
+}
+
+func (s *s3Backend) Put(p string, data []byte) error {
+	key := s.objectKey(p)
+	resp, err := s.do(http.MethodPut, key, nil, nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, "PutObject", key)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// PutIfAbsent memakai conditional write (If-None-Match: *); S3/MinIO membalas 412 jika object sudah ada.
+func (s *s3Backend) PutIfAbsent(p string, data []byte) error {
+	key := s.objectKey(p)
+	resp, err := s.do(http.MethodPut, key, nil, http.Header{"If-None-Match": {"*"}}, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return fmt.Errorf("%w: s3 object %s", ErrExists, key)
+	}
+	return s3Error(resp, "PutObject", key)
+}
+
+func (s *s3Backend) Get(p string, off, n int64) ([]byte, error) {
+	key := s.objectKey(p)
+	h := http.Header{}
+	if n > 0 {
+		h.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+	} else if off > 0 {
+		h.Set("Range", fmt.Sprintf("bytes=%d-", off))
+	}
+	resp, err := s.do(http.MethodGet, key, nil, h, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, s3Error(resp, "GetObject", key)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3Backend) Delete(p string) error {
+	key := s.objectKey(p)
+	resp, err := s.do(http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3 membalas 204 juga untuk object yang tidak ada
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp, "DeleteObject", key)
+	}
+	return nil
+}
+
+// List memakai ListObjectsV2 dengan delimiter "/" agar hanya object langsung di bawah dir yang ikut.
+func (s *s3Backend) List(dir string) ([]string, error) {
+	prefix := s.objectKey(dir) + "/"
+	var names []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", q, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error(resp, "ListObjectsV2", prefix)
+			resp.Body.Close()
+			return nil, err
+		}
+		var out struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range out.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, prefix))
+		}
+		if !out.IsTruncated || out.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+// do mengirim request path-style ke bucket (key kosong = operasi level bucket) dengan SigV4.
+func (s *s3Backend) do(method, key string, q url.Values, h http.Header, body []byte) (*http.Response, error) {
+	p := "/" + s.bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = p
+	u.RawPath = s3EscapePath(p)
+	u.RawQuery = s3CanonicalQuery(q)
+
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u.String(), rd)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.http.Do(req)
+}
+
+// sign menambahkan header Authorization AWS Signature Version 4.
+// Header yang ditandatangani: host, x-amz-content-sha256, x-amz-date.
+func (s *s3Backend) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.accessKey == "" {
+		return // Anonymous request (mis. bucket publik di MinIO lokal)
+	}
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// s3Escape meng-encode string sesuai aturan URI-encode SigV4 (RFC 3986, spasi = %20).
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// s3EscapePath meng-encode tiap segmen path, "/" dibiarkan.
+func s3EscapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = s3Escape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// s3CanonicalQuery menyusun query string terurut menurut nama parameter (syarat SigV4).
+func s3CanonicalQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Error membaca body error XML S3; 404 (NoSuchKey/NoSuchBucket) dipetakan ke ErrNotFound.
+func s3Error(resp *http.Response, op, key string) error {
+	var out struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(b, &out)
+	msg := fmt.Sprintf("s3 %s %s: %d %s %s", op, key, resp.StatusCode, out.Code, out.Message)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, msg)
+	}
+	return fmt.Errorf("coldstore: %s", msg)
+}
@@ -0,0 +1,531 @@
+package coldstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// segmentSeq membedakan nama segment yang ditulis pada milidetik yang sama.
+var segmentSeq atomic.Int64
+
+// Format segment file (segments/seg_<ms>_<seq>.seg):
+//
+//	record*   : uvarint(len key) | key | uvarint(len value) | value
+//	footer    : JSON array segEntry (index key -> offset value di file ini)
+//	bloom     : Bloom filter semua key di segment (lihat bloomFilter.marshal)
+//	trailer   : uint64 big-endian offset footer | magic "KVSEG001"
+//
+// Satu segment berisi banyak key/value hasil satu putaran offload, sehingga
+// jumlah file di HDFS tidak lagi tumbuh satu per key. Offset footer dan bloom juga
+// dicatat di manifest: pembaca hanya memuat bloom tiap segment (kecil), lalu footer
+// dimuat saat pertama kali bloom segment tersebut menjawab "mungkin ada".
+const segmentDir = "segments"
+
+var segmentMagic = []byte("KVSEG001")
+
+const segmentTrailerLen = 16
+
+// KeyValue adalah satu record yang akan ditulis ke segment.
+// TS adalah waktu data (unix ms) untuk aturan "tulisan terbaru menang";
+// jika 0, diambil dari _ts di value atau waktu tulis.
+// ExpireAt adalah waktu kedaluwarsa absolut (unix ms); 0 berarti tanpa expiry.
+// Type adalah tipe Redis asal key ("" = string); untuk tipe lain Value berisi
+// typed envelope JSON (lihat redisx.ReadTypedMany).
+type KeyValue struct {
+	Key      string
+	Value    []byte
+	TS       int64
+	ExpireAt int64
+	Type     string
+}
+
+// segEntry adalah entry footer index: lokasi value di dalam segment.
+type segEntry struct {
+	Key      string `json:"k"`
+	Off      int64  `json:"o"`
+	Len      int64  `json:"n"`
+	TS       int64  `json:"ts"`
+	ExpireAt int64  `json:"x,omitempty"`
+	Type     string `json:"t,omitempty"`
+}
+
+// encodedSegment adalah hasil encodeSegment beserta lokasi footer dan bloom di file.
+type encodedSegment struct {
+	data               []byte
+	entries            []segEntry
+	indexOff, indexLen int64
+	bloomOff, bloomLen int64
+}
+
+// encodeSegment menyusun isi segment file dari kvs; fp adalah target false positive rate bloom.
+func encodeSegment(kvs []KeyValue, fp float64) (encodedSegment, error) {
+	var buf bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	entries := make([]segEntry, 0, len(kvs))
+	bloom := newBloomFilter(len(kvs), fp)
+	for _, kv := range kvs {
+		n := binary.PutUvarint(tmp[:], uint64(len(kv.Key)))
+		buf.Write(tmp[:n])
+		buf.WriteString(kv.Key)
+		n = binary.PutUvarint(tmp[:], uint64(len(kv.Value)))
+		buf.Write(tmp[:n])
+		entries = append(entries, segEntry{Key: kv.Key, Off: int64(buf.Len()), Len: int64(len(kv.Value)), TS: kv.TS, ExpireAt: kv.ExpireAt, Type: kv.Type})
+		buf.Write(kv.Value)
+		bloom.add(kv.Key)
+	}
+	seg := encodedSegment{entries: entries, indexOff: int64(buf.Len())}
+	footer, err := json.Marshal(entries)
+	if err != nil {
+		return seg, err
+	}
+	buf.Write(footer)
+	seg.indexLen = int64(len(footer))
+	bloomData := bloom.marshal()
+	seg.bloomOff, seg.bloomLen = int64(buf.Len()), int64(len(bloomData))
+	buf.Write(bloomData)
+	var trailer [segmentTrailerLen]byte
+	binary.BigEndian.PutUint64(trailer[:8], uint64(seg.indexOff))
+	copy(trailer[8:], segmentMagic)
+	buf.Write(trailer[:])
+	seg.data = buf.Bytes()
+	return seg, nil
+}
+
+// decodeSegmentFooter mem-parse footer index segment.
+func decodeSegmentFooter(b []byte) ([]segEntry, error) {
+	var entries []segEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("coldstore: bad segment footer: %w", err)
+	}
+	return entries, nil
+}
+
+// segLoc adalah lokasi value satu key di segment.
+type segLoc struct {
+	Seg string
+	segEntry
+}
+
+// liveSegment adalah satu segment live di segment index.
+// Bloom dimuat saat manifest di-refresh; footer dimuat (lalu di-cache) saat dibutuhkan.
+// Segment lama tanpa bloom (BloomLen 0) langsung dimuat footer-nya.
+type liveSegment struct {
+	info  SegmentInfo
+	bloom *bloomFilter
+
+	mu     sync.Mutex
+	footer map[string]segEntry
+}
+
+// mayContain bernilai false jika key pasti tidak ada di segment: lewat bloom, atau footer
+// untuk segment tanpa bloom. Segment yang bloom/footer-nya belum dimuat mungkin berisi key apa pun.
+func (ls *liveSegment) mayContain(key string) bool {
+	if ls.bloom != nil {
+		return ls.bloom.mayContain(key)
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.footer == nil {
+		return true
+	}
+	_, ok := ls.footer[key]
+	return ok
+}
+
+// segmentIndex adalah index in-memory segment live sesuai manifest versi terakhir yang dimuat,
+// ditambah Bloom filter key di offloaded/ (layout lama) dan daftar key bertombstone,
+// agar miss maupun hit tanpa tombstone tidak perlu request tambahan ke backend.
+type segmentIndex struct {
+	mu          sync.RWMutex
+	version     int64 // versi manifest yang sudah dimuat
+	segs        map[string]*liveSegment
+	order       []*liveSegment       // urut MaxTS menurun: segment terbaru dicek lebih dulu
+	legacy      *bloomFilter         // nil: isi offloaded/ belum diketahui, selalu cek backend
+	tombs       map[string]tombEntry // key di tombstones/; nil: daftar belum dimuat, selalu cek backend
+	refreshedAt time.Time
+}
+
+// tombUnread menandai tombstone yang ada di daftar tetapi deleted_at-nya belum dibaca.
+const tombUnread = -1
+
+// tombEntry adalah tombstone satu key di segmentIndex.
+type tombEntry struct {
+	deletedAt int64     // unix ms, atau tombUnread
+	wrote     time.Time // kapan tombstone ditulis oleh instance ini (nol jika dari listing)
+}
+
+func newSegmentIndex() *segmentIndex {
+	return &segmentIndex{segs: map[string]*liveSegment{}}
+}
+
+// set mengganti seluruh daftar segment live.
+func (ix *segmentIndex) set(segs map[string]*liveSegment, version int64) {
+	order := make([]*liveSegment, 0, len(segs))
+	for _, ls := range segs {
+		order = append(order, ls)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].info.MaxTS > order[j].info.MaxTS })
+	ix.mu.Lock()
+	ix.segs, ix.order, ix.version = segs, order, version
+	ix.mu.Unlock()
+}
+
+// add menambahkan segment yang baru ditulis instance ini (footer sudah diketahui).
+func (ix *segmentIndex) add(info SegmentInfo, entries []segEntry, bloom *bloomFilter) {
+	ls := &liveSegment{info: info, bloom: bloom, footer: footerMap(entries)}
+	ix.mu.RLock()
+	segs := make(map[string]*liveSegment, len(ix.segs)+1)
+	for name, cur := range ix.segs {
+		segs[name] = cur
+	}
+	version := ix.version
+	ix.mu.RUnlock()
+	segs[info.Name] = ls
+	ix.set(segs, version)
+}
+
+func (ix *segmentIndex) snapshot() []*liveSegment {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.order
+}
+
+// legacyMayContain bernilai false jika key pasti tidak ada di offloaded/.
+func (ix *segmentIndex) legacyMayContain(key string) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.legacy == nil || ix.legacy.mayContain(key)
+}
+
+func footerMap(entries []segEntry) map[string]segEntry {
+	m := make(map[string]segEntry, len(entries))
+	for _, e := range entries {
+		// Key duplikat di satu segment (SCAN bisa mengembalikan key dua kali): TS terbesar menang
+		if cur, ok := m[e.Key]; ok && cur.TS > e.TS {
+			continue
+		}
+		m[e.Key] = e
+	}
+	return m
+}
+
+// WriteKeyValues menulis banyak key/value sebagai satu segment file, lalu
+// mendaftarkannya di manifest. Segment baru terlihat oleh pembaca hanya setelah
+// commit manifest sukses, jadi segment yang gagal di tengah jalan tidak pernah dibaca.
+// TS kosong diisi dari _ts value (ms), atau waktu tulis jika value tidak punya _ts;
+// TS yang terisi juga ditulis balik ke kvs agar pemanggil tahu TS record yang tersimpan.
+func (s *Store) WriteKeyValues(kvs []KeyValue) error {
+	if len(kvs) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	for i := range kvs {
+		if kvs[i].TS == 0 {
+			if kvs[i].TS = valueTSMillis(kvs[i].Value); kvs[i].TS == 0 {
+				kvs[i].TS = now
+			}
+		}
+	}
+	info, seg, err := s.putSegment(kvs, now)
+	if err != nil {
+		return err
+	}
+	if _, err := s.CommitManifest(func(m *Manifest) error {
+		m.Segments = append(m.Segments, info)
+		return nil
+	}); err != nil {
+		// Segment tanpa entry manifest tidak akan pernah dibaca; hapus best-effort
+		_ = s.b.Delete(segmentDir + "/" + info.Name)
+		return err
+	}
+	// Index lokal langsung di-update agar read berikutnya di instance ini tidak perlu refresh
+	bloom, _ := unmarshalBloomFilter(seg.data[seg.bloomOff : seg.bloomOff+seg.bloomLen])
+	s.segs.add(info, seg.entries, bloom)
+	return nil
+}
+
+// putSegment menulis kvs sebagai satu segment file tanpa commit manifest.
+// Mengembalikan metadata untuk manifest dan hasil encode-nya.
+func (s *Store) putSegment(kvs []KeyValue, now int64) (SegmentInfo, encodedSegment, error) {
+	si := SegmentInfo{Name: fmt.Sprintf("seg_%d_%d.seg", now, segmentSeq.Add(1)), Count: len(kvs), Created: now}
+	noExpiry := false
+	for i, kv := range kvs {
+		if i == 0 || kv.TS < si.MinTS {
+			si.MinTS = kv.TS
+		}
+		if kv.TS > si.MaxTS {
+			si.MaxTS = kv.TS
+		}
+		if kv.ExpireAt == 0 {
+			noExpiry = true
+			continue
+		}
+		if si.ExpireMin == 0 || kv.ExpireAt < si.ExpireMin {
+			si.ExpireMin = kv.ExpireAt
+		}
+		if kv.ExpireAt > si.ExpireMax {
+			si.ExpireMax = kv.ExpireAt
+		}
+	}
+	if noExpiry {
+		si.ExpireMax = 0
+	}
+	seg, err := encodeSegment(kvs, s.BloomFPRate)
+	if err != nil {
+		return si, seg, err
+	}
+	si.Size = int64(len(seg.data))
+	si.IndexOff, si.IndexLen = seg.indexOff, seg.indexLen
+	si.BloomOff, si.BloomLen = seg.bloomOff, seg.bloomLen
+	if err := s.b.Put(segmentDir+"/"+si.Name, seg.data); err != nil {
+		return si, seg, err
+	}
+	return si, seg, nil
+}
+
+// Invalidate memaksa ReadByKey berikutnya memuat ulang manifest, bloom, daftar tombstone, dan
+// index overflow dari backend. Dipanggil saat ada notifikasi bahwa offloader/compactor/ingestor
+// baru menulis data atau tombstone.
+func (s *Store) Invalidate() {
+	s.segs.mu.Lock()
+	s.segs.refreshedAt = time.Time{}
+	s.segs.mu.Unlock()
+	s.index.mu.Lock()
+	s.index.refreshedAt = time.Time{}
+	s.index.mu.Unlock()
+}
+
+// refreshSegments memuat ulang manifest terbaru dan daftar tombstone, paling sering sekali per IndexRefresh.
+func (s *Store) refreshSegments() {
+	ix := s.segs
+	ix.mu.Lock()
+	if !ix.refreshedAt.IsZero() && time.Since(ix.refreshedAt) < s.IndexRefresh {
+		ix.mu.Unlock()
+		return
+	}
+	ix.refreshedAt = time.Now()
+	ix.mu.Unlock()
+
+	s.refreshLegacy()
+	s.refreshTombstones()
+	m, err := s.LoadManifest()
+	if err != nil {
+		return
+	}
+	s.applyManifest(m)
+}
+
+// refreshLegacy membangun Bloom filter dari daftar file offloaded/ (layout lama).
+// Tidak ada lagi yang menulis ke offloaded/ (compactor hanya menghapus isinya), jadi filter
+// cukup dibangun sekali: file yang kemudian digabung compactor hanya menjadi false positive
+// (Get menjawab ErrNotFound). Selama listing belum berhasil, filter nil dan ReadByKey tetap mengecek backend.
+func (s *Store) refreshLegacy() {
+	if !s.segs.legacyUnknown() {
+		return
+	}
+	names, err := s.listDir(offloadDir)
+	if err != nil {
+		return
+	}
+	bloom := newBloomFilter(len(names), s.BloomFPRate)
+	for _, n := range names {
+		if k, ok := safeFileNameToKey(strings.TrimSuffix(n, ".json")); ok {
+			bloom.add(k)
+		}
+	}
+	s.segs.mu.Lock()
+	s.segs.legacy = bloom
+	s.segs.mu.Unlock()
+}
+
+func (ix *segmentIndex) legacyUnknown() bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.legacy == nil
+}
+
+// refreshTombstones memuat ulang daftar key di tombstones/ (satu List). deleted_at dibaca
+// belakangan, hanya untuk key yang ditemukan di cold tier (lihat tombstoneAt). Tombstone yang
+// ditulis instance ini selama listing berjalan dipertahankan walaupun belum ikut terdaftar.
+func (s *Store) refreshTombstones() {
+	start := time.Now()
+	names, err := s.listDir(tombstoneDir)
+	if err != nil {
+		s.segs.mu.Lock()
+		s.segs.tombs = nil
+		s.segs.mu.Unlock()
+		return
+	}
+	tombs := make(map[string]tombEntry, len(names))
+	for _, n := range names {
+		if k, ok := safeFileNameToKey(strings.TrimSuffix(n, ".json")); ok {
+			tombs[k] = tombEntry{deletedAt: tombUnread}
+		}
+	}
+	ix := s.segs
+	ix.mu.Lock()
+	for k, e := range ix.tombs {
+		if _, listed := tombs[k]; !listed && e.wrote.After(start) {
+			tombs[k] = e
+		}
+	}
+	ix.tombs = tombs
+	ix.mu.Unlock()
+}
+
+// noteTombstone mencatat tombstone yang baru ditulis instance ini.
+func (ix *segmentIndex) noteTombstone(key string, deletedAt int64) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.tombs != nil {
+		ix.tombs[key] = tombEntry{deletedAt: deletedAt, wrote: time.Now()}
+	}
+}
+
+// tombstoneAt mengembalikan deleted_at tombstone key, jika ada. Key yang tidak ada di daftar
+// tombstones/ (hampir semua read) dijawab tanpa request ke backend; deleted_at key yang ada di
+// daftar dibaca sekali lalu di-cache sampai refresh berikutnya. Selama daftar belum berhasil
+// dimuat, tombstone selalu dibaca dari backend.
+func (s *Store) tombstoneAt(key string) (int64, bool) {
+	ix := s.segs
+	ix.mu.RLock()
+	e, listed := ix.tombs[key]
+	known := ix.tombs != nil
+	ix.mu.RUnlock()
+	if known && !listed {
+		return 0, false
+	}
+	if listed && e.deletedAt != tombUnread {
+		return e.deletedAt, true
+	}
+	deletedAt, ok := s.readTombstone(keyToSafeFileName(key))
+	if ok && listed {
+		ix.mu.Lock()
+		if cur, still := ix.tombs[key]; still && cur.deletedAt == tombUnread {
+			ix.tombs[key] = tombEntry{deletedAt: deletedAt}
+		}
+		ix.mu.Unlock()
+	}
+	return deletedAt, ok
+}
+
+// applyManifest menyamakan segment index dengan manifest m: bloom segment baru
+// dimuat (satu range read per segment), segment yang sudah tidak live dibuang.
+func (s *Store) applyManifest(m Manifest) {
+	s.segs.mu.RLock()
+	same := s.segs.version == m.Version
+	cur := s.segs.segs
+	s.segs.mu.RUnlock()
+	if same {
+		return
+	}
+	segs := make(map[string]*liveSegment, len(m.Segments))
+	for _, si := range m.Segments {
+		if ls, ok := cur[si.Name]; ok {
+			segs[si.Name] = ls
+			continue
+		}
+		ls := &liveSegment{info: si}
+		if si.BloomLen > 0 {
+			data, err := s.b.Get(segmentDir+"/"+si.Name, si.BloomOff, si.BloomLen)
+			if err != nil {
+				return // Coba lagi di refresh berikutnya; versi lama tetap dipakai
+			}
+			if ls.bloom, err = unmarshalBloomFilter(data); err != nil {
+				return
+			}
+		} else if _, err := s.segmentFooter(ls); err != nil {
+			return
+		}
+		segs[si.Name] = ls
+	}
+	s.segs.set(segs, m.Version)
+}
+
+// segmentFooter mengembalikan footer segment, memuatnya dari backend jika belum di-cache.
+func (s *Store) segmentFooter(ls *liveSegment) (map[string]segEntry, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.footer != nil {
+		return ls.footer, nil
+	}
+	data, err := s.b.Get(segmentDir+"/"+ls.info.Name, ls.info.IndexOff, ls.info.IndexLen)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := decodeSegmentFooter(data)
+	if err != nil {
+		return nil, err
+	}
+	ls.footer = footerMap(entries)
+	return ls.footer, nil
+}
+
+// lookupSegment mencari lokasi terbaru key di antara segment live.
+// Segment dicek dari MaxTS terbesar; segment yang bloom-nya menolak key dilewati
+// tanpa request ke backend. stale bernilai true jika file segment sudah hilang
+// (mis. sudah digabung compactor) sehingga index perlu dimuat ulang.
+func (s *Store) lookupSegment(key string) (loc segLoc, found, stale bool, err error) {
+	for _, ls := range s.segs.snapshot() {
+		if found && ls.info.MaxTS < loc.TS {
+			break
+		}
+		if ls.bloom != nil && !ls.bloom.mayContain(key) {
+			continue
+		}
+		footer, ferr := s.segmentFooter(ls)
+		if ferr != nil {
+			if errors.Is(ferr, ErrNotFound) {
+				stale = true
+			} else {
+				err = ferr
+			}
+			continue
+		}
+		e, ok := footer[key]
+		if !ok {
+			bloomFalsePositives.Inc()
+			continue
+		}
+		if !found || e.TS > loc.TS {
+			loc, found = segLoc{Seg: ls.info.Name, segEntry: e}, true
+		}
+	}
+	return loc, found, stale, err
+}
+
+// segmentKeys mengembalikan semua key di segment live beserta TS terbarunya (memuat semua footer).
+func (s *Store) segmentKeys() (map[string]int64, error) {
+	out := map[string]int64{}
+	for _, ls := range s.segs.snapshot() {
+		footer, err := s.segmentFooter(ls)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		for k, e := range footer {
+			if ts, ok := out[k]; !ok || e.TS > ts {
+				out[k] = e.TS
+			}
+		}
+	}
+	return out, nil
+}
+
+// readSegment membaca value satu key dari segment sesuai lokasi di index.
+func (s *Store) readSegment(loc segLoc) ([]byte, error) {
+	if loc.Len == 0 {
+		return []byte{}, nil
+	}
+	return s.b.Get(segmentDir+"/"+loc.Seg, loc.Off, loc.Len)
+}
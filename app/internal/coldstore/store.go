@@ -0,0 +1,420 @@
+package coldstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"monolith-kv-sim/internal/metricsx"
+)
+
+// Layout di dalam root backend:
+//
+//	overflow_<ms>_<seq>.jsonl      event overflow dari ingestor (JSONL)
+//	index/<overflow>.idx.jsonl     index key -> (file, offset, length, ts) per file overflow
+//	segments/seg_<ms>_<seq>.seg    value hasil offload dari Redis (banyak key per file + footer index)
+//	segments/MANIFEST-<ver>.json   daftar segment live (lihat Manifest)
+//	offloaded/<base64 key>.json    layout lama offload (satu file per key), masih dibaca
+//	tombstones/<base64 key>.json   tombstone key yang sudah dihapus
+var (
+	definiteMisses = metricsx.NewCounter("coldstore_definite_misses_total",
+		"Jumlah ReadByKey yang dijawab ErrNotFound oleh Bloom filter/index tanpa request ke backend")
+	expiredReads = metricsx.NewCounter("coldstore_expired_reads_total",
+		"Jumlah ReadByKey yang menemukan record tetapi sudah melewati TTL aslinya (dijawab ErrNotFound)")
+	bloomFalsePositives = metricsx.NewCounter("coldstore_bloom_false_positives_total",
+		"Jumlah Bloom filter segment yang menjawab \"mungkin ada\" padahal key tidak ada di footer")
+)
+
+const (
+	offloadDir   = "offloaded"
+	tombstoneDir = "tombstones"
+	indexDir     = "index"
+)
+
+// Store adalah implementasi ColdStore di atas Backend mana pun.
+// Semua aturan layout (index overflow, tombstone, "tulisan terbaru menang")
+// ada di sini, sehingga backend cukup menyediakan operasi blob dasar.
+type Store struct {
+	b Backend
+
+	// IndexRefresh adalah interval minimal antar refresh index overflow dari backend
+	// (untuk melihat file overflow yang ditulis instance ingestor lain).
+	IndexRefresh time.Duration
+	// BloomFPRate adalah target false positive rate Bloom filter per segment yang ditulis.
+	BloomFPRate float64
+
+	index *overflowIndex // key -> lokasi event terbaru di file overflow_*.jsonl
+	segs  *segmentIndex  // key -> lokasi value terbaru di segment live
+}
+
+// NewStore membuat Store di atas backend b.
+func NewStore(b Backend, indexRefresh time.Duration) *Store {
+	return &Store{b: b, IndexRefresh: indexRefresh, BloomFPRate: 0.01, index: newOverflowIndex(), segs: newSegmentIndex()}
+}
+
+// Name mengembalikan nama backend.
+func (s *Store) Name() string { return s.b.Name() }
+
+// Init menyiapkan backend (direktori root / bucket).
+func (s *Store) Init() error { return s.b.Init() }
+
+// WriteEvents menulis array events dalam format JSONL (JSON Lines).
+// Format JSONL: setiap event adalah satu baris JSON, cocok untuk big data processing.
+// Proses:
+// 1. Encode semua events ke buffer (JSONL), catat offset tiap baris untuk index
+// 2. Tulis buffer sebagai file overflow_<ms>_<seq>.jsonl
+// 3. Tulis index file tersebut ke subdir index/ agar event bisa dibaca lagi lewat ReadByKey
+// Event yang punya field "key" (mis. Event milik ingestor) otomatis ter-index.
+func (s *Store) WriteEvents(events []any) error {
+	// Nama file dengan timestamp + sequence untuk menghindari collision antar batch
+	ts := time.Now().UnixMilli()
+	name := fmt.Sprintf("overflow_%d_%d.jsonl", ts, overflowSeq.Add(1))
+
+	var buf bytes.Buffer
+	var entries []indexEntry
+	for _, ev := range events {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if key := eventKey(line); key != "" {
+			entries = append(entries, indexEntry{Key: key, File: name, Off: int64(buf.Len()), Len: int64(len(line)), TS: ts})
+		}
+		buf.Write(line)
+	}
+
+	if err := s.b.Put(name, buf.Bytes()); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	// Index lokal langsung di-update agar read berikutnya di instance ini tidak perlu refresh
+	s.index.merge(entries)
+	return s.writeIndexFile(name, entries)
+}
+
+// keyToSafeFileName mengubah key menjadi nama file yang aman untuk semua backend.
+// Menggunakan base64 URL encoding agar key dengan karakter khusus tetap unik.
+func keyToSafeFileName(key string) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString([]byte(key)), "=")
+}
+
+// safeFileNameToKey adalah kebalikan keyToSafeFileName (nama file tanpa ekstensi).
+func safeFileNameToKey(name string) (string, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// WriteKeyValue menulis satu pasangan key-value sebagai segment berisi satu record.
+// Untuk banyak key sekaligus (offloader) gunakan WriteKeyValues agar jumlah file tetap kecil.
+// Tombstone lama tidak perlu dihapus: record dengan TS lebih baru dari deleted_at otomatis menang.
+func (s *Store) WriteKeyValue(key string, value []byte) error {
+	return s.WriteKeyValues([]KeyValue{{Key: key, Value: value}})
+}
+
+// Delete menghapus key dari offloaded KV store.
+// Sebelum file dihapus, tombstone ditulis lebih dulu agar ReadByKey tidak
+// "menghidupkan" lagi value lama walaupun penghapusan file gagal di tengah jalan.
+// Instance lain baru melihat tombstone setelah refresh daftar tombstone; pemanggil
+// mengumumkannya lewat InvalidateChannel (lihat deleteHandler di ingestor).
+func (s *Store) Delete(key string) error {
+	safe := keyToSafeFileName(key)
+	if err := s.writeTombstone(key, time.Now().UnixMilli(), ""); err != nil {
+		return err
+	}
+	return s.b.Delete(offloadDir + "/" + safe + ".json")
+}
+
+// Supersede menandai semua salinan key di cold store dengan TS <= ts sebagai usang,
+// tanpa menyentuh data yang lebih baru. Dipakai offloader saat key ternyata sudah
+// ditimpa di Redis setelah value lamanya terlanjur ditulis ke segment: salinan lama
+// tidak boleh muncul lagi lewat ReadByKey jika value baru nanti hilang dari Redis.
+// Tombstone yang sudah ada dengan deleted_at >= ts dibiarkan.
+func (s *Store) Supersede(key string, ts int64) error {
+	if cur, ok := s.readTombstone(keyToSafeFileName(key)); ok && cur >= ts {
+		return nil
+	}
+	return s.writeTombstone(key, ts, "superseded")
+}
+
+// writeTombstone menulis tombstone key dengan waktu hapus deletedAt (unix ms).
+func (s *Store) writeTombstone(key string, deletedAt int64, reason string) error {
+	t := map[string]any{"key": key, "deleted_at": deletedAt}
+	if reason != "" {
+		t["reason"] = reason
+	}
+	tomb, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := s.b.Put(tombstoneDir+"/"+keyToSafeFileName(key)+".json", tomb); err != nil {
+		return err
+	}
+	s.segs.noteTombstone(key, deletedAt)
+	return nil
+}
+
+// ReadByKey membaca value untuk key.
+// Tiga layout dicek: segment live (hasil offloader, dicari lewat footer index),
+// file offloaded/<key>.json (layout lama) dan event di overflow_*.jsonl (hasil
+// overflow ingestor, dicari lewat index). Jika key ada di lebih dari satu tempat,
+// yang paling baru ditulis yang menang (_ts value offload vs waktu tulis overflow).
+// Mengembalikan ErrNotFound jika key tidak ada atau TTL aslinya sudah lewat, error backend
+// jika gagal baca, dan ErrDeleted jika key punya tombstone yang lebih baru dari data (dihapus lewat Delete).
+func (s *Store) ReadByKey(key string) ([]byte, error) {
+	rec, err := s.ReadRecord(key)
+	return rec.Value, err
+}
+
+// Record adalah value terbaru satu key di cold store beserta metadata-nya.
+type Record struct {
+	Value    []byte
+	TS       int64  // waktu data (unix ms), dasar aturan "tulisan terbaru menang"
+	ExpireAt int64  // waktu kedaluwarsa absolut (unix ms); 0 berarti tidak diketahui / tanpa expiry
+	Type     string // tipe Redis asal ("" = string; selain itu Value adalah typed envelope JSON)
+}
+
+// TTL mengembalikan sisa umur record relatif terhadap now (0 jika tanpa expiry).
+func (r Record) TTL(now time.Time) time.Duration {
+	if r.ExpireAt == 0 {
+		return 0
+	}
+	return time.UnixMilli(r.ExpireAt).Sub(now)
+}
+
+// ReadRecord sama seperti ReadByKey, tapi juga mengembalikan TS dan ExpireAt record.
+// Record yang sudah melewati ExpireAt dianggap tidak ada (ErrNotFound), sama seperti
+// key yang TTL-nya habis di Redis.
+func (s *Store) ReadRecord(key string) (Record, error) {
+	rec, stale, err := s.readByKey(key)
+	if stale {
+		// Index menunjuk ke file yang sudah dihapus compactor: muat ulang manifest/index lalu ulangi sekali
+		s.Invalidate()
+		rec, _, err = s.readByKey(key)
+	}
+	if err == nil && rec.ExpireAt > 0 && rec.ExpireAt <= time.Now().UnixMilli() {
+		expiredReads.Inc()
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// readByKey adalah satu percobaan ReadByKey. stale bernilai true jika lokasi dari
+// segment index atau overflow index ternyata sudah tidak ada di backend.
+// Jika bloom offloaded/, bloom semua segment, dan index overflow sama-sama menolak key,
+// ErrNotFound dikembalikan tanpa satu pun request ke backend.
+func (s *Store) readByKey(key string) (rec Record, stale bool, err error) {
+	safe := keyToSafeFileName(key)
+	var (
+		best    []byte
+		bestTS  int64 = -1
+		bestExp int64
+		bestTyp string
+		readErr error
+		touched bool
+	)
+	s.refreshSegments()
+	if s.segs.legacyMayContain(key) {
+		touched = true
+		out, err := s.b.Get(offloadDir+"/"+safe+".json", 0, 0)
+		if err == nil {
+			best, bestTS = out, valueTSMillis(out)
+		} else if !errors.Is(err, ErrNotFound) {
+			readErr = err
+		}
+	}
+
+	loc, found, segStale, err := s.lookupSegment(key)
+	stale = segStale
+	if err != nil {
+		touched, readErr = true, err
+	}
+	if found && loc.TS > bestTS {
+		touched = true
+		if val, err := s.readSegment(loc); err == nil {
+			best, bestTS, bestExp, bestTyp = val, loc.TS, loc.ExpireAt, loc.Type
+		} else if errors.Is(err, ErrNotFound) {
+			stale = true
+		} else {
+			readErr = err
+		}
+	}
+
+	s.refreshIndex()
+	if loc, ok := s.index.get(key); ok && loc.TS > bestTS {
+		touched = true
+		if val, exp, err := s.readOverflow(loc); err == nil {
+			best, bestTS, bestExp, bestTyp = val, loc.TS, exp, ""
+		} else if errors.Is(err, ErrNotFound) {
+			stale = true
+		}
+	}
+	if best == nil {
+		if !touched && !stale {
+			definiteMisses.Inc()
+		}
+		if readErr == nil {
+			readErr = ErrNotFound
+		}
+		return Record{}, stale, readErr
+	}
+
+	// Tombstone hanya berlaku untuk data yang ditulis sebelum key dihapus
+	if deletedAt, ok := s.tombstoneAt(key); ok && deletedAt >= bestTS {
+		return Record{}, stale, ErrDeleted
+	}
+	return Record{Value: best, TS: bestTS, ExpireAt: bestExp, Type: bestTyp}, stale, nil
+}
+
+// List mengembalikan key dengan prefix tertentu yang tersimpan di segment, offloaded/,
+// atau ter-index di file overflow. Key yang tombstone-nya lebih baru
+// dari datanya (sudah dihapus) tidak ikut.
+// Hasil bersifat "mungkin ada": ReadByKey tetap sumber kebenaran per key.
+func (s *Store) List(prefix string) ([]string, error) {
+	seen := map[string]bool{}
+	var keys []string
+	add := func(k string) {
+		if strings.HasPrefix(k, prefix) && !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+
+	names, err := s.b.List(offloadDir)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	for _, n := range names {
+		if k, ok := safeFileNameToKey(strings.TrimSuffix(n, ".json")); ok {
+			add(k)
+		}
+	}
+
+	tombs, err := s.b.List(tombstoneDir)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	deleted := map[string]bool{}
+	for _, n := range tombs {
+		if k, ok := safeFileNameToKey(strings.TrimSuffix(n, ".json")); ok {
+			deleted[k] = true
+		}
+	}
+
+	// Key bertombstone tetap ikut jika ditulis ulang setelah dihapus
+	alive := func(k string, ts int64) bool {
+		if !deleted[k] {
+			return true
+		}
+		deletedAt, ok := s.readTombstone(keyToSafeFileName(k))
+		return !ok || ts > deletedAt
+	}
+	s.refreshSegments()
+	segKeys, err := s.segmentKeys()
+	if err != nil {
+		return nil, err
+	}
+	for k, ts := range segKeys {
+		if alive(k, ts) {
+			add(k)
+		}
+	}
+	s.refreshIndex()
+	for _, k := range s.index.keys() {
+		if loc, ok := s.index.get(k); ok && alive(k, loc.TS) {
+			add(k)
+		}
+	}
+	return keys, nil
+}
+
+// readTombstone membaca waktu hapus (unix ms) dari tombstone key, jika ada.
+func (s *Store) readTombstone(safe string) (int64, bool) {
+	out, err := s.b.Get(tombstoneDir+"/"+safe+".json", 0, 0)
+	if err != nil {
+		return 0, false
+	}
+	var tomb struct {
+		DeletedAt int64 `json:"deleted_at"`
+	}
+	if err := json.Unmarshal(out, &tomb); err != nil {
+		// Tombstone rusak tetap dianggap hapus agar value lama tidak muncul lagi
+		return time.Now().UnixMilli(), true
+	}
+	return tomb.DeletedAt, true
+}
+
+// tsSecondsLimit memisahkan _ts dalam detik (value yang ditulis sebelum _ts memakai milidetik)
+// dari _ts dalam milidetik: 1e11 detik jatuh di tahun 5138, 1e11 ms di tahun 1973.
+const tsSecondsLimit = 1e11
+
+// ValueTS mengambil _ts dari value JSON (value string ingestor atau typed envelope hash)
+// dalam unix milidetik. _ts boleh berupa angka atau string angka (field hash); _ts lama dalam
+// detik dikonversi ke milidetik. ok false jika value tidak punya _ts yang valid.
+// Semua tier membandingkan waktu dalam milidetik (waktu tulis overflow, deleted_at tombstone),
+// jadi _ts detik membuat tulisan yang lebih baru dalam detik yang sama kalah.
+func ValueTS(val []byte) (ms int64, ok bool) {
+	var payload struct {
+		TS json.RawMessage `json:"_ts"`
+	}
+	if err := json.Unmarshal(val, &payload); err != nil || len(payload.TS) == 0 {
+		return 0, false
+	}
+	raw := string(payload.TS)
+	if s, err := strconv.Unquote(raw); err == nil {
+		raw = s
+	}
+	ts, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	if ts < tsSecondsLimit {
+		ts *= 1000
+	}
+	return int64(ts), true
+}
+
+// valueTSMillis adalah ValueTS untuk perbandingan antar record: value tanpa _ts dianggap paling lama (0).
+func valueTSMillis(val []byte) int64 {
+	ts, _ := ValueTS(val)
+	return ts
+}
+
+// TouchTS mengganti _ts (unix milidetik) di value dengan now. Dipakai setiap kali record cold store
+// ditulis kembali ke Redis (promosi read-through, rehydrate): offloader menghitung umur key dari
+// _ts, jadi tanpa ini key yang baru kembali ke Redis langsung dianggap "old" dan dipindah lagi
+// ke cold store di run berikutnya (dan setiap putaran menulis segment baru).
+// typ adalah Record.Type: untuk hash, _ts adalah field hash dan ditulis sebagai string.
+// Objek JSON tanpa _ts (mis. value lama atau ditulis client lain) diberi _ts, agar umurnya juga
+// bisa dihitung offloader. Value yang bukan objek JSON dan list/set/zset dikembalikan apa adanya.
+func TouchTS(typ string, val []byte, now time.Time) []byte {
+	if typ != "" && typ != "string" && typ != "hash" {
+		return val
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(val, &payload); err != nil || payload == nil {
+		return val
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	if typ == "hash" {
+		ts = strconv.Quote(ts)
+	}
+	payload["_ts"] = json.RawMessage(ts)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(payload); err != nil {
+		return val
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
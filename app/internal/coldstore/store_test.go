@@ -0,0 +1,428 @@
+package coldstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingBackend mencatat request ke backend, untuk memastikan miss dijawab Bloom filter.
+type countingBackend struct {
+	Backend
+	mu    sync.Mutex
+	gets  []string
+	lists []string
+
+	// beforePutIfAbsent dipanggil sekali sebelum PutIfAbsent pertama (mis. untuk menyisipkan penulis lain)
+	beforePutIfAbsent func(path string)
+}
+
+func (c *countingBackend) Get(p string, off, n int64) ([]byte, error) {
+	c.mu.Lock()
+	c.gets = append(c.gets, p)
+	c.mu.Unlock()
+	return c.Backend.Get(p, off, n)
+}
+
+func (c *countingBackend) List(dir string) ([]string, error) {
+	c.mu.Lock()
+	c.lists = append(c.lists, dir)
+	c.mu.Unlock()
+	return c.Backend.List(dir)
+}
+
+func (c *countingBackend) PutIfAbsent(p string, data []byte) error {
+	if f := c.beforePutIfAbsent; f != nil {
+		c.beforePutIfAbsent = nil
+		f(p)
+	}
+	return c.Backend.PutIfAbsent(p, data)
+}
+
+func (c *countingBackend) resetGets() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.gets
+	c.gets = nil
+	return out
+}
+
+// newTestStore membuat Store di atas localBackend di direktori sementara.
+// Store kedua di atas dir yang sama mensimulasikan instance lain (index in-memory kosong).
+func newTestStore(t *testing.T, dir string) (*Store, *countingBackend) {
+	t.Helper()
+	b := &countingBackend{Backend: &localBackend{root: dir}}
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(b, time.Hour), b
+}
+
+func TestSegmentRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := newTestStore(t, dir)
+	exp := time.Now().Add(time.Hour).UnixMilli()
+	kvs := []KeyValue{
+		{Key: "user:1", Value: []byte(`{"_ts":1700000000,"name":"a"}`), ExpireAt: exp},
+		{Key: "user:2", Value: []byte("raw bytes \x00\xff")},
+		{Key: "cart:1", Value: []byte(`{"sku":"2"}`), Type: "hash", TS: 42},
+		{Key: "empty", Value: []byte{}},
+	}
+	if err := w.WriteKeyValues(kvs); err != nil {
+		t.Fatalf("WriteKeyValues: %v", err)
+	}
+	if kvs[0].TS != 1700000000*1000 || kvs[2].TS != 42 || kvs[1].TS == 0 {
+		t.Fatalf("TS not filled from _ts / kept: %+v", kvs)
+	}
+
+	// Format file: trailer menunjuk ke footer, bloom tercatat di manifest
+	m, err := w.LoadManifest()
+	if err != nil || m.Version != 1 || len(m.Segments) != 1 {
+		t.Fatalf("manifest = %+v, %v", m, err)
+	}
+	si := m.Segments[0]
+	data, err := w.b.Get(segmentDir+"/"+si.Name, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != si.Size || !bytes.HasSuffix(data, segmentMagic) || si.BloomLen == 0 || si.Count != len(kvs) {
+		t.Fatalf("segment %s: size=%d info=%+v", si.Name, len(data), si)
+	}
+	recs, err := w.readSegmentRecords(si)
+	if err != nil || len(recs) != len(kvs) {
+		t.Fatalf("readSegmentRecords = %d records, %v", len(recs), err)
+	}
+
+	// Instance lain membaca lewat manifest + bloom + footer
+	r, b := newTestStore(t, dir)
+	for _, kv := range kvs {
+		rec, err := r.ReadRecord(kv.Key)
+		if err != nil {
+			t.Fatalf("ReadRecord(%q): %v", kv.Key, err)
+		}
+		if !bytes.Equal(rec.Value, kv.Value) || rec.TS != kv.TS || rec.ExpireAt != kv.ExpireAt || rec.Type != kv.Type {
+			t.Fatalf("ReadRecord(%q) = %+v, want %+v", kv.Key, rec, kv)
+		}
+	}
+
+	// Miss dijawab bloom: tidak ada Get ke file segment
+	b.resetGets()
+	for i := 0; i < 50; i++ {
+		if _, err := r.ReadRecord(fmt.Sprintf("missing:%d", i)); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("ReadRecord(missing) = %v, want ErrNotFound", err)
+		}
+	}
+	segGets := 0
+	for _, p := range b.resetGets() {
+		if strings.HasPrefix(p, segmentDir+"/seg_") {
+			segGets++
+		}
+	}
+	// Target false positive 1%: 50 miss hampir selalu 0 read segment; beri ruang untuk beberapa
+	if segGets > 5 {
+		t.Fatalf("%d segment reads for 50 misses, want bloom to answer them", segGets)
+	}
+}
+
+func TestExpiredRecordIsNotFound(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	if err := s.WriteKeyValues([]KeyValue{{Key: "k", Value: []byte("v"), ExpireAt: time.Now().Add(-time.Second).UnixMilli()}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadRecord("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadRecord(expired) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCommitManifestRetriesOnConflict(t *testing.T) {
+	dir := t.TempDir()
+	s, b := newTestStore(t, dir)
+	other, _ := newTestStore(t, dir)
+
+	// PutIfAbsent localBackend: path yang sudah ada ditolak dengan ErrExists
+	if err := b.PutIfAbsent("x", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutIfAbsent("x", []byte("2")); !errors.Is(err, ErrExists) {
+		t.Fatalf("second PutIfAbsent = %v, want ErrExists", err)
+	}
+
+	// Penulis lain meng-commit manifest versi yang sama tepat sebelum s menulisnya
+	b.beforePutIfAbsent = func(p string) {
+		if !strings.Contains(p, manifestPrefix) {
+			t.Errorf("unexpected PutIfAbsent %s", p)
+		}
+		if err := other.WriteKeyValues([]KeyValue{{Key: "from-other", Value: []byte("o")}}); err != nil {
+			t.Errorf("other writer: %v", err)
+		}
+	}
+	if err := s.WriteKeyValues([]KeyValue{{Key: "from-s", Value: []byte("s")}}); err != nil {
+		t.Fatalf("WriteKeyValues with conflicting commit: %v", err)
+	}
+
+	m, err := s.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 2 || len(m.Segments) != 2 {
+		t.Fatalf("manifest after conflict = %+v, want v2 with both segments", m)
+	}
+	fresh, _ := newTestStore(t, dir)
+	for _, k := range []string{"from-s", "from-other"} {
+		if _, err := fresh.ReadRecord(k); err != nil {
+			t.Fatalf("ReadRecord(%q) after conflict: %v", k, err)
+		}
+	}
+}
+
+func TestTombstoneHidesOlderValue(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newTestStore(t, dir)
+	past := time.Now().Add(-time.Minute).UnixMilli()
+	if err := s.WriteKeyValues([]KeyValue{
+		{Key: "gone", Value: []byte("old"), TS: past},
+		{Key: "kept", Value: []byte("v"), TS: past},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("gone"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadRecord("gone"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("ReadRecord after Delete = %v, want ErrDeleted", err)
+	}
+	// Instance lain juga melihat tombstone
+	other, _ := newTestStore(t, dir)
+	if _, err := other.ReadRecord("gone"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("other instance: ReadRecord after Delete = %v, want ErrDeleted", err)
+	}
+	keys, err := s.List("")
+	if err != nil || len(keys) != 1 || keys[0] != "kept" {
+		t.Fatalf("List = %v, %v; want only kept", keys, err)
+	}
+
+	// Data yang ditulis setelah delete menang atas tombstone
+	if err := s.WriteKeyValues([]KeyValue{{Key: "gone", Value: []byte("new"), TS: time.Now().Add(time.Second).UnixMilli()}}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := s.ReadRecord("gone")
+	if err != nil || string(rec.Value) != "new" {
+		t.Fatalf("ReadRecord after rewrite = %q, %v", rec.Value, err)
+	}
+
+	// Supersede hanya menyembunyikan salinan dengan TS <= ts
+	if err := s.Supersede("kept", past); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadRecord("kept"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("ReadRecord after Supersede = %v, want ErrDeleted", err)
+	}
+	if err := s.Supersede("gone", past); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err := s.ReadRecord("gone"); err != nil || string(rec.Value) != "new" {
+		t.Fatalf("Supersede hid newer copy: %q, %v", rec.Value, err)
+	}
+}
+
+// Hit di cold tier tidak membaca tombstone dari backend kecuali key ada di daftar tombstones/,
+// dan offloaded/ (layout lama, tidak pernah bertambah) hanya di-list sekali.
+func TestColdHitUsesTombstoneIndex(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newTestStore(t, dir)
+	if err := s.WriteKeyValues([]KeyValue{{Key: "k", Value: []byte("v"), TS: 100}, {Key: "other", Value: []byte("v"), TS: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	r, b := newTestStore(t, dir)
+	r.IndexRefresh = 0 // refresh di setiap read
+	countGets := func(prefix string) int {
+		n := 0
+		for _, p := range b.resetGets() {
+			if strings.HasPrefix(p, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.ReadRecord("k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countGets(tombstoneDir); n != 0 {
+		t.Fatalf("%d tombstone reads for cold hits without tombstone", n)
+	}
+
+	if err := s.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	r.IndexRefresh = time.Hour
+	r.Invalidate() // pengumuman lewat InvalidateChannel
+	for i := 0; i < 3; i++ {
+		if _, err := r.ReadRecord("k"); !errors.Is(err, ErrDeleted) {
+			t.Fatalf("ReadRecord after Delete = %v, want ErrDeleted", err)
+		}
+	}
+	if _, err := r.ReadRecord("other"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countGets(tombstoneDir); n != 1 {
+		t.Fatalf("%d tombstone reads, want deleted_at read once and cached", n)
+	}
+
+	// Tombstone yang ditulis instance ini langsung berlaku tanpa refresh
+	if err := r.Supersede("other", 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadRecord("other"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("ReadRecord after local Supersede = %v, want ErrDeleted", err)
+	}
+
+	legacyLists := 0
+	for _, d := range b.lists {
+		if d == offloadDir {
+			legacyLists++
+		}
+	}
+	if legacyLists != 1 {
+		t.Fatalf("offloaded/ listed %d times across refreshes, want once", legacyLists)
+	}
+}
+
+func TestLegacyFileFallback(t *testing.T) {
+	dir := t.TempDir()
+	s, b := newTestStore(t, dir)
+	put := func(key, val string) {
+		t.Helper()
+		if err := b.Put(offloadDir+"/"+keyToSafeFileName(key)+".json", []byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("legacy:only", `{"_ts":1600000000,"v":"legacy"}`)
+	put("legacy:older", `{"_ts":1600000000,"v":"legacy"}`)
+	put("legacy:deleted", `{"_ts":1600000000,"v":"legacy"}`)
+	if err := s.WriteKeyValues([]KeyValue{{Key: "legacy:older", Value: []byte(`{"_ts":1700000000,"v":"segment"}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	// Delete yang gagal menghapus file lama setelah tombstone ditulis: tombstone tetap menyembunyikannya
+	if err := s.writeTombstone("legacy:deleted", time.Now().UnixMilli(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := newTestStore(t, dir)
+	rec, err := r.ReadRecord("legacy:only")
+	if err != nil || rec.TS != 1600000000*1000 || !strings.Contains(string(rec.Value), "legacy") {
+		t.Fatalf("ReadRecord(legacy:only) = %+v, %v", rec, err)
+	}
+	if rec, err := r.ReadRecord("legacy:older"); err != nil || !strings.Contains(string(rec.Value), "segment") {
+		t.Fatalf("newer segment should win over legacy file: %q, %v", rec.Value, err)
+	}
+	if _, err := r.ReadRecord("legacy:deleted"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("ReadRecord(legacy:deleted) = %v, want ErrDeleted", err)
+	}
+	if _, err := r.ReadRecord("legacy:missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ReadRecord(legacy:missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestOverflowEventsReadByKey(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newTestStore(t, dir)
+	events := []any{
+		map[string]any{"key": "ev:1", "value": map[string]any{"a": 1}, "ttl_sec": 3600},
+		map[string]any{"no_key": true},
+	}
+	if err := s.WriteEvents(events); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := newTestStore(t, dir)
+	rec, err := r.ReadRecord("ev:1")
+	if err != nil || string(rec.Value) != `{"a":1}` || rec.ExpireAt != rec.TS+3600*1000 {
+		t.Fatalf("ReadRecord(ev:1) = %+v, %v", rec, err)
+	}
+}
+
+func TestValueTS(t *testing.T) {
+	for _, tc := range []struct {
+		val  string
+		want int64
+		ok   bool
+	}{
+		{`{"_ts":1700000000123}`, 1700000000123, true},
+		{`{"_ts":"1700000000123"}`, 1700000000123, true}, // field hash
+		{`{"_ts":1700000000}`, 1700000000000, true},      // _ts lama dalam detik
+		{`{"_ts":"1700000000"}`, 1700000000000, true},
+		{`{"_ts":1700000000.5}`, 1700000000500, true},
+		{`{"_ts":null}`, 0, false},
+		{`{"_ts":"x"}`, 0, false},
+		{`{"v":1}`, 0, false},
+		{`["a"]`, 0, false},
+		{`plain`, 0, false},
+	} {
+		got, ok := ValueTS([]byte(tc.val))
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ValueTS(%s) = %d, %t; want %d, %t", tc.val, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// Key yang dihapus lalu ditulis ulang dan di-offload dalam detik yang sama dengan DELETE
+// tidak boleh dianggap terhapus: _ts dan deleted_at sama-sama milidetik.
+func TestRewriteInSameSecondAsDeleteWins(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	if err := s.WriteKeyValues([]KeyValue{{Key: "k", Value: []byte(`{"v":"old"}`), TS: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	tombs, err := s.loadTombstones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := tombs["k"].deletedAt
+	// Ingest ulang 1 ms setelah DELETE (sebelum perbaikan _ts dibulatkan ke awal detik)
+	val := fmt.Sprintf(`{"v":"new","_ts":%d}`, deletedAt+1)
+	if err := s.WriteKeyValues([]KeyValue{{Key: "k", Value: []byte(val)}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.ReadByKey("k"); err != nil || string(got) != val {
+		t.Fatalf("ReadByKey = %s, %v; want %s", got, err, val)
+	}
+}
+
+// Value yang ditulis ke Redis sesudah sebuah overflow (detik yang sama) lalu di-offload
+// harus menang atas salinan overflow yang lebih lama.
+func TestOffloadedValueNewerThanOverflowWins(t *testing.T) {
+	s, _ := newTestStore(t, t.TempDir())
+	if err := s.WriteEvents([]any{map[string]any{"key": "k", "value": map[string]any{"v": "overflow"}}}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := s.ReadRecord("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := fmt.Sprintf(`{"v":"redis","_ts":%d}`, rec.TS+1)
+	if err := s.WriteKeyValues([]KeyValue{{Key: "k", Value: []byte(val)}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.ReadByKey("k"); err != nil || string(got) != val {
+		t.Fatalf("ReadByKey = %s, %v; want %s", got, err, val)
+	}
+
+	// Sebaliknya overflow yang lebih baru menang atas value offload yang lebih lama
+	old := fmt.Sprintf(`{"v":"older","_ts":%d}`, rec.TS-1)
+	if err := s.WriteKeyValues([]KeyValue{{Key: "j", Value: []byte(old)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteEvents([]any{map[string]any{"key": "j", "value": map[string]any{"v": "overflow"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.ReadByKey("j"); err != nil || string(got) != `{"v":"overflow"}` {
+		t.Fatalf("ReadByKey(j) = %s, %v", got, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package flight menggabungkan pemanggilan bersamaan untuk key yang sama (singleflight),
+// dipakai jalur baca ingestor dan endpoint laporan offloader.
+package flight
+
+import (
+	"errors"
+	"sync"
+)
+
+// errPanicked dikembalikan ke pemanggil yang menunggu jika fn milik pemanggil pertama panic.
+var errPanicked = errors.New("flight: in-flight call panicked")
+
+// Group menggabungkan pemanggilan bersamaan untuk key yang sama (singleflight): selama fetch
+// sebuah key masih berjalan, pemanggil lain untuk key itu menunggu dan memakai hasil yang sama
+// alih-alih menjalankan fetch sendiri. Mencegah thundering herd ke Redis/cold store ketika
+// key yang hot baru saja hilang dari cache. Zero value siap dipakai.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+}
+
+type call[T any] struct {
+	wg  sync.WaitGroup
+	val T
+	err error
+}
+
+// Do menjalankan fn untuk key, atau menunggu hasil fn yang sedang berjalan untuk key yang sama.
+// shared bernilai true jika hasilnya berasal dari pemanggilan lain.
+func (g *Group[T]) Do(key string, fn func() (T, error)) (v T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[T]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call[T]{err: errPanicked}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
+
+// Forget melepas fetch key yang sedang berjalan dari Group: pemanggil berikutnya menjalankan
+// fetch baru (pemanggil yang sudah menunggu tetap memakai hasil lama). Dipanggil setelah key
+// ditulis/dihapus agar read setelah write tidak ikut memakai fetch yang dimulai sebelum write.
+func (g *Group[T]) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
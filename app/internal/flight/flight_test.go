@@ -0,0 +1,133 @@
+package flight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Pemanggil bersamaan untuk key yang sama menjalankan fn sekali dan berbagi hasilnya.
+func TestDoCoalescesConcurrentCalls(t *testing.T) {
+	var g Group[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (string, error) {
+		calls.Add(1)
+		<-release
+		return "v", nil
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	vals := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err, s := g.Do("k", fn)
+			if err != nil {
+				t.Error(err)
+			}
+			vals[i] = v
+			if s {
+				shared.Add(1)
+			}
+		}(i)
+	}
+	// Beri waktu semua goroutine bergabung ke call yang sedang berjalan
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if c := calls.Load(); c != 1 {
+		t.Fatalf("fn called %d times, want 1", c)
+	}
+	if s := shared.Load(); s != n-1 {
+		t.Fatalf("shared = %d, want %d", s, n-1)
+	}
+	for i, v := range vals {
+		if v != "v" {
+			t.Fatalf("caller %d got %q", i, v)
+		}
+	}
+}
+
+// Setelah call selesai, Do berikutnya menjalankan fn lagi; error ikut dibagi seperti value.
+func TestDoRunsAgainAfterCompletion(t *testing.T) {
+	var g Group[int]
+	boom := errors.New("boom")
+	v, err, shared := g.Do("k", func() (int, error) { return 1, boom })
+	if v != 1 || err != boom || shared {
+		t.Fatalf("Do = %d, %v, %v", v, err, shared)
+	}
+	v, err, shared = g.Do("k", func() (int, error) { return 2, nil })
+	if v != 2 || err != nil || shared {
+		t.Fatalf("second Do = %d, %v, %v", v, err, shared)
+	}
+}
+
+// Forget melepas call yang sedang berjalan: pemanggil berikutnya menjalankan fn baru,
+// sedangkan pemanggil pertama tetap mendapat hasil lamanya.
+func TestForget(t *testing.T) {
+	var g Group[string]
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, _, _ := g.Do("k", func() (string, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+		done <- v
+	}()
+	<-started
+
+	g.Forget("k")
+	v, _, shared := g.Do("k", func() (string, error) { return "new", nil })
+	if v != "new" || shared {
+		t.Fatalf("Do after Forget = %q (shared %v), want a fresh call", v, shared)
+	}
+	close(release)
+	if v := <-done; v != "old" {
+		t.Fatalf("first caller got %q, want old", v)
+	}
+}
+
+// Panic di fn diteruskan ke pemanggil pertama; pemanggil yang menunggu mendapat errPanicked
+// dan key tidak tertinggal di Group.
+func TestDoPanic(t *testing.T) {
+	var g Group[string]
+	started, release := make(chan struct{}), make(chan struct{})
+	recovered := make(chan any)
+	go func() {
+		defer func() { recovered <- recover() }()
+		g.Do("k", func() (string, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err, _ := g.Do("k", func() (string, error) { return "", errors.New("waiter ran its own call") })
+		waiter <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if p := <-recovered; p != "boom" {
+		t.Fatalf("first caller recovered %v, want boom", p)
+	}
+	if err := <-waiter; err != errPanicked {
+		t.Fatalf("waiter err = %v, want errPanicked", err)
+	}
+	v, err, shared := g.Do("k", func() (string, error) { return "v", nil })
+	if v != "v" || err != nil || shared {
+		t.Fatalf("Do after panic = %q, %v, %v", v, err, shared)
+	}
+}
@@ -0,0 +1,326 @@
+package hdfsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound cocok (errors.Is) dengan RemoteError untuk file/direktori yang tidak ada.
+	ErrNotFound = errors.New("hdfsx: not found")
+	// ErrExists cocok dengan RemoteError saat CREATE tanpa overwrite ke path yang sudah ada.
+	ErrExists = errors.New("hdfsx: already exists")
+)
+
+// Client adalah client WebHDFS/HttpFS (REST API HDFS) murni Go.
+// Menggantikan shell-out "hdfs dfs" sehingga tidak perlu JVM/Hadoop di container,
+// koneksi HTTP di-reuse antar request, dan path tidak pernah diinterpolasi ke shell.
+// BaseURL dan HTTP bisa diganti (mis. ke httptest.Server) untuk pengujian.
+type Client struct {
+	BaseURL string       // mis. http://namenode:9870 (tanpa /webhdfs/v1)
+	User    string       // user.name untuk simple auth (pseudo authentication)
+	HTTPFS  bool         // true jika BaseURL adalah HttpFS (upload satu langkah dengan data=true)
+	HTTP    *http.Client // HTTP client (timeout + connection pool)
+}
+
+// NewClient membuat Client dari environment variable:
+// WEBHDFS_URL (default http://namenode:9870), HDFS_USER (default root),
+// HDFS_HTTPFS=1 untuk mode HttpFS, WEBHDFS_TIMEOUT_SECONDS (default 10).
+func NewClient() *Client {
+	base := os.Getenv("WEBHDFS_URL")
+	if base == "" {
+		base = "http://namenode:9870"
+	}
+	user := os.Getenv("HDFS_USER")
+	if user == "" {
+		user = "root"
+	}
+	timeout := 10
+	if s := os.Getenv("WEBHDFS_TIMEOUT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			timeout = v
+		}
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(base, "/"),
+		User:    user,
+		HTTPFS:  os.Getenv("HDFS_HTTPFS") == "1",
+		HTTP: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				DialContext:         (&net.Dialer{Timeout: 2 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+				MaxIdleConns:        64,
+				MaxIdleConnsPerHost: 16, // Namenode + datanode: reuse koneksi untuk banyak request kecil
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// Redirect (namenode -> datanode) ditangani manual di do() agar body upload
+			// hanya dikirim ke datanode, sesuai protokol dua langkah WebHDFS.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// RemoteError adalah error dari server WebHDFS (RemoteException di body JSON).
+// errors.Is(err, ErrNotFound) bernilai true untuk FileNotFoundException / HTTP 404.
+type RemoteError struct {
+	StatusCode    int
+	Op            string
+	Path          string
+	Exception     string
+	JavaClassName string
+	Message       string
+}
+
+func (e *RemoteError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Exception != "" {
+		msg = e.Exception + ": " + msg
+	}
+	return fmt.Sprintf("webhdfs %s %s: %d %s", e.Op, e.Path, e.StatusCode, msg)
+}
+
+// Is membuat RemoteError "file tidak ada" cocok dengan ErrNotFound
+// dan "file sudah ada" cocok dengan ErrExists.
+func (e *RemoteError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.Exception == "FileNotFoundException"
+	case ErrExists:
+		return e.Exception == "FileAlreadyExistsException"
+	}
+	return false
+}
+
+// FileStatus adalah metadata file/direktori dari LISTSTATUS / GETFILESTATUS.
+type FileStatus struct {
+	PathSuffix       string `json:"pathSuffix"`
+	Type             string `json:"type"` // "FILE" atau "DIRECTORY"
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"` // unix ms
+}
+
+// Mkdirs membuat direktori beserta parent-nya (seperti mkdir -p).
+func (c *Client) Mkdirs(path string) error {
+	return c.doBool(http.MethodPut, path, "MKDIRS", nil)
+}
+
+// Create menulis file baru (atau menimpa jika overwrite) dengan isi data.
+func (c *Client) Create(path string, data []byte, overwrite bool) error {
+	resp, err := c.do(http.MethodPut, path, "CREATE", url.Values{"overwrite": {strconv.FormatBool(overwrite)}}, data)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+// Append menambahkan data di akhir file yang sudah ada.
+func (c *Client) Append(path string, data []byte) error {
+	resp, err := c.do(http.MethodPost, path, "APPEND", nil, data)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+// Open membaca isi file. length <= 0 berarti baca sampai akhir file.
+func (c *Client) Open(path string, offset, length int64) ([]byte, error) {
+	q := url.Values{}
+	if offset > 0 {
+		q.Set("offset", strconv.FormatInt(offset, 10))
+	}
+	if length > 0 {
+		q.Set("length", strconv.FormatInt(length, 10))
+	}
+	resp, err := c.do(http.MethodGet, path, "OPEN", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Delete menghapus file/direktori. Path yang tidak ada tidak dianggap error
+// (WebHDFS membalas {"boolean": false} untuk path yang tidak ada).
+func (c *Client) Delete(path string, recursive bool) error {
+	resp, err := c.do(http.MethodDelete, path, "DELETE", url.Values{"recursive": {strconv.FormatBool(recursive)}}, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+// Rename memindahkan src ke dst (atomic di namenode). Gagal jika dst sudah ada.
+func (c *Client) Rename(src, dst string) error {
+	return c.doBool(http.MethodPut, src, "RENAME", url.Values{"destination": {dst}})
+}
+
+// ListStatus mengembalikan isi direktori.
+func (c *Client) ListStatus(dir string) ([]FileStatus, error) {
+	resp, err := c.do(http.MethodGet, dir, "LISTSTATUS", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		FileStatuses struct {
+			FileStatus []FileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.FileStatuses.FileStatus, nil
+}
+
+// GetFileStatus mengembalikan metadata satu path.
+func (c *Client) GetFileStatus(path string) (FileStatus, error) {
+	resp, err := c.do(http.MethodGet, path, "GETFILESTATUS", nil, nil)
+	if err != nil {
+		return FileStatus{}, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		FileStatus FileStatus `json:"FileStatus"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&out)
+	return out.FileStatus, err
+}
+
+// doBool menjalankan operasi yang membalas {"boolean": true|false}.
+// Nilai false dianggap error (mis. RENAME ke tujuan yang sudah ada).
+func (c *Client) doBool(method, path, op string, q url.Values) error {
+	resp, err := c.do(method, path, op, q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Boolean bool `json:"boolean"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	if !out.Boolean {
+		return &RemoteError{StatusCode: resp.StatusCode, Op: op, Path: path, Message: "operation returned false"}
+	}
+	return nil
+}
+
+// do mengirim satu request WebHDFS dan mengembalikan response 2xx.
+// Untuk CREATE/APPEND/OPEN namenode membalas 307 ke datanode; request diulang
+// ke Location tersebut (dengan body untuk upload). Di mode HttpFS upload
+// dikirim langsung dengan data=true tanpa redirect.
+func (c *Client) do(method, path, op string, q url.Values, body []byte) (*http.Response, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("op", op)
+	if c.User != "" {
+		q.Set("user.name", c.User)
+	}
+	upload := op == "CREATE" || op == "APPEND"
+	if upload && c.HTTPFS {
+		q.Set("data", "true")
+	}
+	u := c.BaseURL + "/webhdfs/v1" + escapePath(path) + "?" + q.Encode()
+
+	var first []byte
+	if upload && c.HTTPFS {
+		first = body
+	}
+	resp, err := c.send(method, u, first, upload)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusFound {
+		loc := resp.Header.Get("Location")
+		_ = drain(resp)
+		if loc == "" {
+			return nil, &RemoteError{StatusCode: resp.StatusCode, Op: op, Path: path, Message: "redirect without Location"}
+		}
+		if resp, err = c.send(method, loc, body, upload); err != nil {
+			return nil, err
+		}
+	} else if upload && !c.HTTPFS && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		// Namenode WebHDFS wajib redirect untuk upload; tanpa redirect data tidak pernah terkirim
+		_ = drain(resp)
+		return nil, &RemoteError{StatusCode: resp.StatusCode, Op: op, Path: path, Message: "expected redirect to datanode (set HDFS_HTTPFS=1 for HttpFS)"}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, remoteError(resp, op, path)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(method, u string, body []byte, upload bool) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if upload {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return c.HTTP.Do(req)
+}
+
+// remoteError membaca RemoteException dari body response error.
+func remoteError(resp *http.Response, op, path string) error {
+	defer resp.Body.Close()
+	e := &RemoteError{StatusCode: resp.StatusCode, Op: op, Path: path}
+	var out struct {
+		RemoteException struct {
+			Exception     string `json:"exception"`
+			JavaClassName string `json:"javaClassName"`
+			Message       string `json:"message"`
+		} `json:"RemoteException"`
+	}
+	if b, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil {
+		if json.Unmarshal(b, &out) == nil {
+			e.Exception = out.RemoteException.Exception
+			e.JavaClassName = out.RemoteException.JavaClassName
+			e.Message = out.RemoteException.Message
+		} else {
+			e.Message = strings.TrimSpace(string(b))
+		}
+	}
+	return e
+}
+
+// drain membuang sisa body agar koneksi bisa di-reuse oleh Transport.
+func drain(resp *http.Response) error {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// escapePath meng-escape setiap segmen path HDFS untuk dipakai di URL.
+func escapePath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
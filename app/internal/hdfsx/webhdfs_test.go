@@ -0,0 +1,315 @@
+package hdfsx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeHDFS adalah namenode + datanode WebHDFS minimal di atas httptest.
+// Namenode membalas CREATE/APPEND/OPEN dengan 307 ke datanode (protokol dua langkah);
+// operasi metadata dijawab langsung oleh namenode.
+type fakeHDFS struct {
+	t        *testing.T
+	mu       sync.Mutex
+	files    map[string][]byte
+	nnBodies int // byte body upload yang (salah) terkirim ke namenode
+	ops      []string
+	namenode *httptest.Server
+	datanode *httptest.Server
+}
+
+func newFakeHDFS(t *testing.T) *fakeHDFS {
+	f := &fakeHDFS{t: t, files: map[string][]byte{}}
+	f.namenode = httptest.NewServer(http.HandlerFunc(f.serveNamenode))
+	f.datanode = httptest.NewServer(http.HandlerFunc(f.serveDatanode))
+	t.Cleanup(func() {
+		f.namenode.Close()
+		f.datanode.Close()
+	})
+	return f
+}
+
+func (f *fakeHDFS) client() *Client {
+	return &Client{
+		BaseURL: f.namenode.URL,
+		User:    "tester",
+		HTTP: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+func remoteException(w http.ResponseWriter, status int, exception, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"RemoteException":{"exception":%q,"javaClassName":"org.apache.hadoop.%s","message":%q}}`, exception, exception, msg)
+}
+
+func fsPath(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/webhdfs/v1") }
+
+func (f *fakeHDFS) serveNamenode(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	op := q.Get("op")
+	if q.Get("user.name") != "tester" {
+		f.t.Errorf("%s: user.name = %q", op, q.Get("user.name"))
+	}
+	body, _ := io.ReadAll(r.Body)
+	p := fsPath(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ops = append(f.ops, r.Method+" "+op+" "+p)
+	f.nnBodies += len(body)
+	switch op {
+	case "CREATE", "APPEND", "OPEN":
+		w.Header().Set("Location", f.datanode.URL+r.URL.RequestURI())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	case "LISTSTATUS":
+		prefix := strings.TrimSuffix(p, "/") + "/"
+		var names []string
+		for name := range f.files {
+			if strings.HasPrefix(name, prefix) && !strings.Contains(name[len(prefix):], "/") {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			remoteException(w, http.StatusNotFound, "FileNotFoundException", "File "+p+" does not exist.")
+			return
+		}
+		sort.Strings(names)
+		var sts []FileStatus
+		for _, name := range names {
+			sts = append(sts, FileStatus{PathSuffix: name[len(prefix):], Type: "FILE", Length: int64(len(f.files[name]))})
+		}
+		var out struct {
+			FileStatuses struct {
+				FileStatus []FileStatus `json:"FileStatus"`
+			} `json:"FileStatuses"`
+		}
+		out.FileStatuses.FileStatus = sts
+		_ = json.NewEncoder(w).Encode(out)
+	case "DELETE":
+		_, ok := f.files[p]
+		delete(f.files, p)
+		fmt.Fprintf(w, `{"boolean":%t}`, ok)
+	case "MKDIRS":
+		fmt.Fprint(w, `{"boolean":true}`)
+	default:
+		remoteException(w, http.StatusBadRequest, "IllegalArgumentException", "Invalid value for webhdfs parameter \"op\"")
+	}
+}
+
+func (f *fakeHDFS) serveDatanode(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	p := fsPath(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cur, exists := f.files[p]
+	switch q.Get("op") {
+	case "CREATE":
+		if exists && q.Get("overwrite") != "true" {
+			remoteException(w, http.StatusForbidden, "FileAlreadyExistsException", p+" for client already exists")
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/octet-stream" {
+			f.t.Errorf("CREATE content type = %q", r.Header.Get("Content-Type"))
+		}
+		f.files[p] = body
+		w.WriteHeader(http.StatusCreated)
+	case "APPEND":
+		if !exists {
+			remoteException(w, http.StatusNotFound, "FileNotFoundException", "File does not exist: "+p)
+			return
+		}
+		f.files[p] = append(cur, body...)
+	case "OPEN":
+		if !exists {
+			remoteException(w, http.StatusNotFound, "FileNotFoundException", "File does not exist: "+p)
+			return
+		}
+		off, _ := strconv.ParseInt(q.Get("offset"), 10, 64)
+		if off > int64(len(cur)) {
+			off = int64(len(cur))
+		}
+		data := cur[off:]
+		if n, err := strconv.ParseInt(q.Get("length"), 10, 64); err == nil && n < int64(len(data)) {
+			data = data[:n]
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestCreateAndOpenFollowRedirect(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+
+	if err := c.Create("/data/a b.jsonl", []byte("hello world"), false); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if f.nnBodies != 0 {
+		t.Fatalf("upload body sent to namenode (%d bytes), want only to datanode", f.nnBodies)
+	}
+	if got := string(f.files["/data/a b.jsonl"]); got != "hello world" {
+		t.Fatalf("stored %q", got)
+	}
+
+	got, err := c.Open("/data/a b.jsonl", 0, 0)
+	if err != nil || string(got) != "hello world" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	got, err = c.Open("/data/a b.jsonl", 6, 3)
+	if err != nil || string(got) != "wor" {
+		t.Fatalf("Open(6, 3) = %q, %v", got, err)
+	}
+}
+
+func TestCreateExistingMapsToErrExists(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+	if err := c.Create("/d/f", []byte("v1"), false); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Create("/d/f", []byte("v2"), false)
+	if !errors.Is(err, ErrExists) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Create without overwrite on existing file: %v, want ErrExists", err)
+	}
+	var re *RemoteError
+	if !errors.As(err, &re) || re.Exception != "FileAlreadyExistsException" || re.StatusCode != http.StatusForbidden {
+		t.Fatalf("RemoteError = %+v", re)
+	}
+	if err := c.Create("/d/f", []byte("v2"), true); err != nil {
+		t.Fatalf("Create with overwrite: %v", err)
+	}
+	if got := string(f.files["/d/f"]); got != "v2" {
+		t.Fatalf("after overwrite: %q", got)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+	if err := c.Create("/log", []byte("a\n"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Append("/log", []byte("b\n")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if f.nnBodies != 0 {
+		t.Fatalf("append body sent to namenode (%d bytes)", f.nnBodies)
+	}
+	if got := string(f.files["/log"]); got != "a\nb\n" {
+		t.Fatalf("after append: %q", got)
+	}
+	if err := c.Append("/missing", []byte("x")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Append to missing file: %v, want ErrNotFound", err)
+	}
+}
+
+func TestListStatus(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+	for _, p := range []string{"/root/b.seg", "/root/a.seg", "/root/sub/c.seg"} {
+		if err := c.Create(p, []byte(p), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sts, err := c.ListStatus("/root")
+	if err != nil {
+		t.Fatalf("ListStatus: %v", err)
+	}
+	var names []string
+	for _, st := range sts {
+		names = append(names, st.PathSuffix)
+		if st.Type != "FILE" || st.Length == 0 {
+			t.Fatalf("status %+v", st)
+		}
+	}
+	if strings.Join(names, ",") != "a.seg,b.seg" {
+		t.Fatalf("names = %v", names)
+	}
+	if _, err := c.ListStatus("/nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ListStatus missing dir: %v, want ErrNotFound", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+	if err := c.Create("/x", []byte("1"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("/x", false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := f.files["/x"]; ok {
+		t.Fatal("file still exists after Delete")
+	}
+	// WebHDFS membalas {"boolean": false} untuk path yang tidak ada: bukan error
+	if err := c.Delete("/x", false); err != nil {
+		t.Fatalf("Delete missing path: %v", err)
+	}
+	want := "DELETE DELETE /x"
+	if got := f.ops[len(f.ops)-1]; got != want {
+		t.Fatalf("last op = %q, want %q", got, want)
+	}
+}
+
+func TestRemoteExceptionMapping(t *testing.T) {
+	f := newFakeHDFS(t)
+	c := f.client()
+
+	_, err := c.Open("/missing", 0, 0)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrExists) {
+		t.Fatalf("Open missing file: %v, want ErrNotFound", err)
+	}
+	var re *RemoteError
+	if !errors.As(err, &re) || re.Op != "OPEN" || re.Path != "/missing" || re.JavaClassName == "" {
+		t.Fatalf("RemoteError = %+v", re)
+	}
+
+	// Exception lain tidak cocok dengan ErrNotFound/ErrExists
+	_, err = c.GetFileStatus("/x")
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrExists) {
+		t.Fatalf("GETFILESTATUS on fake server: %v, want plain RemoteError", err)
+	}
+	if !errors.As(err, &re) || re.Exception != "IllegalArgumentException" || re.StatusCode != http.StatusBadRequest {
+		t.Fatalf("RemoteError = %+v", re)
+	}
+}
+
+// Mode HttpFS: upload dikirim langsung ke server dengan data=true, tanpa redirect.
+func TestHTTPFSUploadWithoutRedirect(t *testing.T) {
+	var gotBody, gotData string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotData = string(b), r.URL.Query().Get("data")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, HTTPFS: true, HTTP: srv.Client()}
+	if err := c.Create("/f", []byte("payload"), true); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if gotBody != "payload" || gotData != "true" {
+		t.Fatalf("body=%q data=%q", gotBody, gotData)
+	}
+
+	// Tanpa HttpFS, namenode yang tidak me-redirect upload adalah error (data tidak terkirim)
+	c.HTTPFS = false
+	if err := c.Create("/f", []byte("payload"), true); err == nil {
+		t.Fatal("Create without redirect succeeded, want error")
+	}
+}
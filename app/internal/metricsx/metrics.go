@@ -0,0 +1,92 @@
+package metricsx
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter adalah counter monotonic sederhana yang aman dipakai dari banyak goroutine.
+// Semua counter yang dibuat lewat NewCounter otomatis ter-register dan ikut
+// di-expose oleh Handler dalam format text Prometheus.
+type Counter struct {
+	name string
+	help string
+	v    atomic.Int64
+}
+
+// Inc menambah counter dengan 1.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add menambah counter dengan n (n negatif diabaikan agar counter tetap monotonic).
+func (c *Counter) Add(n int64) {
+	if n > 0 {
+		c.v.Add(n)
+	}
+}
+
+// Value mengembalikan nilai counter saat ini.
+func (c *Counter) Value() int64 { return c.v.Load() }
+
+// gaugeFunc adalah gauge yang nilainya dihitung saat di-scrape.
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+var (
+	mu       sync.Mutex
+	counters = map[string]*Counter{}
+	gauges   = map[string]*gaugeFunc{}
+)
+
+// NewCounter membuat (atau mengambil yang sudah ada) counter dengan nama tertentu.
+// Nama mengikuti konvensi Prometheus, mis. "ingestor_promotions_total".
+func NewCounter(name, help string) *Counter {
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := counters[name]; ok {
+		return c
+	}
+	c := &Counter{name: name, help: help}
+	counters[name] = c
+	return c
+}
+
+// NewGaugeFunc mendaftarkan gauge yang nilainya diambil dari fn setiap kali di-scrape.
+// Pendaftaran ulang dengan nama yang sama menggantikan fn sebelumnya.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	gauges[name] = &gaugeFunc{name: name, help: help, fn: fn}
+}
+
+// Handler mengembalikan http.Handler yang menulis semua metric dalam format text Prometheus.
+// Dipasang di endpoint /metrics agar bisa di-scrape oleh Prometheus.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		cs := make([]*Counter, 0, len(counters))
+		for _, c := range counters {
+			cs = append(cs, c)
+		}
+		gs := make([]*gaugeFunc, 0, len(gauges))
+		for _, g := range gauges {
+			gs = append(gs, g)
+		}
+		mu.Unlock()
+		sort.Slice(cs, func(i, j int) bool { return cs[i].name < cs[j].name })
+		sort.Slice(gs, func(i, j int) bool { return gs[i].name < gs[j].name })
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, c := range cs {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.Value())
+		}
+		for _, g := range gs {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.fn())
+		}
+	})
+}
@@ -14,6 +14,11 @@ import (
 // yang berformat: "host1:port1,host2:port2,host3:port3"
 // Client ini akan otomatis discover semua node di cluster setelah koneksi pertama.
 func NewCluster() *redis.ClusterClient {
+	return redis.NewClusterClient(clusterOptions())
+}
+
+// clusterOptions adalah konfigurasi cluster client dari REDIS_STARTUP_NODES (lihat NewCluster).
+func clusterOptions() *redis.ClusterOptions {
 	nodes := os.Getenv("REDIS_STARTUP_NODES")
 	if nodes == "" {
 		nodes = "redis-1:7001"
@@ -21,12 +26,12 @@ func NewCluster() *redis.ClusterClient {
 	addrs := strings.Split(nodes, ",")
 
 	// Buat cluster client dengan konfigurasi timeout yang reasonable
-	return redis.NewClusterClient(&redis.ClusterOptions{
+	return &redis.ClusterOptions{
 		Addrs:        addrs,                    // List node addresses untuk initial connection
 		ReadTimeout:  2 * time.Second,          // Timeout untuk read operations
 		WriteTimeout: 2 * time.Second,          // Timeout untuk write operations
 		DialTimeout:  2 * time.Second,          // Timeout untuk establish connection
-	})
+	}
 }
 
 // ClusterMemRatio menghitung rasio penggunaan memori di seluruh Redis cluster.
@@ -0,0 +1,41 @@
+package redisx
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EvictedChannel adalah channel keyevent yang dikirim Redis untuk setiap key yang di-evict
+// (butuh notify-keyspace-events berisi "Ee"). Notifikasi keyspace hanya dikirim oleh node
+// pemilik key, jadi channel ini harus di-subscribe di setiap shard, bukan lewat cluster client.
+const EvictedChannel = "__keyevent@0__:evicted"
+
+// IsOOM true jika err adalah penolakan write karena maxmemory tercapai
+// (policy noeviction: "OOM command not allowed when used memory > 'maxmemory'").
+func IsOOM(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "OOM ")
+}
+
+// WatchEvictions men-subscribe EvictedChannel di setiap master shard dan memanggil fn
+// untuk setiap key yang di-evict. Dengan policy noeviction fn seharusnya tidak pernah
+// dipanggil; subscription ini adalah alarm jika policy diubah atau salah konfigurasi,
+// karena value key yang di-evict sudah hilang dan tidak bisa dipindah ke cold store.
+// fn dipanggil dari goroutine per shard, jadi harus aman dipakai bersamaan.
+// Shard yang ditambahkan setelah WatchEvictions dipanggil tidak ikut dipantau.
+func WatchEvictions(ctx context.Context, c *redis.ClusterClient, fn func(key string)) error {
+	return c.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+		sub := shard.Subscribe(ctx, EvictedChannel)
+		if _, err := sub.Receive(ctx); err != nil {
+			sub.Close()
+			return err
+		}
+		go func() {
+			for msg := range sub.Channel() {
+				fn(msg.Payload)
+			}
+		}()
+		return nil
+	})
+}
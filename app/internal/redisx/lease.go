@@ -0,0 +1,40 @@
+package redisx
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Lease adalah lock Redis sederhana dengan masa berlaku: key berisi ID pemilik dengan PX ttl.
+// Pemilik wajib memperpanjang (RenewLease) sebelum ttl habis; jika proses pemilik mati,
+// key expired sendiri dan lease bisa diambil instance lain.
+
+// renewLeaseScript memperpanjang lease hanya jika masih dipegang ARGV[1].
+// PEXPIRE tidak ditolak saat OOM (noeviction), jadi lease tetap bisa diperpanjang saat memori penuh.
+var renewLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// AcquireLease mencoba mengambil lease key untuk owner (SET NX PX).
+// Mengembalikan false jika lease sedang dipegang instance lain.
+func AcquireLease(ctx context.Context, c redis.Cmdable, key, owner string, ttl time.Duration) (bool, error) {
+	return c.SetNX(ctx, key, owner, ttl).Result()
+}
+
+// RenewLease memperpanjang lease milik owner. false berarti lease sudah hilang
+// (expired atau diambil instance lain) dan pemilik harus berhenti bekerja atas nama lease itu.
+func RenewLease(ctx context.Context, c redis.Scripter, key, owner string, ttl time.Duration) (bool, error) {
+	n, err := renewLeaseScript.Run(ctx, c, []string{key}, owner, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// ReleaseLease melepas lease jika masih dipegang owner.
+func ReleaseLease(ctx context.Context, c redis.Scripter, key, owner string) error {
+	_, err := CompareAndDelete(ctx, c, key, []byte(owner))
+	return err
+}
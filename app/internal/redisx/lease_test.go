@@ -0,0 +1,53 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestLease(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+
+	if ok, err := AcquireLease(ctx, c, "l", "a", time.Second); !ok || err != nil {
+		t.Fatalf("acquire a = %t, %v", ok, err)
+	}
+	if ok, err := AcquireLease(ctx, c, "l", "b", time.Second); ok || err != nil {
+		t.Fatalf("acquire b while held by a = %t, %v", ok, err)
+	}
+
+	if ok, err := RenewLease(ctx, c, "l", "b", time.Minute); ok || err != nil {
+		t.Fatalf("renew by non-owner = %t, %v", ok, err)
+	}
+	if ttl := srv.TTL("l"); ttl > time.Second {
+		t.Fatalf("non-owner renew extended TTL to %s", ttl)
+	}
+	if ok, err := RenewLease(ctx, c, "l", "a", time.Minute); !ok || err != nil {
+		t.Fatalf("renew by owner = %t, %v", ok, err)
+	}
+	if ttl := srv.TTL("l"); ttl <= time.Second {
+		t.Fatalf("TTL after renew = %s, want ~1m", ttl)
+	}
+
+	// Release oleh instance lain tidak melepas lease
+	if err := ReleaseLease(ctx, c, "l", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := srv.Get("l"); v != "a" {
+		t.Fatalf("lease = %q after release by non-owner, want a", v)
+	}
+	if err := ReleaseLease(ctx, c, "l", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := AcquireLease(ctx, c, "l", "b", time.Second); !ok || err != nil {
+		t.Fatalf("acquire b after release = %t, %v", ok, err)
+	}
+	// Lease yang sudah hilang tidak bisa diperpanjang
+	if ok, err := RenewLease(ctx, c, "missing", "a", time.Second); ok || err != nil {
+		t.Fatalf("renew missing lease = %t, %v", ok, err)
+	}
+}
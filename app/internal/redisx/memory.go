@@ -0,0 +1,29 @@
+package redisx
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ShardMemRatio menghitung rasio used_memory / maxmemory satu node Redis.
+// Dipakai offloader untuk menilai shard satu per satu, karena shard bisa terisi tidak merata
+// sehingga rata-rata cluster (ClusterMemRatio) masih rendah walaupun satu shard hampir penuh.
+// Mengembalikan 0 jika maxmemory tidak di-set.
+func ShardMemRatio(ctx context.Context, shard *redis.Client) (float64, error) {
+	used, maxm, err := ShardMemory(ctx, shard)
+	if err != nil || maxm <= 0 {
+		return 0, err
+	}
+	return float64(used) / float64(maxm), nil
+}
+
+// ShardMemory membaca used_memory dan maxmemory (byte) satu node Redis dari INFO memory.
+// maxmemory 0 berarti tidak di-set.
+func ShardMemory(ctx context.Context, shard *redis.Client) (used, maxm int64, err error) {
+	s, err := shard.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseInfoInt(s, "used_memory"), parseInfoInt(s, "maxmemory"), nil
+}
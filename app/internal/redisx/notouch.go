@@ -0,0 +1,43 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NoTouch mencatat apakah CLIENT NO-TOUCH ON diterima di setiap koneksi client
+// yang dibuat NewClusterNoTouch.
+type NoTouch struct {
+	rejected atomic.Bool
+}
+
+// Active bernilai true selama belum ada server yang menolak CLIENT NO-TOUCH ON,
+// artinya perintah dari client ini tidak mengubah OBJECT IDLETIME / FREQ key.
+func (n *NoTouch) Active() bool { return n != nil && !n.rejected.Load() }
+
+// NewClusterNoTouch seperti NewCluster, tapi setiap koneksi baru menjalankan CLIENT NO-TOUCH ON
+// (Redis >= 7.2): GET/DUMP/HGETALL dari client ini tidak me-reset LRU/LFU key, sehingga
+// OBJECT IDLETIME tetap mencerminkan akses aplikasi, bukan pembacaan proses pemeliharaan
+// (offloader). Server yang belum mendukung perintah itu tidak membuat koneksi gagal;
+// penolakannya dicatat di NoTouch agar pemanggil bisa memakai cara lain.
+func NewClusterNoTouch() (*redis.ClusterClient, *NoTouch) {
+	nt := &NoTouch{}
+	opt := clusterOptions()
+	opt.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		cmd := redis.NewStatusCmd(ctx, "client", "no-touch", "on")
+		err := cn.Process(ctx, cmd)
+		var rerr redis.Error
+		if err == nil || !errors.As(err, &rerr) {
+			return err // Error jaringan: koneksi gagal dan dicoba ulang seperti biasa
+		}
+		if nt.rejected.CompareAndSwap(false, true) {
+			log.Printf("redis CLIENT NO-TOUCH not supported (%v); OBJECT IDLETIME includes this client's reads", err)
+		}
+		return nil
+	}
+	return redis.NewClusterClient(opt), nt
+}
@@ -0,0 +1,46 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestNoTouchKeepsIdleTime(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	t.Setenv("REDIS_STARTUP_NODES", srv.Addr())
+	c, nt := NewClusterNoTouch()
+	defer c.Close()
+
+	c.Set(ctx, "k", "v", 0)
+	srv.SetIdle("k", time.Hour)
+	if err := c.Get(ctx, "k").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !nt.Active() {
+		t.Fatal("NoTouch inactive on a server that supports CLIENT NO-TOUCH")
+	}
+	if idle, _ := c.ObjectIdleTime(ctx, "k").Result(); idle < time.Hour {
+		t.Fatalf("idle after GET = %s, want reads not to reset it", idle)
+	}
+}
+
+// Redis < 7.2 menolak CLIENT NO-TOUCH: koneksi tetap jalan, penolakannya tercatat.
+func TestNoTouchRejected(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	srv.RejectNoTouch()
+	t.Setenv("REDIS_STARTUP_NODES", srv.Addr())
+	c, nt := NewClusterNoTouch()
+	defer c.Close()
+
+	if err := c.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("command on server without NO-TOUCH: %v", err)
+	}
+	if nt.Active() {
+		t.Fatal("NoTouch still active after the server rejected it")
+	}
+}
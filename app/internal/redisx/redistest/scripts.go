@@ -0,0 +1,52 @@
+package redistest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// registerBuiltinScripts mendaftarkan implementasi Go untuk script Lua milik redisx,
+// dikenali dari isi script-nya: compare-and-delete (redisx.CompareAndDelete) dan
+// perpanjangan lease (redisx.RenewLease).
+func (s *Server) registerBuiltinScripts() {
+	s.scripts = append(s.scripts,
+		script{
+			match: func(src string) bool { return strings.Contains(src, "redis.sha1hex") && strings.Contains(src, "'DEL'") },
+			fn:    compareAndDelete,
+		},
+		script{
+			match: func(src string) bool { return strings.Contains(src, "'PEXPIRE'") && strings.Contains(src, "'GET'") },
+			fn:    renewLease,
+		},
+	)
+}
+
+// compareAndDelete: DEL KEYS[1] jika sha1 versinya (GET untuk string, DUMP untuk tipe lain) == ARGV[1].
+func compareAndDelete(call func(args ...string) any, keys, args []string) any {
+	t := StatusText(call("TYPE", keys[0]))
+	if t == "none" {
+		return -1
+	}
+	var v any
+	if t == "string" {
+		v = call("GET", keys[0])
+	} else {
+		v = call("DUMP", keys[0])
+	}
+	b, _ := v.([]byte)
+	sum := sha1.Sum(b)
+	if hex.EncodeToString(sum[:]) != args[0] {
+		return 0
+	}
+	call("DEL", keys[0])
+	return 1
+}
+
+// renewLease: PEXPIRE KEYS[1] ARGV[2] jika value key == ARGV[1].
+func renewLease(call func(args ...string) any, keys, args []string) any {
+	if b, ok := call("GET", keys[0]).([]byte); ok && string(b) == args[0] {
+		return call("PEXPIRE", keys[0], args[1])
+	}
+	return 0
+}
@@ -0,0 +1,1353 @@
+// Package redistest menyediakan server Redis in-memory (protokol RESP2) untuk test.
+// Server ini satu node yang memegang semua 16384 slot, jadi bisa dipakai oleh
+// redis.Client maupun redis.ClusterClient (CLUSTER SLOTS dijawab dengan node itu sendiri).
+//
+// Yang didukung hanya perintah yang dipakai repo ini: string, hash, list, set, zset,
+// TTL, SCAN/RANDOMKEY/OBJECT IDLETIME/MEMORY USAGE, INFO memory, WATCH/MULTI/EXEC,
+// pub/sub, dan script. Lua tidak dijalankan: script redisx (compare-and-delete, lease)
+// sudah punya implementasi Go bawaan, script lain didaftarkan lewat Server.Script;
+// keduanya dikenali dari isi script yang dikirim EVAL / SCRIPT LOAD.
+package redistest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NodeID adalah ID node (CLUSTER MYID) server test.
+const NodeID = "07c37dfeb235213a872192d90877d0cd55635b91"
+
+// Tipe key, sama seperti balasan TYPE.
+const (
+	typeString = "string"
+	typeHash   = "hash"
+	typeList   = "list"
+	typeSet    = "set"
+	typeZSet   = "zset"
+)
+
+// OOMError adalah balasan write saat server dalam mode OOM (lihat SetOOM).
+const OOMError = "OOM command not allowed when used memory > 'maxmemory'."
+
+// ScriptFunc adalah implementasi Go sebuah script. call menjalankan perintah Redis seperti
+// redis.call di Lua (di dalam lock yang sama, jadi atomic); nilai balik dikirim ke client.
+type ScriptFunc func(call func(args ...string) any, keys, args []string) any
+
+type script struct {
+	match func(src string) bool
+	fn    ScriptFunc
+}
+
+type value struct {
+	typ        string
+	str        []byte
+	hash       map[string]string
+	list       []string
+	set        map[string]bool
+	zset       map[string]float64
+	expireAt   time.Time // zero = tanpa expiry
+	lastAccess time.Time
+}
+
+// Server adalah server Redis in-memory. Zero value tidak bisa dipakai; gunakan NewServer.
+type Server struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	data     map[string]*value
+	versions map[string]uint64 // naik setiap key berubah (WATCH)
+	calls    map[string]int
+	scripts  []script
+	loaded   map[string]ScriptFunc // sha -> script yang sudah di-load (EVAL / SCRIPT LOAD)
+	subs     map[string]map[*conn]bool
+	conns    map[*conn]bool
+
+	baseMemory, maxMemory int64
+	oom                   bool
+	noTouchUnsupported    bool
+	hook                  func(args []string) error
+}
+
+// NewServer menjalankan server di port acak localhost; server ditutup saat test selesai.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		ln:       ln,
+		data:     map[string]*value{},
+		versions: map[string]uint64{},
+		calls:    map[string]int{},
+		loaded:   map[string]ScriptFunc{},
+		subs:     map[string]map[*conn]bool{},
+		conns:    map[*conn]bool{},
+	}
+	s.registerBuiltinScripts()
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Addr adalah alamat host:port server.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// Close menghentikan server dan memutus semua koneksi.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+}
+
+// Client membuat redis.Client ke server; ditutup saat test selesai.
+func (s *Server) Client(t testing.TB) *redis.Client {
+	c := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Cluster membuat redis.ClusterClient ke server; ditutup saat test selesai.
+func (s *Server) Cluster(t testing.TB) *redis.ClusterClient {
+	c := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Script mendaftarkan implementasi fn untuk script yang isinya cocok dengan match.
+func (s *Server) Script(match func(src string) bool, fn ScriptFunc) {
+	s.mu.Lock()
+	s.scripts = append(s.scripts, script{match, fn})
+	s.mu.Unlock()
+}
+
+// SetMemory mengatur INFO memory: used_memory = base + perkiraan ukuran semua key, dan maxmemory.
+func (s *Server) SetMemory(base, maxMemory int64) {
+	s.mu.Lock()
+	s.baseMemory, s.maxMemory = base, maxMemory
+	s.mu.Unlock()
+}
+
+// SetOOM membuat semua write yang butuh memori (SET, HSET, ...) ditolak dengan OOMError,
+// seperti Redis dengan maxmemory-policy noeviction yang sudah penuh. DEL tetap diterima.
+func (s *Server) SetOOM(oom bool) {
+	s.mu.Lock()
+	s.oom = oom
+	s.mu.Unlock()
+}
+
+// RejectNoTouch mensimulasikan Redis < 7.2: CLIENT NO-TOUCH dibalas error.
+func (s *Server) RejectNoTouch() {
+	s.mu.Lock()
+	s.noTouchUnsupported = true
+	s.mu.Unlock()
+}
+
+// Hook dipanggil sebelum setiap perintah (nama perintah huruf kecil); error yang dikembalikan
+// dikirim sebagai balasan error perintah itu.
+func (s *Server) Hook(fn func(args []string) error) {
+	s.mu.Lock()
+	s.hook = fn
+	s.mu.Unlock()
+}
+
+// Calls mengembalikan jumlah pemanggilan perintah name (huruf kecil, mis. "get").
+func (s *Server) Calls(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[name]
+}
+
+// ResetCalls mengosongkan hitungan Calls.
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	s.calls = map[string]int{}
+	s.mu.Unlock()
+}
+
+// Keys mengembalikan semua key yang belum kedaluwarsa, terurut.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedKeys()
+}
+
+// Get mengembalikan value string key (false jika tidak ada atau bukan string).
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.lookup(key)
+	if v == nil || v.typ != typeString {
+		return "", false
+	}
+	return string(v.str), true
+}
+
+// TTL mengembalikan sisa TTL key (0 jika tanpa expiry atau tidak ada).
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.lookup(key)
+	if v == nil || v.expireAt.IsZero() {
+		return 0
+	}
+	return time.Until(v.expireAt)
+}
+
+// SetIdle mengatur OBJECT IDLETIME key menjadi d.
+func (s *Server) SetIdle(key string, d time.Duration) {
+	s.mu.Lock()
+	if v := s.lookup(key); v != nil {
+		v.lastAccess = time.Now().Add(-d)
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{s: s, nc: nc, w: bufio.NewWriter(nc)}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+		go c.serve()
+	}
+}
+
+// Balasan RESP selain []byte (bulk), int64, []any (array) dan nil (null bulk).
+type (
+	status    string
+	errReply  string
+	nullArray struct{}
+)
+
+var (
+	ok          = status("OK")
+	errWrongTyp = errReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errSyntax   = errReply("ERR syntax error")
+	errNotInt   = errReply("ERR value is not an integer or out of range")
+)
+
+func errArgs(cmd string) errReply {
+	return errReply("ERR wrong number of arguments for '" + cmd + "' command")
+}
+
+type conn struct {
+	s  *Server
+	nc net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	multi   bool
+	queued  [][]string
+	watched map[string]uint64
+	dirty   bool // perintah invalid di dalam MULTI
+	noTouch bool
+	subs    map[string]bool
+}
+
+func (c *conn) serve() {
+	defer func() {
+		c.nc.Close()
+		c.s.mu.Lock()
+		delete(c.s.conns, c)
+		for ch := range c.subs {
+			delete(c.s.subs[ch], c)
+		}
+		c.s.mu.Unlock()
+	}()
+	r := bufio.NewReader(c.nc)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		c.reply(c.handle(args))
+	}
+}
+
+func (c *conn) reply(v any) {
+	if p, ok := v.(pushes); ok {
+		c.replyPushes(p)
+		return
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	writeReply(c.w, v)
+	c.w.Flush()
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil // inline command
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("redistest: expected bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, v any) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case nullArray:
+		w.WriteString("*-1\r\n")
+	case status:
+		w.WriteString("+" + string(v) + "\r\n")
+	case errReply:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case int:
+		w.WriteString(":" + strconv.Itoa(v) + "\r\n")
+	case []byte:
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n")
+		w.Write(v)
+		w.WriteString("\r\n")
+	case string:
+		writeReply(w, []byte(v))
+	case []any:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, x := range v {
+			writeReply(w, x)
+		}
+	case []string:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, x := range v {
+			writeReply(w, []byte(x))
+		}
+	default:
+		panic(fmt.Sprintf("redistest: unsupported reply %T", v))
+	}
+}
+
+// handle menjalankan satu perintah dari client, termasuk state koneksi (MULTI, pub/sub).
+func (c *conn) handle(args []string) any {
+	name := strings.ToLower(args[0])
+	s := c.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[name]++
+	if s.hook != nil {
+		if err := s.hook(append([]string{name}, args[1:]...)); err != nil {
+			if c.multi && name != "exec" && name != "discard" {
+				c.dirty = true
+			}
+			return errReply(err.Error())
+		}
+	}
+
+	if len(c.subs) > 0 {
+		switch name {
+		case "subscribe", "unsubscribe", "ping", "quit":
+		default:
+			return errReply("ERR Can't execute '" + name + "': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context")
+		}
+	}
+
+	if c.multi {
+		switch name {
+		case "exec":
+			return c.exec()
+		case "discard":
+			c.resetTx()
+			return ok
+		case "multi":
+			return errReply("ERR MULTI calls can not be nested")
+		case "watch":
+			return errReply("ERR WATCH inside MULTI is not allowed")
+		}
+		c.queued = append(c.queued, args)
+		return status("QUEUED")
+	}
+
+	switch name {
+	case "multi":
+		c.multi = true
+		return ok
+	case "exec":
+		return errReply("ERR EXEC without MULTI")
+	case "discard":
+		return errReply("ERR DISCARD without MULTI")
+	case "watch":
+		if c.watched == nil {
+			c.watched = map[string]uint64{}
+		}
+		for _, k := range args[1:] {
+			s.lookup(k) // expiry lazily menaikkan versi
+			c.watched[k] = s.versions[k]
+		}
+		return ok
+	case "unwatch":
+		c.watched = nil
+		return ok
+	case "client":
+		return c.client(args)
+	case "subscribe":
+		return c.subscribe(args[1:])
+	case "unsubscribe":
+		return c.unsubscribe(args[1:])
+	case "ping":
+		if len(c.subs) > 0 {
+			msg := ""
+			if len(args) > 1 {
+				msg = args[1]
+			}
+			return []any{[]byte("pong"), []byte(msg)}
+		}
+	}
+	return s.exec(c, args)
+}
+
+func (c *conn) resetTx() {
+	c.multi, c.queued, c.watched, c.dirty = false, nil, nil, false
+}
+
+func (c *conn) exec() any {
+	s := c.s
+	defer c.resetTx()
+	if c.dirty {
+		return errReply("EXECABORT Transaction discarded because of previous errors.")
+	}
+	for k, ver := range c.watched {
+		s.lookup(k)
+		if s.versions[k] != ver {
+			return nullArray{}
+		}
+	}
+	out := make([]any, len(c.queued))
+	for i, args := range c.queued {
+		out[i] = s.exec(c, args)
+	}
+	return out
+}
+
+func (c *conn) client(args []string) any {
+	if len(args) < 2 {
+		return errArgs("client")
+	}
+	switch strings.ToLower(args[1]) {
+	case "setinfo", "setname":
+		return ok
+	case "id":
+		return int64(1)
+	case "no-touch":
+		if c.s.noTouchUnsupported {
+			return errReply("ERR unknown subcommand 'NO-TOUCH'. Try CLIENT HELP.")
+		}
+		if len(args) != 3 {
+			return errArgs("client|no-touch")
+		}
+		c.noTouch = strings.EqualFold(args[2], "on")
+		return ok
+	}
+	return errReply("ERR unknown subcommand '" + args[1] + "'")
+}
+
+func (c *conn) subscribe(chs []string) any {
+	s := c.s
+	if c.subs == nil {
+		c.subs = map[string]bool{}
+	}
+	out := make([]any, 0, 3*len(chs))
+	for _, ch := range chs {
+		c.subs[ch] = true
+		if s.subs[ch] == nil {
+			s.subs[ch] = map[*conn]bool{}
+		}
+		s.subs[ch][c] = true
+		out = append(out, []byte("subscribe"), []byte(ch), int64(len(c.subs)))
+	}
+	return pushes(out)
+}
+
+func (c *conn) unsubscribe(chs []string) any {
+	s := c.s
+	if len(chs) == 0 {
+		for ch := range c.subs {
+			chs = append(chs, ch)
+		}
+	}
+	out := make([]any, 0, 3*len(chs))
+	for _, ch := range chs {
+		delete(c.subs, ch)
+		delete(s.subs[ch], c)
+		out = append(out, []byte("unsubscribe"), []byte(ch), int64(len(c.subs)))
+	}
+	return pushes(out)
+}
+
+// pushes adalah beberapa balasan array 3 elemen yang dikirim berurutan (SUBSCRIBE banyak channel).
+type pushes []any
+
+func (c *conn) replyPushes(p pushes) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	for i := 0; i+3 <= len(p); i += 3 {
+		writeReply(c.w, []any(p[i:i+3]))
+	}
+	c.w.Flush()
+}
+
+// publish mengirim pesan ke subscriber channel (dipanggil dengan s.mu terkunci).
+func (s *Server) publish(ch, msg string) int64 {
+	var n int64
+	for sub := range s.subs[ch] {
+		n++
+		go sub.reply([]any{[]byte("message"), []byte(ch), []byte(msg)})
+	}
+	return n
+}
+
+// Publish mengirim pesan pub/sub dari sisi server (mis. notifikasi keyevent).
+func (s *Server) Publish(ch, msg string) {
+	s.mu.Lock()
+	s.publish(ch, msg)
+	s.mu.Unlock()
+}
+
+// lookup mengembalikan value key yang belum kedaluwarsa (nil jika tidak ada).
+func (s *Server) lookup(key string) *value {
+	v, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+	if !v.expireAt.IsZero() && !time.Now().Before(v.expireAt) {
+		delete(s.data, key)
+		s.versions[key]++
+		return nil
+	}
+	return v
+}
+
+func (s *Server) touch(c *conn, v *value) {
+	if c == nil || !c.noTouch {
+		v.lastAccess = time.Now()
+	}
+}
+
+func (s *Server) modified(key string) { s.versions[key]++ }
+
+func (s *Server) sortedKeys() []string {
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if s.lookup(k) != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// usage adalah perkiraan MEMORY USAGE satu key.
+func usage(key string, v *value) int64 {
+	n := int64(len(key)) + 48
+	switch v.typ {
+	case typeString:
+		n += int64(len(v.str))
+	case typeHash:
+		for f, x := range v.hash {
+			n += int64(len(f)+len(x)) + 16
+		}
+	case typeList:
+		for _, x := range v.list {
+			n += int64(len(x)) + 16
+		}
+	case typeSet:
+		for x := range v.set {
+			n += int64(len(x)) + 16
+		}
+	case typeZSet:
+		for x := range v.zset {
+			n += int64(len(x)) + 24
+		}
+	}
+	return n
+}
+
+func (s *Server) usedMemory() int64 {
+	n := s.baseMemory
+	for k, v := range s.data {
+		n += usage(k, v)
+	}
+	return n
+}
+
+// denyOOM adalah perintah yang ditolak saat OOM (flag "denyoom" di Redis).
+var denyOOM = map[string]bool{
+	"set": true, "setnx": true, "hset": true, "rpush": true, "sadd": true, "zadd": true,
+}
+
+// writeOf mengambil value key untuk ditulis dengan tipe typ (dibuat jika belum ada).
+func (s *Server) writeOf(key, typ string) (*value, errReply) {
+	v := s.lookup(key)
+	if v == nil {
+		v = &value{typ: typ}
+		s.data[key] = v
+	} else if v.typ != typ {
+		return nil, errWrongTyp
+	}
+	return v, ""
+}
+
+// readOf mengambil value key bertipe typ untuk dibaca (nil jika tidak ada).
+func (s *Server) readOf(c *conn, key, typ string) (*value, errReply) {
+	v := s.lookup(key)
+	if v == nil {
+		return nil, ""
+	}
+	if v.typ != typ {
+		return nil, errWrongTyp
+	}
+	s.touch(c, v)
+	return v, ""
+}
+
+// exec menjalankan satu perintah data (dipanggil dengan s.mu terkunci). c bisa nil (dari script).
+func (s *Server) exec(c *conn, args []string) any {
+	name := strings.ToLower(args[0])
+	a := args[1:]
+	if s.oom && denyOOM[name] {
+		return errReply(OOMError)
+	}
+	need := func(n int) bool { return len(a) >= n }
+	switch name {
+	case "ping":
+		if len(a) > 0 {
+			return []byte(a[0])
+		}
+		return status("PONG")
+	case "echo":
+		if !need(1) {
+			return errArgs(name)
+		}
+		return []byte(a[0])
+	case "hello":
+		return errReply("ERR unknown command 'HELLO'")
+	case "select", "readonly", "readwrite":
+		return ok
+	case "cluster":
+		return s.cluster(a)
+	case "info":
+		used := s.usedMemory()
+		return []byte(fmt.Sprintf("# Memory\r\nused_memory:%d\r\nused_memory_human:%dB\r\nmaxmemory:%d\r\nmaxmemory_policy:noeviction\r\n# Stats\r\nevicted_keys:0\r\n", used, used, s.maxMemory))
+	case "dbsize":
+		return int64(len(s.sortedKeys()))
+	case "flushall", "flushdb":
+		for k := range s.data {
+			s.modified(k)
+		}
+		s.data = map[string]*value{}
+		return ok
+	case "publish":
+		if !need(2) {
+			return errArgs(name)
+		}
+		return s.publish(a[0], a[1])
+
+	case "get":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeString)
+		if e != "" {
+			return e
+		}
+		if v == nil {
+			return nil
+		}
+		return append([]byte(nil), v.str...)
+	case "mget":
+		out := make([]any, len(a))
+		for i, k := range a {
+			if v := s.lookup(k); v != nil && v.typ == typeString {
+				s.touch(c, v)
+				out[i] = append([]byte(nil), v.str...)
+			}
+		}
+		return out
+	case "set":
+		return s.set(a)
+	case "setnx":
+		if !need(2) {
+			return errArgs(name)
+		}
+		if s.lookup(a[0]) != nil {
+			return int64(0)
+		}
+		s.data[a[0]] = &value{typ: typeString, str: []byte(a[1]), lastAccess: time.Now()}
+		s.modified(a[0])
+		return int64(1)
+	case "del", "unlink":
+		var n int64
+		for _, k := range a {
+			if s.lookup(k) != nil {
+				delete(s.data, k)
+				s.modified(k)
+				n++
+			}
+		}
+		return n
+	case "exists":
+		var n int64
+		for _, k := range a {
+			if s.lookup(k) != nil {
+				n++
+			}
+		}
+		return n
+	case "type":
+		if !need(1) {
+			return errArgs(name)
+		}
+		if v := s.lookup(a[0]); v != nil {
+			return status(v.typ)
+		}
+		return status("none")
+	case "pttl", "ttl":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v := s.lookup(a[0])
+		switch {
+		case v == nil:
+			return int64(-2)
+		case v.expireAt.IsZero():
+			return int64(-1)
+		}
+		d := time.Until(v.expireAt)
+		if name == "ttl" {
+			return int64((d + time.Second - 1) / time.Second)
+		}
+		return int64((d + time.Millisecond - 1) / time.Millisecond)
+	case "pexpire", "expire":
+		if !need(2) {
+			return errArgs(name)
+		}
+		n, err := strconv.ParseInt(a[1], 10, 64)
+		if err != nil {
+			return errNotInt
+		}
+		v := s.lookup(a[0])
+		if v == nil {
+			return int64(0)
+		}
+		unit := time.Millisecond
+		if name == "expire" {
+			unit = time.Second
+		}
+		v.expireAt = time.Now().Add(time.Duration(n) * unit)
+		s.modified(a[0])
+		s.lookup(a[0])
+		return int64(1)
+	case "persist":
+		if v := s.lookup(a[0]); v != nil && !v.expireAt.IsZero() {
+			v.expireAt = time.Time{}
+			s.modified(a[0])
+			return int64(1)
+		}
+		return int64(0)
+	case "dump":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v := s.lookup(a[0])
+		if v == nil {
+			return nil
+		}
+		return dump(v)
+	case "randomkey":
+		keys := s.sortedKeys()
+		if len(keys) == 0 {
+			return nil
+		}
+		return []byte(keys[rand.Intn(len(keys))])
+	case "object":
+		if !need(2) || !strings.EqualFold(a[0], "idletime") {
+			return errSyntax
+		}
+		v := s.lookup(a[1])
+		if v == nil {
+			return nil
+		}
+		return int64(time.Since(v.lastAccess) / time.Second)
+	case "memory":
+		if !need(2) || !strings.EqualFold(a[0], "usage") {
+			return errSyntax
+		}
+		v := s.lookup(a[1])
+		if v == nil {
+			return nil
+		}
+		return usage(a[1], v)
+	case "scan":
+		return s.scan(a)
+
+	case "hset":
+		if len(a) < 3 || len(a)%2 != 1 {
+			return errArgs(name)
+		}
+		v, e := s.writeOf(a[0], typeHash)
+		if e != "" {
+			return e
+		}
+		if v.hash == nil {
+			v.hash = map[string]string{}
+		}
+		var n int64
+		for i := 1; i+1 < len(a); i += 2 {
+			if _, ok := v.hash[a[i]]; !ok {
+				n++
+			}
+			v.hash[a[i]] = a[i+1]
+		}
+		s.touch(c, v)
+		s.modified(a[0])
+		return n
+	case "hget":
+		if !need(2) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeHash)
+		if e != "" {
+			return e
+		}
+		if x, ok := v.hashField(a[1]); ok {
+			return []byte(x)
+		}
+		return nil
+	case "hgetall":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeHash)
+		if e != "" {
+			return e
+		}
+		out := []any{}
+		if v != nil {
+			fields := make([]string, 0, len(v.hash))
+			for f := range v.hash {
+				fields = append(fields, f)
+			}
+			sort.Strings(fields)
+			for _, f := range fields {
+				out = append(out, []byte(f), []byte(v.hash[f]))
+			}
+		}
+		return out
+	case "rpush":
+		if !need(2) {
+			return errArgs(name)
+		}
+		v, e := s.writeOf(a[0], typeList)
+		if e != "" {
+			return e
+		}
+		v.list = append(v.list, a[1:]...)
+		s.touch(c, v)
+		s.modified(a[0])
+		return int64(len(v.list))
+	case "lrange":
+		if !need(3) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeList)
+		if e != "" {
+			return e
+		}
+		out := []any{}
+		if v != nil {
+			lo, hi, err := rangeIdx(a[1], a[2], len(v.list))
+			if err != "" {
+				return err
+			}
+			for _, x := range v.list[lo:hi] {
+				out = append(out, []byte(x))
+			}
+		}
+		return out
+	case "sadd":
+		if !need(2) {
+			return errArgs(name)
+		}
+		v, e := s.writeOf(a[0], typeSet)
+		if e != "" {
+			return e
+		}
+		if v.set == nil {
+			v.set = map[string]bool{}
+		}
+		var n int64
+		for _, x := range a[1:] {
+			if !v.set[x] {
+				n++
+				v.set[x] = true
+			}
+		}
+		s.touch(c, v)
+		s.modified(a[0])
+		return n
+	case "smembers":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeSet)
+		if e != "" {
+			return e
+		}
+		out := []any{}
+		if v != nil {
+			// Urutan SMEMBERS di Redis tidak ditentukan; dibalik agar pemanggil tidak bergantung padanya
+			members := make([]string, 0, len(v.set))
+			for x := range v.set {
+				members = append(members, x)
+			}
+			sort.Sort(sort.Reverse(sort.StringSlice(members)))
+			for _, x := range members {
+				out = append(out, []byte(x))
+			}
+		}
+		return out
+	case "zadd":
+		if len(a) < 3 || len(a)%2 != 1 {
+			return errArgs(name)
+		}
+		v, e := s.writeOf(a[0], typeZSet)
+		if e != "" {
+			return e
+		}
+		if v.zset == nil {
+			v.zset = map[string]float64{}
+		}
+		var n int64
+		for i := 1; i+1 < len(a); i += 2 {
+			score, err := strconv.ParseFloat(a[i], 64)
+			if err != nil {
+				return errReply("ERR value is not a valid float")
+			}
+			if _, ok := v.zset[a[i+1]]; !ok {
+				n++
+			}
+			v.zset[a[i+1]] = score
+		}
+		s.touch(c, v)
+		s.modified(a[0])
+		return n
+	case "zcard":
+		if !need(1) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeZSet)
+		if e != "" {
+			return e
+		}
+		if v == nil {
+			return int64(0)
+		}
+		return int64(len(v.zset))
+	case "zremrangebyscore":
+		if !need(3) {
+			return errArgs(name)
+		}
+		// ParseFloat juga menerima "-inf" / "+inf"; batas eksklusif "(" tidak didukung
+		lo, err1 := strconv.ParseFloat(a[1], 64)
+		hi, err2 := strconv.ParseFloat(a[2], 64)
+		if err1 != nil || err2 != nil {
+			return errReply("ERR min or max is not a float")
+		}
+		v, e := s.readOf(c, a[0], typeZSet)
+		if e != "" {
+			return e
+		}
+		if v == nil {
+			return int64(0)
+		}
+		var n int64
+		for m, score := range v.zset {
+			if score >= lo && score <= hi {
+				delete(v.zset, m)
+				n++
+			}
+		}
+		if n > 0 {
+			if len(v.zset) == 0 {
+				delete(s.data, a[0])
+			}
+			s.modified(a[0])
+		}
+		return n
+	case "zrange":
+		if !need(3) {
+			return errArgs(name)
+		}
+		v, e := s.readOf(c, a[0], typeZSet)
+		if e != "" {
+			return e
+		}
+		withScores := len(a) > 3 && strings.EqualFold(a[3], "withscores")
+		out := []any{}
+		if v != nil {
+			ms := v.zmembers()
+			lo, hi, err := rangeIdx(a[1], a[2], len(ms))
+			if err != "" {
+				return err
+			}
+			for _, m := range ms[lo:hi] {
+				out = append(out, []byte(m))
+				if withScores {
+					out = append(out, []byte(strconv.FormatFloat(v.zset[m], 'g', -1, 64)))
+				}
+			}
+		}
+		return out
+
+	case "eval", "evalsha":
+		return s.eval(c, name, a)
+	case "script":
+		return s.scriptCmd(a)
+	}
+	return errReply("ERR unknown command '" + args[0] + "'")
+}
+
+func (v *value) hashField(f string) (string, bool) {
+	if v == nil {
+		return "", false
+	}
+	x, ok := v.hash[f]
+	return x, ok
+}
+
+func (v *value) zmembers() []string {
+	ms := make([]string, 0, len(v.zset))
+	for m := range v.zset {
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if v.zset[ms[i]] != v.zset[ms[j]] {
+			return v.zset[ms[i]] < v.zset[ms[j]]
+		}
+		return ms[i] < ms[j]
+	})
+	return ms
+}
+
+// rangeIdx mengubah start/stop LRANGE/ZRANGE (boleh negatif) menjadi slice [lo:hi].
+func rangeIdx(start, stop string, n int) (int, int, errReply) {
+	lo, err1 := strconv.Atoi(start)
+	hi, err2 := strconv.Atoi(stop)
+	if err1 != nil || err2 != nil {
+		return 0, 0, errNotInt
+	}
+	if lo < 0 {
+		lo += n
+	}
+	if hi < 0 {
+		hi += n
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	if hi >= n {
+		hi = n - 1
+	}
+	if lo > hi {
+		return 0, 0, ""
+	}
+	return lo, hi + 1, ""
+}
+
+// dump adalah pengganti format DUMP: berbeda untuk isi yang berbeda, sama untuk isi yang sama.
+func dump(v *value) []byte {
+	var x any
+	switch v.typ {
+	case typeString:
+		x = string(v.str)
+	case typeHash:
+		x = v.hash
+	case typeList:
+		x = v.list
+	case typeSet:
+		x = v.set
+	case typeZSet:
+		x = v.zset
+	}
+	b, _ := json.Marshal(map[string]any{v.typ: x})
+	return append([]byte("\x00redistest"), b...)
+}
+
+func (s *Server) set(a []string) any {
+	if len(a) < 2 {
+		return errArgs("set")
+	}
+	key, val := a[0], a[1]
+	var (
+		nx, xx, keepTTL bool
+		expireAt        time.Time
+	)
+	for i := 2; i < len(a); i++ {
+		switch strings.ToLower(a[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "keepttl":
+			keepTTL = true
+		case "ex", "px":
+			if i+1 >= len(a) {
+				return errSyntax
+			}
+			n, err := strconv.ParseInt(a[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errReply("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.EqualFold(a[i], "px") {
+				unit = time.Millisecond
+			}
+			expireAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return errSyntax
+		}
+	}
+	cur := s.lookup(key)
+	if (nx && cur != nil) || (xx && cur == nil) {
+		return nil
+	}
+	v := &value{typ: typeString, str: []byte(val), expireAt: expireAt, lastAccess: time.Now()}
+	if keepTTL && cur != nil {
+		v.expireAt = cur.expireAt
+	}
+	s.data[key] = v
+	s.modified(key)
+	return ok
+}
+
+func (s *Server) cluster(a []string) any {
+	if len(a) == 0 {
+		return errArgs("cluster")
+	}
+	host, port, _ := net.SplitHostPort(s.Addr())
+	p, _ := strconv.Atoi(port)
+	switch strings.ToLower(a[0]) {
+	case "slots":
+		return []any{[]any{int64(0), int64(16383), []any{[]byte(host), int64(p), []byte(NodeID)}}}
+	case "myid":
+		return []byte(NodeID)
+	case "nodes":
+		return []byte(fmt.Sprintf("%s %s:%d@%d myself,master - 0 0 1 connected 0-16383\n", NodeID, host, p, p+10000))
+	case "info":
+		return []byte("cluster_state:ok\r\ncluster_slots_assigned:16384\r\ncluster_known_nodes:1\r\n")
+	}
+	return errReply("ERR unknown subcommand '" + a[0] + "'")
+}
+
+func (s *Server) scan(a []string) any {
+	if len(a) < 1 {
+		return errArgs("scan")
+	}
+	cursor, err := strconv.Atoi(a[0])
+	if err != nil {
+		return errReply("ERR invalid cursor")
+	}
+	match, count := "*", 10
+	for i := 1; i+1 < len(a); i += 2 {
+		switch strings.ToLower(a[i]) {
+		case "match":
+			match = a[i+1]
+		case "count":
+			if count, err = strconv.Atoi(a[i+1]); err != nil || count <= 0 {
+				return errSyntax
+			}
+		case "type":
+		default:
+			return errSyntax
+		}
+	}
+	keys := s.sortedKeys()
+	if cursor > len(keys) {
+		cursor = len(keys)
+	}
+	end := cursor + count
+	if end >= len(keys) {
+		end = 0
+	}
+	page := keys[cursor:]
+	if end != 0 {
+		page = keys[cursor:end]
+	}
+	out := []any{}
+	for _, k := range page {
+		if ok, _ := path.Match(match, k); ok || match == "*" {
+			out = append(out, []byte(k))
+		}
+	}
+	return []any{[]byte(strconv.Itoa(end)), out}
+}
+
+func sha(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
+
+// load mendaftarkan src sebagai script yang sudah di-load (false jika tidak ada implementasinya).
+func (s *Server) load(src string) (string, bool) {
+	for _, sc := range s.scripts {
+		if sc.match(src) {
+			h := sha(src)
+			s.loaded[h] = sc.fn
+			return h, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) scriptCmd(a []string) any {
+	if len(a) == 0 {
+		return errArgs("script")
+	}
+	switch strings.ToLower(a[0]) {
+	case "load":
+		if len(a) != 2 {
+			return errArgs("script|load")
+		}
+		h, ok := s.load(a[1])
+		if !ok {
+			return errReply("ERR redistest: no implementation registered for script")
+		}
+		return []byte(h)
+	case "exists":
+		out := make([]any, len(a)-1)
+		for i, h := range a[1:] {
+			out[i] = int64(0)
+			if s.loaded[h] != nil {
+				out[i] = int64(1)
+			}
+		}
+		return out
+	case "flush":
+		s.loaded = map[string]ScriptFunc{}
+		return ok
+	}
+	return errSyntax
+}
+
+func (s *Server) eval(c *conn, name string, a []string) any {
+	if len(a) < 2 {
+		return errArgs(name)
+	}
+	var fn ScriptFunc
+	if name == "evalsha" {
+		if fn = s.loaded[strings.ToLower(a[0])]; fn == nil {
+			return errReply("NOSCRIPT No matching script. Please use EVAL.")
+		}
+	} else {
+		h, ok := s.load(a[0])
+		if !ok {
+			return errReply("ERR redistest: no implementation registered for script")
+		}
+		fn = s.loaded[h]
+	}
+	n, err := strconv.Atoi(a[1])
+	if err != nil || n < 0 || 2+n > len(a) {
+		return errReply("ERR Number of keys can't be greater than number of args")
+	}
+	keys, args := a[2:2+n], a[2+n:]
+	call := func(cmd ...string) any { return s.exec(c, cmd) }
+	return scriptReply(fn(call, keys, args))
+}
+
+// scriptReply mengubah nilai balik ScriptFunc menjadi balasan RESP (aturan konversi Lua -> Redis).
+func scriptReply(v any) any {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return nil
+	case int:
+		return int64(v)
+	case error:
+		return errReply(v.Error())
+	}
+	return v
+}
+
+// StatusText mengembalikan isi balasan status (mis. hasil call("TYPE", k)) untuk ScriptFunc.
+func StatusText(v any) string {
+	switch v := v.(type) {
+	case status:
+		return string(v)
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// ErrReply mengembalikan pesan error balasan call untuk ScriptFunc ("" jika bukan error).
+func ErrReply(v any) error {
+	if e, ok := v.(errReply); ok {
+		return errors.New(string(e))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package redisx
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Hasil CompareAndDelete.
+const (
+	CADDeleted = 1  // value masih sama, key sudah dihapus
+	CADChanged = 0  // value sudah ditimpa penulis lain, key tidak dihapus
+	CADMissing = -1 // key sudah tidak ada (expired / dihapus / di-evict)
+)
+
+// compareAndDeleteScript menghapus key hanya jika SHA1 versinya masih sama dengan ARGV[1].
+// Versi key string adalah value-nya (GET); tipe lain memakai hasil DUMP (lihat TypedValue.Version).
+// Dijalankan atomic di node pemilik key, jadi tidak ada write yang bisa menyelip di antara cek dan DEL.
+// Yang dikirim hanya hash (bukan value utuh) agar request tetap kecil untuk value besar.
+var compareAndDeleteScript = redis.NewScript(`
+local t = redis.call('TYPE', KEYS[1])['ok']
+if t == 'none' then
+	return -1
+end
+local v
+if t == 'string' then
+	v = redis.call('GET', KEYS[1])
+else
+	v = redis.call('DUMP', KEYS[1])
+end
+if redis.sha1hex(v) ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+// CompareAndDelete menghapus key jika versinya di Redis masih sama dengan val
+// (value untuk key string, hasil DUMP untuk tipe lain).
+// Mengembalikan CADDeleted, CADChanged, atau CADMissing.
+func CompareAndDelete(ctx context.Context, c redis.Scripter, key string, val []byte) (int64, error) {
+	sum := sha1.Sum(val)
+	return compareAndDeleteScript.Run(ctx, c, []string{key}, hex.EncodeToString(sum[:])).Int64()
+}
+
+// CompareAndDeleteMany menjalankan CompareAndDelete untuk banyak key dalam satu pipeline
+// (EVALSHA; script di-load ulang sekali jika node belum mengenalnya). Pipeline tidak di-route
+// per slot, jadi c harus client satu node dan semua key harus milik node tersebut.
+// Hasil dan error dikembalikan per key, dengan index yang sama seperti keys.
+func CompareAndDeleteMany(ctx context.Context, c redis.Cmdable, keys []string, vals [][]byte) ([]int64, []error) {
+	hashes := make([]any, len(vals))
+	for i, v := range vals {
+		sum := sha1.Sum(v)
+		hashes[i] = hex.EncodeToString(sum[:])
+	}
+	run := func() []*redis.Cmd {
+		cmds := make([]*redis.Cmd, len(keys))
+		_, _ = c.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for i, k := range keys {
+				cmds[i] = compareAndDeleteScript.EvalSha(ctx, p, []string{k}, hashes[i])
+			}
+			return nil
+		})
+		return cmds
+	}
+	cmds := run()
+	for _, cmd := range cmds {
+		if redis.HasErrorPrefix(cmd.Err(), "NOSCRIPT") {
+			if err := compareAndDeleteScript.Load(ctx, c).Err(); err == nil {
+				cmds = run()
+			}
+			break
+		}
+	}
+	res := make([]int64, len(keys))
+	errs := make([]error, len(keys))
+	for i, cmd := range cmds {
+		res[i], errs[i] = cmd.Int64()
+	}
+	return res, errs
+}
@@ -0,0 +1,64 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+func TestCompareAndDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	c.Set(ctx, "same", "v1", 0)
+	c.Set(ctx, "changed", "v2", 0)
+	c.HSet(ctx, "hash", "f", "x")
+	hv, err := ReadTyped(ctx, c, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"same", "changed", "missing", "hash"}
+	vals := [][]byte{[]byte("v1"), []byte("v1"), []byte("v1"), hv.Version()}
+	res, errs := CompareAndDeleteMany(ctx, c, keys, vals)
+	want := []int64{CADDeleted, CADChanged, CADMissing, CADDeleted}
+	for i, k := range keys {
+		if errs[i] != nil || res[i] != want[i] {
+			t.Errorf("%s: result %d, %v; want %d", k, res[i], errs[i], want[i])
+		}
+	}
+	if got := srv.Keys(); len(got) != 1 || got[0] != "changed" {
+		t.Fatalf("keys left = %v, want [changed]", got)
+	}
+}
+
+// Node yang belum mengenal script (restart / SCRIPT FLUSH) menjawab NOSCRIPT;
+// script di-load sekali lalu pipeline diulang.
+func TestCompareAndDeleteManyReloadsScript(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	c.Set(ctx, "a", "1", 0)
+	c.Set(ctx, "b", "2", 0)
+	if err := c.ScriptFlush(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	srv.ResetCalls()
+
+	res, errs := CompareAndDeleteMany(ctx, c, []string{"a", "b"}, [][]byte{[]byte("1"), []byte("2")})
+	for i := range res {
+		if errs[i] != nil || res[i] != CADDeleted {
+			t.Fatalf("key %d: result %d, %v", i, res[i], errs[i])
+		}
+	}
+	if n := srv.Calls("evalsha"); n != 4 {
+		t.Fatalf("evalsha calls = %d, want 4 (2 NOSCRIPT + 2 after reload)", n)
+	}
+	if n := srv.Calls("script"); n != 1 {
+		t.Fatalf("script calls = %d, want a single SCRIPT LOAD", n)
+	}
+	if len(srv.Keys()) != 0 {
+		t.Fatalf("keys left = %v", srv.Keys())
+	}
+}
@@ -0,0 +1,47 @@
+package redisx
+
+import "strings"
+
+// SlotCount adalah jumlah hash slot di Redis Cluster (tetap 16384).
+const SlotCount = 16384
+
+// Slot menghitung hash slot Redis Cluster untuk sebuah key.
+// Algoritma sama dengan server Redis: CRC16 (XMODEM) dari key, modulo 16384.
+// Jika key mengandung hash tag {…} yang tidak kosong, hanya isi tag yang di-hash,
+// sehingga key seperti "user:{42}:a" dan "user:{42}:b" berada di slot yang sama.
+func Slot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key) % SlotCount)
+}
+
+// GroupBySlot mengelompokkan index key berdasarkan hash slot.
+// Hasilnya dipakai untuk pipeline/MGET per slot (command multi-key di cluster wajib satu slot).
+// Urutan index di dalam tiap grup mengikuti urutan input.
+func GroupBySlot(keys []string) map[int][]int {
+	groups := make(map[int][]int)
+	for i, k := range keys {
+		s := Slot(k)
+		groups[s] = append(groups[s], i)
+	}
+	return groups
+}
+
+// crc16 mengimplementasikan CRC16-CCITT (XMODEM) seperti di crc16.c milik Redis.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,48 @@
+package redisx
+
+import "testing"
+
+func TestCRC16Vector(t *testing.T) {
+	// Vektor uji CRC16-CCITT (XMODEM) dari spesifikasi Redis Cluster
+	if got := crc16("123456789"); got != 0x31C3 {
+		t.Fatalf("crc16(123456789) = %#04x, want 0x31c3", got)
+	}
+}
+
+func TestSlot(t *testing.T) {
+	for _, tc := range []struct {
+		key  string
+		want int
+	}{
+		{"123456789", 0x31C3},
+		{"foo", 12182},
+		{"user", Slot("{user}1000")},
+		{"{user}1000", Slot("{user}1001")},
+		{"a{user}b", Slot("user")},
+		// Tag kosong: seluruh key di-hash
+		{"{}user", int(crc16("{}user") % SlotCount)},
+		// Hanya tag pertama yang dipakai
+		{"{a}{b}", Slot("a")},
+		// "{" tanpa "}" bukan tag
+		{"{user", int(crc16("{user") % SlotCount)},
+		{"", 0},
+	} {
+		if got := Slot(tc.key); got != tc.want {
+			t.Errorf("Slot(%q) = %d, want %d", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestGroupBySlotKeepsInputOrder(t *testing.T) {
+	keys := []string{"{a}1", "{b}1", "{a}2", "{b}2", "{a}3"}
+	groups := GroupBySlot(keys)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %v", len(groups), groups)
+	}
+	if got := groups[Slot("a")]; len(got) != 3 || got[0] != 0 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("group {a} = %v, want [0 2 4]", got)
+	}
+	if got := groups[Slot("b")]; len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("group {b} = %v, want [1 3]", got)
+	}
+}
@@ -0,0 +1,259 @@
+package redisx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Tipe Redis yang bisa dipindah ke cold store. String disimpan apa adanya; tipe lain
+// disimpan sebagai JSON (typed envelope) yang bisa dibaca langsung tanpa Redis:
+//
+//	hash: {"field":"value",...}
+//	list: ["a","b",...] (urutan list)
+//	set:  ["a","b",...] (terurut)
+//	zset: [{"member":"a","score":1.5},...] (urutan score)
+//
+// JSON dipilih alih-alih DUMP/RESTORE karena format DUMP terikat versi RDB Redis dan
+// tidak bisa dibaca dari cold store. Konsekuensinya semua field/member harus teks UTF-8.
+const (
+	TypeString = "string"
+	TypeHash   = "hash"
+	TypeList   = "list"
+	TypeSet    = "set"
+	TypeZSet   = "zset"
+)
+
+// ErrUnsupportedType dikembalikan untuk tipe selain di atas (stream, module) atau data
+// yang tidak bisa direpresentasikan sebagai JSON (member bukan UTF-8, score inf).
+var ErrUnsupportedType = errors.New("redisx: unsupported type")
+
+// ZMember adalah satu member sorted set di typed envelope.
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// TypedValue adalah value satu key beserta tipenya.
+type TypedValue struct {
+	Type  string
+	Value []byte // string: value mentah; tipe lain: typed envelope JSON
+	Dump  []byte // hasil DUMP untuk tipe non-string: versi yang dibandingkan CompareAndDelete
+}
+
+// Version mengembalikan byte yang dibandingkan CompareAndDelete untuk value ini.
+func (v TypedValue) Version() []byte {
+	if v.Type == TypeString {
+		return v.Value
+	}
+	return v.Dump
+}
+
+// ReadTypedMany membaca value key dengan tipe apa pun dalam dua pipeline (TYPE, lalu
+// perintah baca sesuai tipe + DUMP). Seperti CompareAndDeleteMany, c harus client satu node
+// atau ClusterClient. Key yang tidak ada mendapat error redis.Nil.
+func ReadTypedMany(ctx context.Context, c redis.Cmdable, keys []string) ([]TypedValue, []error) {
+	out := make([]TypedValue, len(keys))
+	errs := make([]error, len(keys))
+	types := make([]*redis.StatusCmd, len(keys))
+	_, _ = c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			types[i] = p.Type(ctx, k)
+		}
+		return nil
+	})
+
+	reads := make([]redis.Cmder, len(keys))
+	dumps := make([]*redis.StringCmd, len(keys))
+	_, _ = c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			t, err := types[i].Result()
+			switch {
+			case err != nil:
+				errs[i] = err
+				continue
+			case t == "none":
+				errs[i] = redis.Nil
+				continue
+			}
+			out[i].Type = t
+			switch t {
+			case TypeString:
+				reads[i] = p.Get(ctx, k)
+			case TypeHash:
+				reads[i] = p.HGetAll(ctx, k)
+			case TypeList:
+				reads[i] = p.LRange(ctx, k, 0, -1)
+			case TypeSet:
+				reads[i] = p.SMembers(ctx, k)
+			case TypeZSet:
+				reads[i] = p.ZRangeWithScores(ctx, k, 0, -1)
+			default:
+				errs[i] = fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+				continue
+			}
+			if t != TypeString {
+				dumps[i] = p.Dump(ctx, k)
+			}
+		}
+		return nil
+	})
+	for i := range keys {
+		if errs[i] != nil {
+			continue
+		}
+		if out[i].Value, errs[i] = encodeTyped(out[i].Type, reads[i]); errs[i] != nil {
+			continue
+		}
+		if dumps[i] != nil {
+			d, err := dumps[i].Result()
+			out[i].Dump, errs[i] = []byte(d), err
+		}
+	}
+	return out, errs
+}
+
+// ReadTyped membaca satu key dengan tipe apa pun (lihat ReadTypedMany).
+func ReadTyped(ctx context.Context, c redis.Cmdable, key string) (TypedValue, error) {
+	out, errs := ReadTypedMany(ctx, c, []string{key})
+	return out[0], errs[0]
+}
+
+// encodeTyped mengubah hasil perintah baca key bertipe typ menjadi value cold store.
+func encodeTyped(typ string, cmd redis.Cmder) ([]byte, error) {
+	var v any
+	switch cmd := cmd.(type) {
+	case *redis.StringCmd:
+		b, err := cmd.Bytes()
+		return b, err
+	case *redis.MapStringStringCmd:
+		m, err := cmd.Result()
+		if err != nil {
+			return nil, err
+		}
+		for f, x := range m {
+			if !utf8.ValidString(f) || !utf8.ValidString(x) {
+				return nil, fmt.Errorf("%w: non UTF-8 hash field", ErrUnsupportedType)
+			}
+		}
+		v = m
+	case *redis.StringSliceCmd:
+		s, err := cmd.Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range s {
+			if !utf8.ValidString(x) {
+				return nil, fmt.Errorf("%w: non UTF-8 member", ErrUnsupportedType)
+			}
+		}
+		if typ == TypeSet {
+			sort.Strings(s) // SMEMBERS tidak berurutan; diurutkan agar hasil encode deterministik
+		}
+		v = s
+	case *redis.ZSliceCmd:
+		zs, err := cmd.Result()
+		if err != nil {
+			return nil, err
+		}
+		ms := make([]ZMember, len(zs))
+		for i, z := range zs {
+			m, _ := z.Member.(string)
+			if !utf8.ValidString(m) {
+				return nil, fmt.Errorf("%w: non UTF-8 member", ErrUnsupportedType)
+			}
+			ms[i] = ZMember{Member: m, Score: z.Score}
+		}
+		v = ms
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	return b, nil
+}
+
+// Watcher adalah client yang mendukung WATCH/MULTI (*redis.Client, *redis.ClusterClient).
+type Watcher interface {
+	Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error
+}
+
+// RestoreTyped menulis ulang value cold store ke Redis hanya jika key belum ada
+// (seperti SET NX), dalam satu transaksi WATCH/MULTI. ttl <= 0 berarti tanpa expiry.
+// Mengembalikan false jika key sudah ada atau berubah selama restore.
+func RestoreTyped(ctx context.Context, c Watcher, key, typ string, value []byte, ttl time.Duration) (bool, error) {
+	var (
+		args []any // hash: field, value, ...; list/set: member, ...
+		zs   []redis.Z
+	)
+	switch typ {
+	case "", TypeString:
+	case TypeHash:
+		var m map[string]string
+		if err := json.Unmarshal(value, &m); err != nil {
+			return false, err
+		}
+		for f, x := range m {
+			args = append(args, f, x)
+		}
+	case TypeList, TypeSet:
+		var s []string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return false, err
+		}
+		for _, x := range s {
+			args = append(args, x)
+		}
+	case TypeZSet:
+		var ms []ZMember
+		if err := json.Unmarshal(value, &ms); err != nil {
+			return false, err
+		}
+		for _, m := range ms {
+			zs = append(zs, redis.Z{Member: m.Member, Score: m.Score})
+		}
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedType, typ)
+	}
+	// Redis tidak punya hash/list/set/zset kosong
+	if typ != "" && typ != TypeString && len(args) == 0 && len(zs) == 0 {
+		return false, fmt.Errorf("redisx: empty %s value for key %q", typ, key)
+	}
+
+	restored := false
+	err := c.Watch(ctx, func(tx *redis.Tx) error {
+		if n, err := tx.Exists(ctx, key).Result(); err != nil || n > 0 {
+			return err
+		}
+		_, err := tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			switch typ {
+			case "", TypeString:
+				p.Set(ctx, key, value, 0)
+			case TypeHash:
+				p.HSet(ctx, key, args...)
+			case TypeList:
+				p.RPush(ctx, key, args...)
+			case TypeSet:
+				p.SAdd(ctx, key, args...)
+			case TypeZSet:
+				p.ZAdd(ctx, key, zs...)
+			}
+			if ttl > 0 {
+				p.PExpire(ctx, key, ttl)
+			}
+			return nil
+		})
+		restored = err == nil
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+	return restored, err
+}
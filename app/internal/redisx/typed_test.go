@@ -0,0 +1,130 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"monolith-kv-sim/internal/redisx/redistest"
+)
+
+// Value setiap tipe dibaca sebagai typed envelope, lalu ditulis ulang ke key lain dan dibaca
+// lagi dengan hasil yang sama (termasuk urutan list dan score zset).
+func TestTypedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	c.Set(ctx, "s", `{"v":1}`, 0)
+	c.HSet(ctx, "h", "b", "2", "a", "1", "_ts", "1700000000000")
+	c.RPush(ctx, "l", "z", "a", "z")
+	c.SAdd(ctx, "set", "b", "c", "a")
+	c.ZAdd(ctx, "zs", redis.Z{Member: "hi", Score: 1.5}, redis.Z{Member: "lo", Score: -2}, redis.Z{Member: "tiny", Score: 0.001})
+
+	for _, tc := range []struct {
+		key, typ, want string
+	}{
+		{"s", TypeString, `{"v":1}`},
+		{"h", TypeHash, `{"_ts":"1700000000000","a":"1","b":"2"}`},
+		{"l", TypeList, `["z","a","z"]`},
+		{"set", TypeSet, `["a","b","c"]`},
+		{"zs", TypeZSet, `[{"member":"lo","score":-2},{"member":"tiny","score":0.001},{"member":"hi","score":1.5}]`},
+	} {
+		t.Run(tc.typ, func(t *testing.T) {
+			v, err := ReadTyped(ctx, c, tc.key)
+			if err != nil || v.Type != tc.typ || string(v.Value) != tc.want {
+				t.Fatalf("ReadTyped = %s %s, %v; want %s %s", v.Type, v.Value, err, tc.typ, tc.want)
+			}
+			if tc.typ != TypeString && len(v.Dump) == 0 {
+				t.Fatal("no DUMP version for typed value")
+			}
+
+			ok, err := RestoreTyped(ctx, c, "copy:"+tc.key, v.Type, v.Value, time.Minute)
+			if !ok || err != nil {
+				t.Fatalf("RestoreTyped = %t, %v", ok, err)
+			}
+			got, err := ReadTyped(ctx, c, "copy:"+tc.key)
+			if err != nil || got.Type != tc.typ || string(got.Value) != tc.want {
+				t.Fatalf("restored = %s %s, %v", got.Type, got.Value, err)
+			}
+			if ttl := srv.TTL("copy:" + tc.key); ttl <= 59*time.Second || ttl > time.Minute {
+				t.Fatalf("restored TTL = %s", ttl)
+			}
+		})
+	}
+}
+
+func TestReadTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	c.SAdd(ctx, "bin", "\xff\xfe")
+	_, errs := ReadTypedMany(ctx, c, []string{"missing", "bin"})
+	if !errors.Is(errs[0], redis.Nil) {
+		t.Errorf("missing key: %v, want redis.Nil", errs[0])
+	}
+	if !errors.Is(errs[1], ErrUnsupportedType) {
+		t.Errorf("non UTF-8 member: %v, want ErrUnsupportedType", errs[1])
+	}
+}
+
+func TestRestoreTypedRejects(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	for _, tc := range []struct{ typ, val string }{
+		{TypeHash, `{}`},
+		{TypeList, `[]`},
+		{TypeSet, `[]`},
+		{TypeZSet, `[]`},
+		{TypeHash, `not json`},
+		{"stream", `[]`},
+	} {
+		if ok, err := RestoreTyped(ctx, c, "k", tc.typ, []byte(tc.val), 0); ok || err == nil {
+			t.Errorf("RestoreTyped(%s, %s) = %t, %v; want error", tc.typ, tc.val, ok, err)
+		}
+	}
+	if keys := srv.Keys(); len(keys) != 0 {
+		t.Fatalf("keys written by rejected restores: %v", keys)
+	}
+}
+
+// watchHook menjalankan fn setelah WATCH, sebelum isi transaksi RestoreTyped.
+type watchHook struct {
+	c  *redis.Client
+	fn func()
+}
+
+func (w watchHook) Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error {
+	return w.c.Watch(ctx, func(tx *redis.Tx) error {
+		w.fn()
+		return fn(tx)
+	}, keys...)
+}
+
+// RestoreTyped tidak menimpa key yang sudah ada, termasuk yang ditulis (lalu dihapus) selama restore.
+func TestRestoreTypedDoesNotOverwrite(t *testing.T) {
+	ctx := context.Background()
+	srv := redistest.NewServer(t)
+	c := srv.Client(t)
+	c.Set(ctx, "exists", "new", 0)
+	if ok, err := RestoreTyped(ctx, c, "exists", TypeHash, []byte(`{"f":"old"}`), 0); ok || err != nil {
+		t.Fatalf("restore over existing key = %t, %v", ok, err)
+	}
+	if v, _ := srv.Get("exists"); v != "new" {
+		t.Fatalf("existing key = %q", v)
+	}
+
+	other := srv.Client(t)
+	w := watchHook{c, func() {
+		other.Set(ctx, "raced", "x", 0)
+		other.Del(ctx, "raced")
+	}}
+	if ok, err := RestoreTyped(ctx, w, "raced", TypeList, []byte(`["a"]`), 0); ok || err != nil {
+		t.Fatalf("restore of key changed after WATCH = %t, %v; want false", ok, err)
+	}
+	if keys := srv.Keys(); len(keys) != 1 {
+		t.Fatalf("keys = %v, want only exists", keys)
+	}
+}
@@ -0,0 +1,56 @@
+// Package throttle membatasi laju operasi (ops/detik) yang dibagi beberapa goroutine,
+// dipakai proses background (offloader, dll) agar tidak menghabiskan kapasitas Redis
+// yang dibutuhkan ingestor.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter membagi waktu menjadi slot tetap: setiap operasi memesan 1/perSec detik.
+// Pemesanan sebanyak n operasi sekaligus (mis. satu pipeline) menunggu sampai slotnya tiba.
+// Limiter nil atau dengan perSec <= 0 tidak membatasi apa pun.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// New membuat Limiter dengan laju perSec operasi per detik (<= 0: tanpa batas, mengembalikan nil).
+func New(perSec int) *Limiter {
+	if perSec <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Second / time.Duration(perSec)}
+}
+
+// Wait memesan n operasi dan menunggu sampai boleh dijalankan (atau ctx selesai).
+// Waktu idle tidak ditabung lebih dari satu detik, jadi setelah jeda panjang burst tetap dibatasi.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if floor := now.Add(-time.Second); l.next.Before(floor) {
+		l.next = floor
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(n) * l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
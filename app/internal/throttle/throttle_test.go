@@ -0,0 +1,50 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNilLimiterDoesNotWait(t *testing.T) {
+	if l := New(0); l != nil {
+		t.Fatalf("New(0) = %v, want nil", l)
+	}
+	var l *Limiter
+	if err := l.Wait(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Setelah idle, paling banyak satu detik operasi boleh langsung jalan; sisanya menunggu slot.
+func TestLimiterRate(t *testing.T) {
+	ctx := context.Background()
+	l := New(100) // satu operasi per 10ms
+	start := time.Now()
+	if err := l.Wait(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Fatalf("first second of ops waited %s", d)
+	}
+	if err := l.Wait(ctx, 5); err != nil { // slot ke-101..105 dimulai sekitar sekarang
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if err := l.Wait(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("op after burst waited %s, want ~50ms", d)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := New(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = l.Wait(ctx, 2) // detik idle + satu detik ke depan sudah terpesan
+	cancel()
+	if err := l.Wait(ctx, 1); err != context.Canceled {
+		t.Fatalf("Wait after cancel = %v, want context.Canceled", err)
+	}
+}